@@ -0,0 +1,38 @@
+package database
+
+import (
+	"os"
+	"strings"
+
+	"gym-app-backend/models"
+)
+
+// configuredAdmins returns the usernames listed in ADMIN_USERNAMES,
+// separated by commas
+func configuredAdmins() []interface{} {
+	var usernames []interface{}
+	for _, name := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			usernames = append(usernames, name)
+		}
+	}
+	return usernames
+}
+
+// GrantConfiguredAdmins gives the admin role to the users listed in
+// ADMIN_USERNAMES. It only runs at startup, never on registration, so list
+// accounts that already exist; claiming a listed name does not make anyone an
+// admin before the operator restarts the server. Users are never demoted
+// here, so admins can also be appointed directly in the database.
+func GrantConfiguredAdmins(e Execer) error {
+	usernames := configuredAdmins()
+	if len(usernames) == 0 {
+		return nil
+	}
+	params := append([]interface{}{models.RoleAdmin}, usernames...)
+	_, err := e.Exec(
+		"UPDATE users SET role = ? WHERE username IN (?"+strings.Repeat(", ?", len(usernames)-1)+")",
+		params...,
+	)
+	return err
+}
@@ -1,12 +1,17 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -47,22 +52,69 @@ func InitializeDatabase() error {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	// Seed public exercises
+	// Sync the public exercise library with the embedded data file
 	if err := seedPublicExercises(); err != nil {
 		return fmt.Errorf("failed to seed public exercises: %w", err)
 	}
 
+	// Promote the existing users configured as admins
+	if err := GrantConfiguredAdmins(DB); err != nil {
+		return fmt.Errorf("failed to grant admin roles: %w", err)
+	}
+
 	fmt.Println("Database initialized successfully")
 	return nil
 }
 
+// workoutLogsTable creates the workout_logs table under the given name.
+// exercise_source tells whether exercise_id points at exercises or
+// public_exercises, so exercise_id has no foreign key.
+const workoutLogsTable = `
+		CREATE TABLE %s (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL DEFAULT 'private',
+			session_id INTEGER REFERENCES workout_sessions(id) ON DELETE CASCADE,
+			session_order INTEGER,
+			group_id INTEGER REFERENCES exercise_groups(id),
+			date DATE NOT NULL,
+			sets INTEGER,
+			reps INTEGER,
+			weight REAL,
+			weight_per_set TEXT,
+			rest_time INTEGER,
+			distance REAL,
+			duration INTEGER,
+			pace REAL,
+			lap_times TEXT,
+			elevation_gain REAL,
+			notes TEXT,
+			planned BOOLEAN NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`
+
 func createSchema() error {
-	// Users table
+	// Schema migrations table (one-time data migrations that have been applied)
 	_, err := DB.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			name TEXT PRIMARY KEY,
+			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	// Users table
+	_, err = DB.Exec(`
 		CREATE TABLE IF NOT EXISTS users (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			username TEXT UNIQUE NOT NULL,
 			password_hash TEXT NOT NULL,
+			role TEXT NOT NULL DEFAULT 'user',
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)
 	`)
@@ -83,6 +135,7 @@ func createSchema() error {
 			instructions TEXT,
 			video_link TEXT,
 			image_link TEXT,
+			source_public_exercise_id INTEGER,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 		)
@@ -91,30 +144,106 @@ func createSchema() error {
 		return fmt.Errorf("failed to create exercises table: %w", err)
 	}
 
-	// Workout logs table
+	// Workout sessions table (groups workout logs performed together)
 	_, err = DB.Exec(`
-		CREATE TABLE IF NOT EXISTS workout_logs (
+		CREATE TABLE IF NOT EXISTS workout_sessions (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
 			user_id INTEGER NOT NULL,
-			exercise_id INTEGER NOT NULL,
+			name TEXT,
 			date DATE NOT NULL,
-			sets INTEGER,
+			started_at DATETIME,
+			ended_at DATETIME,
+			notes TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create workout_sessions table: %w", err)
+	}
+
+	// Workout logs table
+	_, err = DB.Exec(fmt.Sprintf(workoutLogsTable, "IF NOT EXISTS workout_logs"))
+	if err != nil {
+		return fmt.Errorf("failed to create workout_logs table: %w", err)
+	}
+
+	// Workout sets table (individual sets of a workout log)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS workout_sets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			workout_log_id INTEGER NOT NULL,
+			set_index INTEGER NOT NULL,
 			reps INTEGER,
 			weight REAL,
-			weight_per_set TEXT,
-			rest_time INTEGER,
+			set_type TEXT NOT NULL DEFAULT 'working',
+			rpe REAL,
+			tempo TEXT,
+			duration_seconds INTEGER,
+			FOREIGN KEY (workout_log_id) REFERENCES workout_logs(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create workout_sets table: %w", err)
+	}
+
+	// Workout laps table (laps or splits of a cardio workout log)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS workout_laps (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			workout_log_id INTEGER NOT NULL,
+			lap_index INTEGER NOT NULL,
+			label TEXT,
 			distance REAL,
-			duration INTEGER,
-			pace REAL,
-			lap_times TEXT,
-			notes TEXT,
+			duration_seconds REAL,
+			heart_rate INTEGER,
+			FOREIGN KEY (workout_log_id) REFERENCES workout_logs(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create workout_laps table: %w", err)
+	}
+
+	// Workout imports table (activity files imported as workout logs, by content hash)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS workout_imports (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			file_hash TEXT NOT NULL,
+			format TEXT NOT NULL,
+			filename TEXT,
+			workout_log_id INTEGER NOT NULL,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, file_hash),
 			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
-			FOREIGN KEY (exercise_id) REFERENCES exercises(id) ON DELETE CASCADE
+			FOREIGN KEY (workout_log_id) REFERENCES workout_logs(id) ON DELETE CASCADE
 		)
 	`)
 	if err != nil {
-		return fmt.Errorf("failed to create workout_logs table: %w", err)
+		return fmt.Errorf("failed to create workout_imports table: %w", err)
+	}
+
+	// Personal records table (history of records set by workout logs)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS personal_records (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL DEFAULT 'private',
+			record_type TEXT NOT NULL,
+			value REAL NOT NULL,
+			weight REAL,
+			reps INTEGER,
+			previous_value REAL,
+			workout_log_id INTEGER NOT NULL,
+			achieved_on DATE NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (workout_log_id) REFERENCES workout_logs(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create personal_records table: %w", err)
 	}
 
 	// Public exercises table
@@ -129,6 +258,9 @@ func createSchema() error {
 			instructions TEXT,
 			video_link TEXT,
 			image_link TEXT,
+			slug TEXT,
+			deprecated_at DATETIME,
+			replaced_by_id INTEGER,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)
 	`)
@@ -136,12 +268,382 @@ func createSchema() error {
 		return fmt.Errorf("failed to create public_exercises table: %w", err)
 	}
 
+	// Full-text index of the public exercise library, kept in sync by triggers
+	_, err = DB.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS public_exercises_fts USING fts4(
+			content="public_exercises", name, description, muscle_group, equipment,
+			tokenize=unicode61, prefix="2,3"
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create public_exercises_fts table: %w", err)
+	}
+
+	ftsTriggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS public_exercises_fts_bu BEFORE UPDATE ON public_exercises BEGIN
+			DELETE FROM public_exercises_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS public_exercises_fts_bd BEFORE DELETE ON public_exercises BEGIN
+			DELETE FROM public_exercises_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS public_exercises_fts_au AFTER UPDATE ON public_exercises BEGIN
+			INSERT INTO public_exercises_fts (docid, name, description, muscle_group, equipment)
+			VALUES (new.id, new.name, new.description, new.muscle_group, new.equipment);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS public_exercises_fts_ai AFTER INSERT ON public_exercises BEGIN
+			INSERT INTO public_exercises_fts (docid, name, description, muscle_group, equipment)
+			VALUES (new.id, new.name, new.description, new.muscle_group, new.equipment);
+		END`,
+	}
+	for _, trigger := range ftsTriggers {
+		if _, err := DB.Exec(trigger); err != nil {
+			return fmt.Errorf("failed to create public_exercises_fts trigger: %w", err)
+		}
+	}
+
+	// Workout templates table (reusable routines)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS workout_templates (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create workout_templates table: %w", err)
+	}
+
+	// Workout template exercises table (ordered targets within a template)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS workout_template_exercises (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			template_id INTEGER NOT NULL,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL DEFAULT 'private',
+			position INTEGER NOT NULL,
+			target_sets INTEGER,
+			target_reps INTEGER,
+			target_weight REAL,
+			target_distance REAL,
+			target_duration INTEGER,
+			rest_time INTEGER,
+			notes TEXT,
+			FOREIGN KEY (template_id) REFERENCES workout_templates(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create workout_template_exercises table: %w", err)
+	}
+
+	// Training programs table (multi-week plans)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS training_programs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			description TEXT,
+			duration_weeks INTEGER NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create training_programs table: %w", err)
+	}
+
+	// Program days table (a NULL week repeats every week)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS program_days (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			program_id INTEGER NOT NULL,
+			week INTEGER,
+			day INTEGER NOT NULL,
+			name TEXT,
+			template_id INTEGER,
+			FOREIGN KEY (program_id) REFERENCES training_programs(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create program_days table: %w", err)
+	}
+
+	// Program prescriptions table (percentage or increment based targets)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS program_prescriptions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			program_day_id INTEGER NOT NULL,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL DEFAULT 'private',
+			method TEXT NOT NULL,
+			sets INTEGER,
+			reps INTEGER,
+			percentage REAL,
+			base_weight REAL,
+			weight_increment REAL,
+			base_distance REAL,
+			distance_increment REAL,
+			base_duration INTEGER,
+			duration_increment INTEGER,
+			FOREIGN KEY (program_day_id) REFERENCES program_days(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create program_prescriptions table: %w", err)
+	}
+
+	// Program enrollments table
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS program_enrollments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			program_id INTEGER NOT NULL,
+			start_date DATE NOT NULL,
+			status TEXT NOT NULL DEFAULT 'active',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (program_id) REFERENCES training_programs(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create program_enrollments table: %w", err)
+	}
+
+	// Program training maxes table (reference weights for percentage prescriptions)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS program_training_maxes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			enrollment_id INTEGER NOT NULL,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL DEFAULT 'private',
+			weight REAL NOT NULL,
+			FOREIGN KEY (enrollment_id) REFERENCES program_enrollments(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create program_training_maxes table: %w", err)
+	}
+
+	// Progression rules table (how the next prescription of an exercise is suggested)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS progression_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL DEFAULT 'private',
+			method TEXT NOT NULL,
+			min_reps INTEGER,
+			max_reps INTEGER,
+			target_reps INTEGER,
+			increment REAL,
+			hold_on_missed_reps BOOLEAN NOT NULL DEFAULT 1,
+			deload_after_misses INTEGER,
+			deload_percent REAL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, exercise_id, exercise_source),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create progression_rules table: %w", err)
+	}
+
+	// Body metrics table (dated bodyweight, body fat and circumference measurements)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS body_metrics (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			date DATE NOT NULL,
+			bodyweight REAL,
+			body_fat_percent REAL,
+			neck REAL,
+			chest REAL,
+			waist REAL,
+			hips REAL,
+			arm REAL,
+			forearm REAL,
+			thigh REAL,
+			calf REAL,
+			notes TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, date),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create body_metrics table: %w", err)
+	}
+
+	// Exercise groups table (supersets, circuits and giant sets within a session)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS exercise_groups (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			session_id INTEGER NOT NULL,
+			group_type TEXT NOT NULL,
+			rounds INTEGER,
+			rest_between_rounds INTEGER,
+			notes TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+			FOREIGN KEY (session_id) REFERENCES workout_sessions(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create exercise_groups table: %w", err)
+	}
+
+	// Custom fields users define on an exercise (incline, cadence, band color, ...)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS exercise_custom_fields (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL DEFAULT 'private',
+			name TEXT NOT NULL,
+			field_type TEXT NOT NULL,
+			unit TEXT,
+			options TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (user_id, exercise_id, exercise_source, name),
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create exercise_custom_fields table: %w", err)
+	}
+
+	// Values of custom fields recorded by a workout log
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS workout_log_custom_values (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			workout_log_id INTEGER NOT NULL,
+			field_id INTEGER NOT NULL,
+			number_value REAL,
+			text_value TEXT,
+			UNIQUE (workout_log_id, field_id),
+			FOREIGN KEY (workout_log_id) REFERENCES workout_logs(id) ON DELETE CASCADE,
+			FOREIGN KEY (field_id) REFERENCES exercise_custom_fields(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create workout_log_custom_values table: %w", err)
+	}
+
+	// Goals table (targets to reach by a deadline, evaluated from workout logs)
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS goals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL,
+			exercise_id INTEGER,
+			exercise_source TEXT,
+			goal_type TEXT NOT NULL,
+			target_value REAL NOT NULL,
+			target_reps INTEGER,
+			target_duration INTEGER,
+			start_date DATE NOT NULL,
+			deadline DATE NOT NULL,
+			notes TEXT,
+			achieved_on DATE,
+			achieved_log_id INTEGER,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create goals table: %w", err)
+	}
+
+	// Exercise taxonomy: catalogs of muscles, equipment and movement patterns
+	// and their links to private and public exercises
+	taxonomyTables := []string{
+		`CREATE TABLE IF NOT EXISTS muscles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			region TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS equipment (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS movement_patterns (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			slug TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS exercise_muscles (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL,
+			muscle_id INTEGER NOT NULL,
+			role TEXT NOT NULL,
+			UNIQUE (exercise_id, exercise_source, muscle_id),
+			FOREIGN KEY (muscle_id) REFERENCES muscles(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS exercise_equipment (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL,
+			equipment_id INTEGER NOT NULL,
+			UNIQUE (exercise_id, exercise_source, equipment_id),
+			FOREIGN KEY (equipment_id) REFERENCES equipment(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS exercise_movement_patterns (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			exercise_id INTEGER NOT NULL,
+			exercise_source TEXT NOT NULL,
+			movement_pattern_id INTEGER NOT NULL,
+			UNIQUE (exercise_id, exercise_source, movement_pattern_id),
+			FOREIGN KEY (movement_pattern_id) REFERENCES movement_patterns(id) ON DELETE CASCADE
+		)`,
+	}
+	for _, table := range taxonomyTables {
+		if _, err := DB.Exec(table); err != nil {
+			return fmt.Errorf("failed to create taxonomy table: %w", err)
+		}
+	}
+
+	if err := syncTaxonomyCatalog(); err != nil {
+		return fmt.Errorf("failed to sync taxonomy catalog: %w", err)
+	}
+
 	// Create indexes
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_exercises_user_id ON exercises(user_id)",
 		"CREATE INDEX IF NOT EXISTS idx_workout_logs_user_id ON workout_logs(user_id)",
 		"CREATE INDEX IF NOT EXISTS idx_workout_logs_exercise_id ON workout_logs(exercise_id)",
 		"CREATE INDEX IF NOT EXISTS idx_workout_logs_date ON workout_logs(date)",
+		"CREATE INDEX IF NOT EXISTS idx_workout_sets_workout_log_id ON workout_sets(workout_log_id)",
+		"CREATE INDEX IF NOT EXISTS idx_workout_laps_workout_log_id ON workout_laps(workout_log_id)",
+		"CREATE INDEX IF NOT EXISTS idx_workout_imports_workout_log_id ON workout_imports(workout_log_id)",
+		"CREATE INDEX IF NOT EXISTS idx_personal_records_user_exercise ON personal_records(user_id, exercise_id)",
+		"CREATE INDEX IF NOT EXISTS idx_workout_sessions_user_id ON workout_sessions(user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_workout_sessions_date ON workout_sessions(date)",
+		"CREATE INDEX IF NOT EXISTS idx_workout_templates_user_id ON workout_templates(user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_workout_template_exercises_template_id ON workout_template_exercises(template_id)",
+		"CREATE INDEX IF NOT EXISTS idx_training_programs_user_id ON training_programs(user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_program_days_program_id ON program_days(program_id)",
+		"CREATE INDEX IF NOT EXISTS idx_program_prescriptions_day_id ON program_prescriptions(program_day_id)",
+		"CREATE INDEX IF NOT EXISTS idx_program_enrollments_user_id ON program_enrollments(user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_program_training_maxes_enrollment_id ON program_training_maxes(enrollment_id)",
+		"CREATE INDEX IF NOT EXISTS idx_exercise_groups_session_id ON exercise_groups(session_id)",
+		"CREATE INDEX IF NOT EXISTS idx_exercise_custom_fields_exercise ON exercise_custom_fields(user_id, exercise_id, exercise_source)",
+		"CREATE INDEX IF NOT EXISTS idx_workout_log_custom_values_field_id ON workout_log_custom_values(field_id)",
+		"CREATE INDEX IF NOT EXISTS idx_goals_user_id ON goals(user_id)",
+		"CREATE INDEX IF NOT EXISTS idx_exercise_muscles_muscle_id ON exercise_muscles(muscle_id)",
+		"CREATE INDEX IF NOT EXISTS idx_exercise_equipment_equipment_id ON exercise_equipment(equipment_id)",
+		"CREATE INDEX IF NOT EXISTS idx_exercise_movement_patterns_pattern_id ON exercise_movement_patterns(movement_pattern_id)",
+		"CREATE INDEX IF NOT EXISTS idx_public_exercises_name ON public_exercises(name COLLATE NOCASE)",
+		"CREATE INDEX IF NOT EXISTS idx_public_exercises_type_name ON public_exercises(exercise_type, name COLLATE NOCASE)",
+		"CREATE INDEX IF NOT EXISTS idx_public_exercises_created_at ON public_exercises(created_at)",
 	}
 
 	for _, idx := range indexes {
@@ -160,11 +662,23 @@ func runMigrations() error {
 		return fmt.Errorf("failed to add exercise_type column: %w", err)
 	}
 
+	// Exercises copied from the public library remember where they came from
+	_, err = DB.Exec("ALTER TABLE exercises ADD COLUMN source_public_exercise_id INTEGER")
+	if err != nil && !isColumnExistsError(err) {
+		return fmt.Errorf("failed to add source_public_exercise_id column: %w", err)
+	}
+
 	// Add new columns to workout_logs if they don't exist
 	workoutLogColumns := []string{
 		"ALTER TABLE workout_logs ADD COLUMN weight_per_set TEXT",
 		"ALTER TABLE workout_logs ADD COLUMN rest_time INTEGER",
 		"ALTER TABLE workout_logs ADD COLUMN lap_times TEXT",
+		"ALTER TABLE workout_logs ADD COLUMN session_id INTEGER REFERENCES workout_sessions(id) ON DELETE CASCADE",
+		"ALTER TABLE workout_logs ADD COLUMN session_order INTEGER",
+		"ALTER TABLE workout_logs ADD COLUMN elevation_gain REAL",
+		"ALTER TABLE workout_logs ADD COLUMN group_id INTEGER REFERENCES exercise_groups(id)",
+		"ALTER TABLE workout_logs ADD COLUMN exercise_source TEXT NOT NULL DEFAULT 'private'",
+		"ALTER TABLE workout_logs ADD COLUMN planned BOOLEAN NOT NULL DEFAULT 0",
 	}
 
 	for _, col := range workoutLogColumns {
@@ -174,6 +688,40 @@ func runMigrations() error {
 		}
 	}
 
+	_, err = DB.Exec("ALTER TABLE personal_records ADD COLUMN exercise_source TEXT NOT NULL DEFAULT 'private'")
+	if err != nil && !isColumnExistsError(err) {
+		return fmt.Errorf("failed to add personal_records exercise_source column: %w", err)
+	}
+
+	if err := rebuildWorkoutLogs(); err != nil {
+		return fmt.Errorf("failed to rebuild workout_logs: %w", err)
+	}
+
+	if err := RunOnce("workout_log_exercise_source", resolveWorkoutLogExerciseSource); err != nil {
+		return fmt.Errorf("failed to resolve workout log exercise sources: %w", err)
+	}
+
+	// Index session_id here since older databases only get the column above
+	_, err = DB.Exec("CREATE INDEX IF NOT EXISTS idx_workout_logs_session_id ON workout_logs(session_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create session index: %w", err)
+	}
+
+	_, err = DB.Exec("CREATE INDEX IF NOT EXISTS idx_workout_logs_group_id ON workout_logs(group_id)")
+	if err != nil {
+		return fmt.Errorf("failed to create group index: %w", err)
+	}
+
+	// Sets of timed holds and mobility work record how long they were held
+	_, err = DB.Exec("ALTER TABLE workout_sets ADD COLUMN duration_seconds INTEGER")
+	if err != nil && !isColumnExistsError(err) {
+		return fmt.Errorf("failed to add duration_seconds column: %w", err)
+	}
+
+	if err := migrateWeightPerSet(); err != nil {
+		return fmt.Errorf("failed to migrate weight_per_set: %w", err)
+	}
+
 	// Add recovery columns to users if they don't exist
 	// Note: SQLite doesn't allow adding UNIQUE constraint directly when adding a column
 	// So we add the column without UNIQUE, then create a unique index separately
@@ -216,12 +764,81 @@ func runMigrations() error {
 		return fmt.Errorf("failed to add weekly_report_enabled column: %w", err)
 	}
 
+	// Add unit preference columns to users if they don't exist
+	_, err = DB.Exec("ALTER TABLE users ADD COLUMN weight_unit TEXT DEFAULT 'lb'")
+	if err != nil && !isColumnExistsError(err) {
+		return fmt.Errorf("failed to add weight_unit column: %w", err)
+	}
+
+	_, err = DB.Exec("ALTER TABLE users ADD COLUMN distance_unit TEXT DEFAULT 'mi'")
+	if err != nil && !isColumnExistsError(err) {
+		return fmt.Errorf("failed to add distance_unit column: %w", err)
+	}
+
+	// Values used to be stored without a unit and were displayed as pounds and
+	// miles; convert them once to the canonical kilograms and kilometers
+	if err := RunOnce("canonical_metric_units", migrateToMetricUnits); err != nil {
+		return fmt.Errorf("failed to convert stored units: %w", err)
+	}
+
+	// Move lap_times JSON into workout_laps rows
+	if err := migrateLapTimes(); err != nil {
+		return fmt.Errorf("failed to migrate lap_times: %w", err)
+	}
+
+	// Pace used to be whatever the client sent; derive it from distance and duration
+	if err := RunOnce("derive_cardio_pace", deriveCardioPace); err != nil {
+		return fmt.Errorf("failed to derive cardio pace: %w", err)
+	}
+
+	// Index public exercises that existed before the full-text index
+	if err := RunOnce("index_public_exercises_fts", func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO public_exercises_fts (public_exercises_fts) VALUES ('rebuild')")
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to build public exercise search index: %w", err)
+	}
+
+	// Link exercises created before the taxonomy to it from their free text
+	if err := RunOnce("map_exercise_taxonomy", mapExerciseTaxonomy); err != nil {
+		return fmt.Errorf("failed to map exercise taxonomy: %w", err)
+	}
+
 	// Create unique index on email if it doesn't exist
 	_, err = DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(email)")
 	if err != nil {
 		// Ignore errors - index might already exist
 	}
 
+	// Admins curate the public exercise library
+	_, err = DB.Exec("ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'")
+	if err != nil && !isColumnExistsError(err) {
+		return fmt.Errorf("failed to add role column: %w", err)
+	}
+
+	// Public exercises can be deprecated instead of deleted
+	publicExerciseColumns := []string{
+		"ALTER TABLE public_exercises ADD COLUMN deprecated_at DATETIME",
+		"ALTER TABLE public_exercises ADD COLUMN replaced_by_id INTEGER",
+	}
+	for _, col := range publicExerciseColumns {
+		_, err := DB.Exec(col)
+		if err != nil && !isColumnExistsError(err) {
+			return fmt.Errorf("failed to add column: %w", err)
+		}
+	}
+
+	// Seeded public exercises are matched by a stable slug
+	_, err = DB.Exec("ALTER TABLE public_exercises ADD COLUMN slug TEXT")
+	if err != nil && !isColumnExistsError(err) {
+		return fmt.Errorf("failed to add slug column: %w", err)
+	}
+
+	_, err = DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_public_exercises_slug ON public_exercises(slug)")
+	if err != nil {
+		return fmt.Errorf("failed to create public exercise slug index: %w", err)
+	}
+
 	// Add TOTP columns to users if they don't exist
 	_, err = DB.Exec("ALTER TABLE users ADD COLUMN totp_secret TEXT")
 	if err != nil && !isColumnExistsError(err) {
@@ -241,6 +858,246 @@ func runMigrations() error {
 	return nil
 }
 
+// RunOnce applies a data migration in a transaction unless it has been
+// recorded in schema_migrations already
+func RunOnce(name string, migrate func(tx *sql.Tx) error) error {
+	var applied int
+	err := DB.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE name = ?", name).Scan(&applied)
+	if err != nil {
+		return err
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := migrate(tx); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO schema_migrations (name) VALUES (?)", name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// rebuildWorkoutLogs recreates workout_logs without the foreign key that tied
+// exercise_id to exercises and rejected logs of public exercises. SQLite
+// cannot drop a constraint, so the rows are copied into a new table. Foreign
+// keys are switched off on the connection doing it, or dropping the old
+// table would cascade to sets, laps and records.
+func rebuildWorkoutLogs() error {
+	var constrained int
+	err := DB.QueryRow(
+		`SELECT COUNT(*) FROM pragma_foreign_key_list('workout_logs') WHERE "table" = 'exercises'`,
+	).Scan(&constrained)
+	if err != nil || constrained == 0 {
+		return err
+	}
+
+	ctx := context.Background()
+	conn, err := DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Indexes go with the old table and are recreated afterwards
+	var indexes []string
+	rows, err := tx.Query("SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = 'workout_logs' AND sql IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var index string
+		if err := rows.Scan(&index); err != nil {
+			rows.Close()
+			return err
+		}
+		indexes = append(indexes, index)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var columns []string
+	rows, err = tx.Query("SELECT name FROM pragma_table_info('workout_logs')")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			rows.Close()
+			return err
+		}
+		columns = append(columns, column)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	columnList := strings.Join(columns, ", ")
+
+	statements := []string{
+		fmt.Sprintf(workoutLogsTable, "workout_logs_rebuilt"),
+		fmt.Sprintf("INSERT INTO workout_logs_rebuilt (%s) SELECT %s FROM workout_logs", columnList, columnList),
+		"DROP TABLE workout_logs",
+		"ALTER TABLE workout_logs_rebuilt RENAME TO workout_logs",
+	}
+	for _, statement := range append(statements, indexes...) {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// resolveWorkoutLogExerciseSource marks the logs that point at public
+// exercises. Logs used to be read as the user's own exercise whenever one
+// with the same ID existed and as a public exercise otherwise; that reading
+// is kept so no log changes exercise. Personal records follow their logs.
+func resolveWorkoutLogExerciseSource(tx *sql.Tx) error {
+	_, err := tx.Exec(
+		`UPDATE workout_logs SET exercise_source = 'public'
+		 WHERE NOT EXISTS (SELECT 1 FROM exercises e WHERE e.id = workout_logs.exercise_id AND e.user_id = workout_logs.user_id)
+		   AND EXISTS (SELECT 1 FROM public_exercises pe WHERE pe.id = workout_logs.exercise_id)`,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(
+		`UPDATE personal_records SET exercise_source = COALESCE(
+		   (SELECT wl.exercise_source FROM workout_logs wl WHERE wl.id = personal_records.workout_log_id),
+		   exercise_source)`,
+	)
+	return err
+}
+
+// migrateToMetricUnits converts stored pounds to kilograms, miles to
+// kilometers and minutes per mile to minutes per kilometer
+func migrateToMetricUnits(tx *sql.Tx) error {
+	const kgPerLb = 0.45359237
+	const kmPerMi = 1.609344
+
+	statements := []struct {
+		query  string
+		factor float64
+	}{
+		{"UPDATE workout_logs SET weight = weight * ? WHERE weight IS NOT NULL", kgPerLb},
+		{"UPDATE workout_logs SET distance = distance * ? WHERE distance IS NOT NULL", kmPerMi},
+		{"UPDATE workout_logs SET pace = pace / ? WHERE pace IS NOT NULL", kmPerMi},
+		{"UPDATE workout_sets SET weight = weight * ? WHERE weight IS NOT NULL", kgPerLb},
+		{"UPDATE workout_template_exercises SET target_weight = target_weight * ? WHERE target_weight IS NOT NULL", kgPerLb},
+		{"UPDATE workout_template_exercises SET target_distance = target_distance * ? WHERE target_distance IS NOT NULL", kmPerMi},
+		{"UPDATE program_prescriptions SET base_weight = base_weight * ?, weight_increment = weight_increment * ?", kgPerLb},
+		{"UPDATE program_prescriptions SET base_distance = base_distance * ?, distance_increment = distance_increment * ?", kmPerMi},
+		{"UPDATE program_training_maxes SET weight = weight * ?", kgPerLb},
+	}
+
+	for _, stmt := range statements {
+		args := make([]interface{}, strings.Count(stmt.query, "?"))
+		for i := range args {
+			args[i] = stmt.factor
+		}
+		if _, err := tx.Exec(stmt.query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateWeightPerSet moves the legacy weight_per_set JSON of workout logs into
+// workout_sets rows. Converted logs have weight_per_set cleared, so running it
+// again only picks up logs that still carry the old field.
+func migrateWeightPerSet() error {
+	rows, err := DB.Query(`
+		SELECT id, reps, weight_per_set FROM workout_logs
+		WHERE weight_per_set IS NOT NULL AND weight_per_set != ''
+		AND id NOT IN (SELECT workout_log_id FROM workout_sets)
+	`)
+	if err != nil {
+		return err
+	}
+
+	type legacyLog struct {
+		id           int64
+		reps         *int
+		weightPerSet string
+	}
+	var logs []legacyLog
+	for rows.Next() {
+		var l legacyLog
+		if err := rows.Scan(&l.id, &l.reps, &l.weightPerSet); err != nil {
+			rows.Close()
+			return err
+		}
+		logs = append(logs, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, l := range logs {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(l.weightPerSet), &parsed); err != nil {
+			fmt.Printf("Warning: skipping weight_per_set of workout log %d: %v\n", l.id, err)
+			continue
+		}
+		sets, err := models.WorkoutSetsFromWeightPerSet(parsed, l.reps)
+		if err != nil {
+			fmt.Printf("Warning: skipping weight_per_set of workout log %d: %v\n", l.id, err)
+			continue
+		}
+
+		tx, err := DB.Begin()
+		if err != nil {
+			return err
+		}
+		for _, set := range sets {
+			_, err = tx.Exec(
+				"INSERT INTO workout_sets (workout_log_id, set_index, reps, weight, set_type, rpe, tempo) VALUES (?, ?, ?, ?, ?, ?, ?)",
+				l.id, set.SetIndex, set.Reps, set.Weight, set.SetType, set.RPE, set.Tempo,
+			)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+		if _, err := tx.Exec("UPDATE workout_logs SET weight_per_set = NULL WHERE id = ?", l.id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		migrated++
+	}
+
+	if migrated > 0 {
+		fmt.Printf("Migrated weight_per_set of %d workout logs to workout_sets\n", migrated)
+	}
+	return nil
+}
+
 func isColumnExistsError(err error) bool {
 	if err == nil {
 		return false
@@ -250,149 +1107,100 @@ func isColumnExistsError(err error) bool {
 	return errStr != "" && (errStr == "duplicate column name" || strings.Contains(errStr, "duplicate column name"))
 }
 
-// seedPublicExercises seeds the database with common public exercises
-func seedPublicExercises() error {
-	// Check if public exercises already exist
-	var count int
-	err := DB.QueryRow("SELECT COUNT(*) FROM public_exercises").Scan(&count)
+// migrateLapTimes moves the legacy lap_times JSON of workout logs into
+// workout_laps rows. Converted logs have lap_times cleared, so running it
+// again only picks up logs written by older versions. Legacy lap distances
+// were entered in miles, the only unit at the time.
+func migrateLapTimes() error {
+	rows, err := DB.Query(`
+		SELECT id, lap_times FROM workout_logs
+		WHERE lap_times IS NOT NULL AND lap_times != ''
+		AND id NOT IN (SELECT workout_log_id FROM workout_laps)
+	`)
 	if err != nil {
-		return fmt.Errorf("failed to check public exercises: %w", err)
+		return err
 	}
 
-	// If exercises already exist, skip seeding
-	if count > 0 {
-		return nil
+	type legacyLog struct {
+		id       int64
+		lapTimes string
+	}
+	var logs []legacyLog
+	for rows.Next() {
+		var l legacyLog
+		if err := rows.Scan(&l.id, &l.lapTimes); err != nil {
+			rows.Close()
+			return err
+		}
+		logs = append(logs, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
-	// Common strength exercises
-	strengthExercises := []struct {
-		name         string
-		muscleGroup  string
-		equipment    string
-		description  string
-		instructions string
-	}{
-		{
-			name:        "Bench Press",
-			muscleGroup: "Chest, Triceps, Shoulders",
-			equipment:   "Barbell, Bench",
-			description: "A compound exercise that targets the chest, shoulders, and triceps.",
-			instructions: "Lie on bench, grip bar slightly wider than shoulders. Lower bar to chest, then press up.",
-		},
-		{
-			name:        "Squat",
-			muscleGroup: "Quadriceps, Glutes, Hamstrings",
-			equipment:   "Barbell",
-			description: "A fundamental lower body exercise targeting the quadriceps, glutes, and hamstrings.",
-			instructions: "Stand with feet shoulder-width apart, bar on upper back. Lower by bending knees and hips, then stand up.",
-		},
-		{
-			name:        "Deadlift",
-			muscleGroup: "Back, Glutes, Hamstrings",
-			equipment:   "Barbell",
-			description: "A compound exercise that works the entire posterior chain.",
-			instructions: "Stand with feet hip-width apart, bar over mid-foot. Hinge at hips, grip bar, then lift by extending hips and knees.",
-		},
-		{
-			name:        "Overhead Press",
-			muscleGroup: "Shoulders, Triceps",
-			equipment:   "Barbell",
-			description: "A shoulder-focused exercise that also works the triceps and core.",
-			instructions: "Stand with feet shoulder-width apart, bar at shoulder height. Press bar overhead until arms are fully extended.",
-		},
-		{
-			name:        "Barbell Row",
-			muscleGroup: "Back, Biceps",
-			equipment:   "Barbell",
-			description: "A pulling exercise that targets the back muscles and biceps.",
-			instructions: "Bend at hips, grip bar with overhand grip. Pull bar to lower chest/upper abdomen, then lower with control.",
-		},
-		{
-			name:        "Pull-ups",
-			muscleGroup: "Back, Biceps",
-			equipment:   "Pull-up Bar",
-			description: "A bodyweight exercise that targets the back and biceps.",
-			instructions: "Hang from bar with palms facing away. Pull body up until chin is over bar, then lower with control.",
-		},
-		{
-			name:        "Dips",
-			muscleGroup: "Triceps, Chest, Shoulders",
-			equipment:   "Parallel Bars",
-			description: "A bodyweight exercise targeting the triceps, chest, and shoulders.",
-			instructions: "Support body on parallel bars. Lower by bending arms, then press up to starting position.",
-		},
-		{
-			name:        "Bicep Curls",
-			muscleGroup: "Biceps",
-			equipment:   "Dumbbells, Barbell",
-			description: "An isolation exercise targeting the biceps.",
-			instructions: "Stand holding weights at sides. Curl weights up by flexing biceps, then lower with control.",
-		},
-	}
-
-	// Common cardio exercises
-	cardioExercises := []struct {
-		name         string
-		muscleGroup  string
-		equipment    string
-		description  string
-		instructions string
-	}{
-		{
-			name:        "Running",
-			muscleGroup: "Full Body",
-			equipment:   "None",
-			description: "A cardiovascular exercise that improves endurance and burns calories.",
-			instructions: "Start with a warm-up walk, then gradually increase to running pace. Maintain steady breathing.",
-		},
-		{
-			name:        "Cycling",
-			muscleGroup: "Legs, Cardiovascular",
-			equipment:   "Bicycle",
-			description: "A low-impact cardiovascular exercise that strengthens the legs.",
-			instructions: "Adjust seat height so leg is almost fully extended at bottom of pedal stroke. Maintain steady cadence.",
-		},
-		{
-			name:        "Rowing",
-			muscleGroup: "Full Body",
-			equipment:   "Rowing Machine",
-			description: "A full-body cardiovascular exercise that works legs, core, and upper body.",
-			instructions: "Start with legs extended, lean back slightly, pull handle to chest. Return to starting position in reverse order.",
-		},
-		{
-			name:        "Swimming",
-			muscleGroup: "Full Body",
-			equipment:   "Pool",
-			description: "A full-body, low-impact cardiovascular exercise.",
-			instructions: "Use proper stroke technique. Focus on breathing rhythm and efficient movement through the water.",
-		},
-	}
-
-	// Insert strength exercises
-	for _, ex := range strengthExercises {
-		_, err := DB.Exec(
-			`INSERT INTO public_exercises (name, exercise_type, muscle_group, equipment, description, instructions)
-			 VALUES (?, ?, ?, ?, ?, ?)`,
-			ex.name, "strength", ex.muscleGroup, ex.equipment, ex.description, ex.instructions,
-		)
+	legacyUnits := utils.Units{Weight: utils.WeightUnitLb, Distance: utils.DistanceUnitMi}
+	migrated := 0
+	for _, l := range logs {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(l.lapTimes), &parsed); err != nil {
+			fmt.Printf("Warning: skipping lap_times of workout log %d: %v\n", l.id, err)
+			continue
+		}
+		laps, err := models.WorkoutLapsFromLapTimes(parsed)
 		if err != nil {
-			return fmt.Errorf("failed to insert public exercise %s: %w", ex.name, err)
+			fmt.Printf("Warning: skipping lap_times of workout log %d: %v\n", l.id, err)
+			continue
 		}
-	}
 
-	// Insert cardio exercises
-	for _, ex := range cardioExercises {
-		_, err := DB.Exec(
-			`INSERT INTO public_exercises (name, exercise_type, muscle_group, equipment, description, instructions)
-			 VALUES (?, ?, ?, ?, ?, ?)`,
-			ex.name, "cardio", ex.muscleGroup, ex.equipment, ex.description, ex.instructions,
-		)
+		tx, err := DB.Begin()
 		if err != nil {
-			return fmt.Errorf("failed to insert public exercise %s: %w", ex.name, err)
+			return err
+		}
+		for _, lap := range laps {
+			if lap.Distance != nil {
+				km := legacyUnits.DistanceToCanonical(*lap.Distance)
+				lap.Distance = &km
+			}
+			_, err = tx.Exec(
+				"INSERT INTO workout_laps (workout_log_id, lap_index, label, distance, duration_seconds, heart_rate) VALUES (?, ?, ?, ?, ?, ?)",
+				l.id, lap.LapIndex, lap.Label, lap.Distance, lap.DurationSeconds, lap.HeartRate,
+			)
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
 		}
+		if _, err := tx.Exec("UPDATE workout_logs SET lap_times = NULL WHERE id = ?", l.id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		migrated++
 	}
 
-	fmt.Println("Public exercises seeded successfully")
+	if migrated > 0 {
+		fmt.Printf("Migrated lap_times of %d workout logs to workout_laps\n", migrated)
+	}
 	return nil
 }
 
+// deriveCardioPace replaces missing paces, and paces more than 5% off what
+// distance and duration imply, with the derived pace in minutes per kilometer
+func deriveCardioPace(tx *sql.Tx) error {
+	result, err := tx.Exec(`
+		UPDATE workout_logs
+		SET pace = CAST(duration AS REAL) / distance
+		WHERE distance > 0 AND duration > 0
+		AND (pace IS NULL OR ABS(pace - CAST(duration AS REAL) / distance) > 0.05 * CAST(duration AS REAL) / distance)
+	`)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n > 0 {
+		fmt.Printf("Derived pace of %d cardio workout logs\n", n)
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gym-app-backend/models"
+)
+
+// publicExerciseData is the public exercise library shipped with the binary.
+// Bump its version whenever entries are added or corrected so existing
+// databases pick the changes up on their next start.
+//
+//go:embed seed/public_exercises.json
+var publicExerciseData []byte
+
+var seedSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+type publicExerciseSeed struct {
+	Version   int                   `json:"version"`
+	Exercises []publicExerciseEntry `json:"exercises"`
+}
+
+// publicExerciseEntry is one exercise of the data file. The slug identifies
+// it across versions, so names and everything else can be corrected. The
+// taxonomy lists are optional and mapped from muscle_group and equipment
+// when left out.
+type publicExerciseEntry struct {
+	Slug             string                 `json:"slug"`
+	Name             string                 `json:"name"`
+	ExerciseType     string                 `json:"exercise_type"`
+	MuscleGroup      *string                `json:"muscle_group"`
+	Equipment        *string                `json:"equipment"`
+	Description      *string                `json:"description"`
+	Instructions     *string                `json:"instructions"`
+	VideoLink        *string                `json:"video_link"`
+	ImageLink        *string                `json:"image_link"`
+	Muscles          []publicExerciseMuscle `json:"muscles"`
+	EquipmentItems   []string               `json:"equipment_items"`
+	MovementPatterns []string               `json:"movement_patterns"`
+	Deprecated       bool                   `json:"deprecated"`
+	ReplacedBy       string                 `json:"replaced_by"` // slug of the exercise to use instead
+}
+
+type publicExerciseMuscle struct {
+	Slug string `json:"slug"`
+	Role string `json:"role"`
+}
+
+// loadPublicExerciseSeed parses and checks the embedded data file
+func loadPublicExerciseSeed() (*publicExerciseSeed, error) {
+	decoder := json.NewDecoder(bytes.NewReader(publicExerciseData))
+	decoder.DisallowUnknownFields()
+	var seed publicExerciseSeed
+	if err := decoder.Decode(&seed); err != nil {
+		return nil, err
+	}
+	if seed.Version < 1 {
+		return nil, fmt.Errorf("version must be at least 1")
+	}
+
+	slugs := make(map[string]bool, len(seed.Exercises))
+	names := make(map[string]bool, len(seed.Exercises))
+	for i := range seed.Exercises {
+		ex := &seed.Exercises[i]
+		if !seedSlugPattern.MatchString(ex.Slug) {
+			return nil, fmt.Errorf("invalid slug %q", ex.Slug)
+		}
+		if slugs[ex.Slug] {
+			return nil, fmt.Errorf("duplicate slug %q", ex.Slug)
+		}
+		slugs[ex.Slug] = true
+
+		ex.Name = strings.TrimSpace(ex.Name)
+		if ex.Name == "" {
+			return nil, fmt.Errorf("%s: name is required", ex.Slug)
+		}
+		if names[strings.ToLower(ex.Name)] {
+			return nil, fmt.Errorf("%s: duplicate name %q", ex.Slug, ex.Name)
+		}
+		names[strings.ToLower(ex.Name)] = true
+
+		if ex.ExerciseType == "" {
+			ex.ExerciseType = models.ExerciseTypeStrength
+		}
+		if !models.IsValidExerciseType(ex.ExerciseType) {
+			return nil, fmt.Errorf("%s: unknown exercise_type %q", ex.Slug, ex.ExerciseType)
+		}
+		for _, m := range ex.Muscles {
+			if !models.IsValidMuscle(m.Slug) || !models.IsValidMuscleRole(m.Role) {
+				return nil, fmt.Errorf("%s: unknown muscle %q or role %q", ex.Slug, m.Slug, m.Role)
+			}
+		}
+		for _, slug := range ex.EquipmentItems {
+			if !models.IsValidEquipment(slug) {
+				return nil, fmt.Errorf("%s: unknown equipment %q", ex.Slug, slug)
+			}
+		}
+		for _, slug := range ex.MovementPatterns {
+			if !models.IsValidMovementPattern(slug) {
+				return nil, fmt.Errorf("%s: unknown movement pattern %q", ex.Slug, slug)
+			}
+		}
+	}
+
+	for _, ex := range seed.Exercises {
+		if ex.ReplacedBy == "" {
+			continue
+		}
+		if !ex.Deprecated || ex.ReplacedBy == ex.Slug || !slugs[ex.ReplacedBy] {
+			return nil, fmt.Errorf("%s: replaced_by %q must be the slug of another exercise, on a deprecated entry", ex.Slug, ex.ReplacedBy)
+		}
+	}
+	return &seed, nil
+}
+
+// seedPublicExercises upserts the embedded public exercise library by slug.
+// Each version of the data file is applied once, so edits admins make
+// through the API survive restarts until a newer file ships.
+func seedPublicExercises() error {
+	seed, err := loadPublicExerciseSeed()
+	if err != nil {
+		return fmt.Errorf("invalid public exercise data: %w", err)
+	}
+	return RunOnce(fmt.Sprintf("seed_public_exercises_v%d", seed.Version), func(tx *sql.Tx) error {
+		return syncPublicExercises(tx, seed)
+	})
+}
+
+// syncPublicExercises inserts the exercises of the data file that the
+// database lacks and overwrites the others. Rows seeded before exercises had
+// slugs are adopted by name instead of duplicated. Exercises missing from
+// the file, such as those admins added, are left alone.
+func syncPublicExercises(tx *sql.Tx, seed *publicExerciseSeed) error {
+	ids := make(map[string]int64, len(seed.Exercises))
+	var added, updated int
+
+	for _, ex := range seed.Exercises {
+		muscleGroup, equipment := ex.MuscleGroup, ex.Equipment
+		if muscleGroup == nil && ex.Muscles != nil {
+			text := models.MuscleGroupText(ex.exerciseMuscles())
+			muscleGroup = &text
+		}
+		if equipment == nil && ex.EquipmentItems != nil {
+			text := models.EquipmentText(ex.EquipmentItems)
+			equipment = &text
+		}
+
+		var id int64
+		err := tx.QueryRow(
+			`SELECT id FROM public_exercises
+			 WHERE slug = ? OR (slug IS NULL AND LOWER(name) = LOWER(?))
+			 ORDER BY slug IS NULL, id LIMIT 1`,
+			ex.Slug, ex.Name,
+		).Scan(&id)
+
+		switch {
+		case err == sql.ErrNoRows:
+			result, err := tx.Exec(
+				`INSERT INTO public_exercises (slug, name, exercise_type, muscle_group, equipment, description, instructions, video_link, image_link)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				ex.Slug, ex.Name, ex.ExerciseType, muscleGroup, equipment,
+				ex.Description, ex.Instructions, ex.VideoLink, ex.ImageLink,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to insert public exercise %s: %w", ex.Slug, err)
+			}
+			id, _ = result.LastInsertId()
+			added++
+		case err != nil:
+			return err
+		default:
+			_, err := tx.Exec(
+				`UPDATE public_exercises SET slug = ?, name = ?, exercise_type = ?, muscle_group = ?, equipment = ?,
+				 description = ?, instructions = ?, video_link = ?, image_link = ?
+				 WHERE id = ?`,
+				ex.Slug, ex.Name, ex.ExerciseType, muscleGroup, equipment,
+				ex.Description, ex.Instructions, ex.VideoLink, ex.ImageLink, id,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update public exercise %s: %w", ex.Slug, err)
+			}
+			updated++
+		}
+		ids[ex.Slug] = id
+
+		if err := MapExerciseTaxonomy(tx, id, "public", ex.Name, ex.ExerciseType, muscleGroup, equipment); err != nil {
+			return fmt.Errorf("failed to map public exercise %s: %w", ex.Slug, err)
+		}
+		if ex.Muscles != nil {
+			if err := SetExerciseMuscles(tx, id, "public", ex.exerciseMuscles()); err != nil {
+				return err
+			}
+		}
+		if ex.EquipmentItems != nil {
+			if err := SetExerciseEquipment(tx, id, "public", ex.EquipmentItems); err != nil {
+				return err
+			}
+		}
+		if ex.MovementPatterns != nil {
+			if err := SetExerciseMovementPatterns(tx, id, "public", ex.MovementPatterns); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Deprecations go last so replacements are known. Exercises the file
+	// keeps active are not restored, since an admin may have retired them.
+	for _, ex := range seed.Exercises {
+		if !ex.Deprecated {
+			continue
+		}
+		var replacedByID *int64
+		if ex.ReplacedBy != "" {
+			id := ids[ex.ReplacedBy]
+			replacedByID = &id
+		}
+		_, err := tx.Exec(
+			"UPDATE public_exercises SET deprecated_at = COALESCE(deprecated_at, CURRENT_TIMESTAMP), replaced_by_id = ? WHERE id = ?",
+			replacedByID, ids[ex.Slug],
+		)
+		if err != nil {
+			return fmt.Errorf("failed to deprecate public exercise %s: %w", ex.Slug, err)
+		}
+	}
+
+	fmt.Printf("Public exercise library synced to version %d (%d added, %d updated)\n", seed.Version, added, updated)
+	return nil
+}
+
+func (ex publicExerciseEntry) exerciseMuscles() []models.ExerciseMuscle {
+	muscles := make([]models.ExerciseMuscle, 0, len(ex.Muscles))
+	for _, m := range ex.Muscles {
+		muscles = append(muscles, models.ExerciseMuscle{Slug: m.Slug, Role: m.Role})
+	}
+	return muscles
+}
@@ -0,0 +1,194 @@
+package database
+
+import (
+	"database/sql"
+
+	"gym-app-backend/models"
+)
+
+// Execer is satisfied by both *sql.DB and *sql.Tx
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// syncTaxonomyCatalog adds the muscles, equipment and movement patterns of
+// the catalog that the database lacks and refreshes the names of the others
+func syncTaxonomyCatalog() error {
+	for _, m := range models.MuscleCatalog {
+		_, err := DB.Exec(
+			`INSERT INTO muscles (slug, name, region) VALUES (?, ?, ?)
+			 ON CONFLICT (slug) DO UPDATE SET name = excluded.name, region = excluded.region`,
+			m.Slug, m.Name, m.Region,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	catalogs := map[string][]models.TaxonomyItem{
+		"equipment":         models.EquipmentCatalog,
+		"movement_patterns": models.MovementPatternCatalog,
+	}
+	for table, items := range catalogs {
+		for _, item := range items {
+			_, err := DB.Exec(
+				"INSERT INTO "+table+" (slug, name) VALUES (?, ?) ON CONFLICT (slug) DO UPDATE SET name = excluded.name",
+				item.Slug, item.Name,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SetExerciseMuscles replaces the muscles an exercise is linked to. source is
+// "private" or "public"; slugs must be valid muscles.
+func SetExerciseMuscles(e Execer, exerciseID int64, source string, muscles []models.ExerciseMuscle) error {
+	_, err := e.Exec("DELETE FROM exercise_muscles WHERE exercise_id = ? AND exercise_source = ?", exerciseID, source)
+	if err != nil {
+		return err
+	}
+	for _, m := range muscles {
+		_, err := e.Exec(
+			`INSERT INTO exercise_muscles (exercise_id, exercise_source, muscle_id, role)
+			 SELECT ?, ?, id, ? FROM muscles WHERE slug = ?`,
+			exerciseID, source, m.Role, m.Slug,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetExerciseEquipment replaces the equipment an exercise is linked to
+func SetExerciseEquipment(e Execer, exerciseID int64, source string, slugs []string) error {
+	_, err := e.Exec("DELETE FROM exercise_equipment WHERE exercise_id = ? AND exercise_source = ?", exerciseID, source)
+	if err != nil {
+		return err
+	}
+	for _, slug := range slugs {
+		_, err := e.Exec(
+			`INSERT INTO exercise_equipment (exercise_id, exercise_source, equipment_id)
+			 SELECT ?, ?, id FROM equipment WHERE slug = ?`,
+			exerciseID, source, slug,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetExerciseMovementPatterns replaces the movement patterns an exercise is linked to
+func SetExerciseMovementPatterns(e Execer, exerciseID int64, source string, slugs []string) error {
+	_, err := e.Exec("DELETE FROM exercise_movement_patterns WHERE exercise_id = ? AND exercise_source = ?", exerciseID, source)
+	if err != nil {
+		return err
+	}
+	for _, slug := range slugs {
+		_, err := e.Exec(
+			`INSERT INTO exercise_movement_patterns (exercise_id, exercise_source, movement_pattern_id)
+			 SELECT ?, ?, id FROM movement_patterns WHERE slug = ?`,
+			exerciseID, source, slug,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteExerciseTaxonomy removes every taxonomy link of an exercise
+func DeleteExerciseTaxonomy(e Execer, exerciseID int64, source string) error {
+	for _, table := range []string{"exercise_muscles", "exercise_equipment", "exercise_movement_patterns"} {
+		_, err := e.Exec("DELETE FROM "+table+" WHERE exercise_id = ? AND exercise_source = ?", exerciseID, source)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MapExerciseTaxonomy links an exercise to the taxonomy from its free-text
+// muscle_group and equipment, inferring the movement pattern from its name
+func MapExerciseTaxonomy(e Execer, exerciseID int64, source, name, exerciseType string, muscleGroup, equipment *string) error {
+	var muscleText, equipmentText string
+	if muscleGroup != nil {
+		muscleText = *muscleGroup
+	}
+	if equipment != nil {
+		equipmentText = *equipment
+	}
+
+	if err := SetExerciseMuscles(e, exerciseID, source, models.MapMuscleGroup(muscleText)); err != nil {
+		return err
+	}
+	if err := SetExerciseEquipment(e, exerciseID, source, models.MapEquipment(equipmentText)); err != nil {
+		return err
+	}
+	return SetExerciseMovementPatterns(e, exerciseID, source, models.InferMovementPatterns(name, exerciseType))
+}
+
+// mapExerciseTaxonomy links every private and public exercise to the
+// taxonomy from its free text
+func mapExerciseTaxonomy(tx *sql.Tx) error {
+	type exercise struct {
+		id           int64
+		source       string
+		name         string
+		exerciseType sql.NullString
+		muscleGroup  *string
+		equipment    *string
+	}
+
+	rows, err := tx.Query(
+		`SELECT id, 'private', name, exercise_type, muscle_group, equipment FROM exercises
+		 UNION ALL
+		 SELECT id, 'public', name, exercise_type, muscle_group, equipment FROM public_exercises`,
+	)
+	if err != nil {
+		return err
+	}
+	var exercises []exercise
+	for rows.Next() {
+		var ex exercise
+		if err := rows.Scan(&ex.id, &ex.source, &ex.name, &ex.exerciseType, &ex.muscleGroup, &ex.equipment); err != nil {
+			rows.Close()
+			return err
+		}
+		exercises = append(exercises, ex)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, ex := range exercises {
+		if err := MapExerciseTaxonomy(tx, ex.id, ex.source, ex.name, ex.exerciseType.String, ex.muscleGroup, ex.equipment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyExerciseTaxonomy links an exercise to the same taxonomy as another
+func CopyExerciseTaxonomy(e Execer, fromID int64, fromSource string, toID int64, toSource string) error {
+	queries := []string{
+		`INSERT INTO exercise_muscles (exercise_id, exercise_source, muscle_id, role)
+		 SELECT ?, ?, muscle_id, role FROM exercise_muscles WHERE exercise_id = ? AND exercise_source = ?`,
+		`INSERT INTO exercise_equipment (exercise_id, exercise_source, equipment_id)
+		 SELECT ?, ?, equipment_id FROM exercise_equipment WHERE exercise_id = ? AND exercise_source = ?`,
+		`INSERT INTO exercise_movement_patterns (exercise_id, exercise_source, movement_pattern_id)
+		 SELECT ?, ?, movement_pattern_id FROM exercise_movement_patterns WHERE exercise_id = ? AND exercise_source = ?`,
+	}
+	for _, query := range queries {
+		if _, err := e.Exec(query, toID, toSource, fromID, fromSource); err != nil {
+			return err
+		}
+	}
+	return nil
+}
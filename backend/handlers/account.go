@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+	"gym-app-backend/utils"
+)
+
+// maxArchiveSize caps the size of an uploaded account archive
+const maxArchiveSize = 100 << 20
+
+type AccountImportResponse struct {
+	Summary *services.AccountImportSummary `json:"summary"`
+}
+
+// unsafeFilenameChars are replaced when a username is put in a download name
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// ExportAccount downloads the authenticated user's data as a versioned JSON
+// archive that ImportAccount can restore on any instance
+func ExportAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	archive, err := services.ExportAccount(userID)
+	if err != nil {
+		fmt.Printf("Export account error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("gym-app-%s-%s.json",
+		unsafeFilenameChars.ReplaceAllString(archive.Profile.Username, "_"), utils.FormatDate(time.Now()))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	json.NewEncoder(w).Encode(archive)
+}
+
+// ImportAccount restores an archive produced by ExportAccount into the
+// authenticated user's account, remapping every ID. The archive is the JSON
+// request body. Accounts that already hold workout logs are only imported into
+// with merge=true, so a restore is not accidentally applied twice.
+func ImportAccount(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	var archive models.AccountArchive
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveSize)
+	if err := json.NewDecoder(r.Body).Decode(&archive); err != nil {
+		http.Error(w, `{"error":"Invalid archive"}`, http.StatusBadRequest)
+		return
+	}
+	if err := services.ValidateAccountArchive(&archive); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if r.URL.Query().Get("merge") != "true" {
+		var logCount int
+		err := database.DB.QueryRow("SELECT COUNT(*) FROM workout_logs WHERE user_id = ?", userID).Scan(&logCount)
+		if err != nil {
+			fmt.Printf("Import account error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if logCount > 0 {
+			http.Error(w, `{"error":"This account already has workout logs; pass merge=true to add the archive to them"}`, http.StatusConflict)
+			return
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Import account error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	summary, err := services.ImportAccount(tx, userID, &archive)
+	if err != nil {
+		fmt.Printf("Import account error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Import account error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := AccountImportResponse{Summary: summary}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
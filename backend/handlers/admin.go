@@ -0,0 +1,480 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/models"
+)
+
+type PublicExerciseRequest struct {
+	Name         *string `json:"name"`
+	ExerciseType *string `json:"exercise_type"`
+	MuscleGroup  *string `json:"muscle_group"`
+	Equipment    *string `json:"equipment"`
+	Description  *string `json:"description"`
+	Instructions *string `json:"instructions"`
+	VideoLink    *string `json:"video_link"`
+	ImageLink    *string `json:"image_link"`
+	ExerciseTaxonomyRequest
+}
+
+type DeprecatePublicExerciseRequest struct {
+	ReplacedByID *int64 `json:"replaced_by_id"` // exercise users should move to
+}
+
+// parseAdminPublicExerciseID reads the ID from /api/admin/public-exercises/:id[/action]
+func parseAdminPublicExerciseID(r *http.Request, action string) (int64, error) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/admin/public-exercises/")
+	if action != "" {
+		path = strings.TrimSuffix(path, "/"+action)
+	}
+	return strconv.ParseInt(path, 10, 64)
+}
+
+// publicExerciseNameTaken reports whether another public exercise already
+// uses the name, ignoring case. Archive imports match public exercises by
+// name, so names must stay unique.
+func publicExerciseNameTaken(name string, exceptID int64) (bool, error) {
+	var id int64
+	err := database.DB.QueryRow(
+		"SELECT id FROM public_exercises WHERE LOWER(name) = LOWER(?) AND id != ? LIMIT 1",
+		strings.TrimSpace(name), exceptID,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreatePublicExercise adds an exercise to the public library (admin only)
+func CreatePublicExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req PublicExerciseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		http.Error(w, `{"error":"Exercise name is required"}`, http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(*req.Name)
+
+	exerciseType := models.ExerciseTypeStrength
+	if req.ExerciseType != nil {
+		if !models.IsValidExerciseType(*req.ExerciseType) {
+			http.Error(w, exerciseTypeError, http.StatusBadRequest)
+			return
+		}
+		exerciseType = *req.ExerciseType
+	}
+
+	if err := req.ExerciseTaxonomyRequest.validate(); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	muscleGroup, equipment := taxonomyText(req.ExerciseTaxonomyRequest, req.MuscleGroup, req.Equipment)
+
+	taken, err := publicExerciseNameTaken(name, 0)
+	if err != nil {
+		fmt.Printf("Create public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if taken {
+		http.Error(w, `{"error":"A public exercise with this name already exists"}`, http.StatusConflict)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Create public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO public_exercises (name, exercise_type, muscle_group, equipment, description, instructions, video_link, image_link)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		name, exerciseType, muscleGroup, equipment,
+		req.Description, req.Instructions, req.VideoLink, req.ImageLink,
+	)
+	if err != nil {
+		fmt.Printf("Create public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	exerciseID, _ := result.LastInsertId()
+
+	inferPattern := func() []string { return models.InferMovementPatterns(name, exerciseType) }
+	err = applyExerciseTaxonomy(tx, exerciseID, "public", req.ExerciseTaxonomyRequest, muscleGroup, equipment, inferPattern)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Create public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ex, err := fetchPublicExercise(exerciseID)
+	if err != nil {
+		fmt.Printf("Error fetching created public exercise: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := PublicExerciseResponse{Exercise: ex}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdatePublicExercise edits an exercise of the public library (admin only)
+func UpdatePublicExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	exerciseID, err := parseAdminPublicExerciseID(r, "")
+	if err != nil {
+		http.Error(w, `{"error":"Invalid exercise ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var existingID int64
+	err = database.DB.QueryRow("SELECT id FROM public_exercises WHERE id = ?", exerciseID).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Public exercise not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Update public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var req PublicExerciseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := req.ExerciseTaxonomyRequest.validate(); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	req.MuscleGroup, req.Equipment = taxonomyText(req.ExerciseTaxonomyRequest, req.MuscleGroup, req.Equipment)
+
+	// Build update query dynamically
+	updates := []string{}
+	values := []interface{}{}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			http.Error(w, `{"error":"Exercise name is required"}`, http.StatusBadRequest)
+			return
+		}
+		taken, err := publicExerciseNameTaken(name, exerciseID)
+		if err != nil {
+			fmt.Printf("Update public exercise error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if taken {
+			http.Error(w, `{"error":"A public exercise with this name already exists"}`, http.StatusConflict)
+			return
+		}
+		updates = append(updates, "name = ?")
+		values = append(values, name)
+	}
+	if req.ExerciseType != nil {
+		if !models.IsValidExerciseType(*req.ExerciseType) {
+			http.Error(w, exerciseTypeError, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "exercise_type = ?")
+		values = append(values, *req.ExerciseType)
+	}
+	if req.MuscleGroup != nil {
+		updates = append(updates, "muscle_group = ?")
+		values = append(values, *req.MuscleGroup)
+	}
+	if req.Equipment != nil {
+		updates = append(updates, "equipment = ?")
+		values = append(values, *req.Equipment)
+	}
+	if req.Description != nil {
+		updates = append(updates, "description = ?")
+		values = append(values, *req.Description)
+	}
+	if req.Instructions != nil {
+		updates = append(updates, "instructions = ?")
+		values = append(values, *req.Instructions)
+	}
+	if req.VideoLink != nil {
+		updates = append(updates, "video_link = ?")
+		values = append(values, *req.VideoLink)
+	}
+	if req.ImageLink != nil {
+		updates = append(updates, "image_link = ?")
+		values = append(values, *req.ImageLink)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Update public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if len(updates) > 0 {
+		values = append(values, exerciseID)
+		query := fmt.Sprintf("UPDATE public_exercises SET %s WHERE id = ?", strings.Join(updates, ", "))
+
+		if _, err := tx.Exec(query, values...); err != nil {
+			fmt.Printf("Update public exercise error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = applyExerciseTaxonomy(tx, exerciseID, "public", req.ExerciseTaxonomyRequest, req.MuscleGroup, req.Equipment, nil)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Update public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ex, err := fetchPublicExercise(exerciseID)
+	if err != nil {
+		fmt.Printf("Error fetching updated public exercise: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := PublicExerciseResponse{Exercise: ex}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeprecatePublicExercise hides an exercise from the public library without
+// touching anything that references it (admin only). Workout logs, templates,
+// programs and goals keep resolving it by ID; replaced_by_id optionally
+// points users to the exercise that supersedes it.
+func DeprecatePublicExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	exerciseID, err := parseAdminPublicExerciseID(r, "deprecate")
+	if err != nil {
+		http.Error(w, `{"error":"Invalid exercise ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req DeprecatePublicExerciseRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var existingID int64
+	err = database.DB.QueryRow("SELECT id FROM public_exercises WHERE id = ?", exerciseID).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Public exercise not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Deprecate public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if req.ReplacedByID != nil {
+		if *req.ReplacedByID == exerciseID {
+			http.Error(w, `{"error":"An exercise cannot replace itself"}`, http.StatusBadRequest)
+			return
+		}
+		var deprecatedAt sql.NullTime
+		err := database.DB.QueryRow(
+			"SELECT deprecated_at FROM public_exercises WHERE id = ?", *req.ReplacedByID,
+		).Scan(&deprecatedAt)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Replacement exercise not found"}`, http.StatusBadRequest)
+			return
+		} else if err != nil {
+			fmt.Printf("Deprecate public exercise error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if deprecatedAt.Valid {
+			http.Error(w, `{"error":"Replacement exercise is deprecated"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Deprecating again keeps the original date but may change the replacement
+	_, err = database.DB.Exec(
+		`UPDATE public_exercises SET deprecated_at = COALESCE(deprecated_at, CURRENT_TIMESTAMP), replaced_by_id = ?
+		 WHERE id = ?`,
+		req.ReplacedByID, exerciseID,
+	)
+	if err != nil {
+		fmt.Printf("Deprecate public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ex, err := fetchPublicExercise(exerciseID)
+	if err != nil {
+		fmt.Printf("Error fetching deprecated public exercise: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := PublicExerciseResponse{Exercise: ex}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RestorePublicExercise puts a deprecated exercise back in the public library (admin only)
+func RestorePublicExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	exerciseID, err := parseAdminPublicExerciseID(r, "restore")
+	if err != nil {
+		http.Error(w, `{"error":"Invalid exercise ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec(
+		"UPDATE public_exercises SET deprecated_at = NULL, replaced_by_id = NULL WHERE id = ?",
+		exerciseID,
+	)
+	if err != nil {
+		fmt.Printf("Restore public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		http.Error(w, `{"error":"Public exercise not found"}`, http.StatusNotFound)
+		return
+	}
+
+	ex, err := fetchPublicExercise(exerciseID)
+	if err != nil {
+		fmt.Printf("Error fetching restored public exercise: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := PublicExerciseResponse{Exercise: ex}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// publicExerciseReferences lists the queries that find rows pointing at a
+// public exercise
+var publicExerciseReferences = []string{
+	"SELECT 1 FROM workout_logs WHERE exercise_id = ? AND exercise_source = 'public'",
+	"SELECT 1 FROM workout_template_exercises WHERE exercise_id = ? AND exercise_source = 'public'",
+	"SELECT 1 FROM program_prescriptions WHERE exercise_id = ? AND exercise_source = 'public'",
+	"SELECT 1 FROM program_training_maxes WHERE exercise_id = ? AND exercise_source = 'public'",
+	"SELECT 1 FROM progression_rules WHERE exercise_id = ? AND exercise_source = 'public'",
+	"SELECT 1 FROM exercise_custom_fields WHERE exercise_id = ? AND exercise_source = 'public'",
+	"SELECT 1 FROM goals WHERE exercise_id = ? AND exercise_source = 'public'",
+}
+
+// DeletePublicExercise removes an exercise from the public library (admin
+// only). Exercises that anything still references cannot be deleted, only
+// deprecated, so no workout log is ever left pointing at nothing.
+func DeletePublicExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	exerciseID, err := parseAdminPublicExerciseID(r, "")
+	if err != nil {
+		http.Error(w, `{"error":"Invalid exercise ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Delete public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var existingID int64
+	err = tx.QueryRow("SELECT id FROM public_exercises WHERE id = ?", exerciseID).Scan(&existingID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Public exercise not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Delete public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	for _, query := range publicExerciseReferences {
+		var found int
+		err := tx.QueryRow(query+" LIMIT 1", exerciseID).Scan(&found)
+		if err == nil {
+			http.Error(w, `{"error":"Public exercise is in use; deprecate it instead"}`, http.StatusConflict)
+			return
+		} else if err != sql.ErrNoRows {
+			fmt.Printf("Delete public exercise error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = database.DeleteExerciseTaxonomy(tx, exerciseID, "public")
+	if err == nil {
+		_, err = tx.Exec("UPDATE public_exercises SET replaced_by_id = NULL WHERE replaced_by_id = ?", exerciseID)
+	}
+	if err == nil {
+		_, err = tx.Exec("UPDATE exercises SET source_public_exercise_id = NULL WHERE source_public_exercise_id = ?", exerciseID)
+	}
+	if err == nil {
+		_, err = tx.Exec("DELETE FROM public_exercises WHERE id = ?", exerciseID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Delete public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Public exercise deleted successfully"})
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+	"gym-app-backend/utils"
+)
+
+// Share of a set credited to the muscles of an exercise by their role
+const (
+	primaryMuscleShare   = 1.0
+	secondaryMuscleShare = 0.5
+)
+
+// defaultVolumeWeeks is the number of weeks returned when no range is given
+const defaultVolumeWeeks = 12
+
+// MuscleVolume is the training volume of one muscle over a week
+type MuscleVolume struct {
+	Muscle  string  `json:"muscle"`
+	Sets    float64 `json:"sets"`
+	Tonnage float64 `json:"tonnage"`
+}
+
+// VolumeBalance sums weekly sets on each side of common splits. Push and pull
+// count the sets of exercises with that movement pattern; upper and lower
+// count the credited sets of the muscles in that region.
+type VolumeBalance struct {
+	PushSets  float64 `json:"push_sets"`
+	PullSets  float64 `json:"pull_sets"`
+	UpperSets float64 `json:"upper_sets"`
+	LowerSets float64 `json:"lower_sets"`
+}
+
+type WeeklyMuscleVolume struct {
+	WeekStart string         `json:"week_start"`
+	Muscles   []MuscleVolume `json:"muscles"`
+	Balance   VolumeBalance  `json:"balance"`
+}
+
+type MuscleVolumeResponse struct {
+	Unit    string               `json:"unit"`
+	Muscles []string             `json:"muscles"`
+	Weeks   []WeeklyMuscleVolume `json:"weeks"`
+}
+
+// muscleShare is the part of an exercise's volume credited to one muscle
+type muscleShare struct {
+	muscle string
+	region string
+	share  float64
+}
+
+// exerciseVolumeShares lists the muscles an exercise's volume is credited to
+// and whether it counts as a push or pull movement
+type exerciseVolumeShares struct {
+	muscles []muscleShare
+	push    bool
+	pull    bool
+}
+
+// volumeShares derives the volume credits of an exercise from its taxonomy
+func volumeShares(taxonomy *models.ExerciseTaxonomy) exerciseVolumeShares {
+	var v exerciseVolumeShares
+	if taxonomy == nil {
+		return v
+	}
+	for _, m := range taxonomy.Muscles {
+		share := secondaryMuscleShare
+		if m.Role == models.MuscleRolePrimary {
+			share = primaryMuscleShare
+		}
+		v.muscles = append(v.muscles, muscleShare{muscle: m.Slug, region: m.Region, share: share})
+	}
+	for _, p := range taxonomy.MovementPatterns {
+		v.push = v.push || p.Slug == "push"
+		v.pull = v.pull || p.Slug == "pull"
+	}
+	return v
+}
+
+// GetMuscleVolume returns weekly working sets and tonnage per muscle of the
+// taxonomy. Sets count fully for the exercise's primary muscles and half for
+// its secondary muscles, and tonnage of bodyweight movements includes the
+// lifter's bodyweight. Weeks start on Sunday like the weekly report;
+// start_date and end_date default to the last 12 weeks.
+func GetMuscleVolume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	startDate, endDate := r.URL.Query().Get("start_date"), r.URL.Query().Get("end_date")
+	if err := validateProgressRange(startDate, endDate); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	end := time.Now()
+	if endDate != "" {
+		end, _ = time.Parse(utils.DateLayout, endDate)
+	}
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	start := utils.WeekStart(end).AddDate(0, 0, -7*(defaultVolumeWeeks-1))
+	if startDate != "" {
+		start, _ = time.Parse(utils.DateLayout, startDate)
+	}
+	// Widen the range to whole weeks so no bucket is partial at the start
+	start = utils.WeekStart(start)
+
+	// Every type lifted against a load counts, bodyweight movements included
+	params := []interface{}{userID, utils.FormatDate(start), utils.FormatDate(end)}
+	var loadedTypes []string
+	for _, t := range models.ExerciseTypes {
+		if models.RulesForExerciseType(t).Load != models.LoadNone {
+			loadedTypes = append(loadedTypes, "?")
+			params = append(params, t)
+		}
+	}
+
+	rows, err := database.DB.Query(
+		`SELECT wl.id, wl.exercise_id, wl.exercise_source, wl.date, wl.sets, wl.reps, wl.weight,
+		        COALESCE(e.exercise_type, pe.exercise_type) as exercise_type
+		 FROM workout_logs wl
+		 LEFT JOIN exercises e ON wl.exercise_source = 'private' AND wl.exercise_id = e.id AND wl.user_id = e.user_id
+		 LEFT JOIN public_exercises pe ON wl.exercise_source = 'public' AND wl.exercise_id = pe.id
+		 WHERE wl.user_id = ? AND substr(wl.date, 1, 10) >= ? AND substr(wl.date, 1, 10) <= ?
+		   AND COALESCE(e.exercise_type, pe.exercise_type) IN (`+strings.Join(loadedTypes, ", ")+`)
+		 ORDER BY wl.date ASC`,
+		params...,
+	)
+	if err != nil {
+		fmt.Printf("Get muscle volume error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var logs []models.WorkoutLog
+	exerciseIDs := make(map[string][]int64)
+	seenExercises := make(map[string]bool)
+	for rows.Next() {
+		var log models.WorkoutLog
+		if err := rows.Scan(&log.ID, &log.ExerciseID, &log.ExerciseSource, &log.Date, &log.Sets, &log.Reps, &log.Weight, &log.ExerciseType); err != nil {
+			fmt.Printf("Error scanning log: %v\n", err)
+			continue
+		}
+		if key := fmt.Sprintf("%s:%d", log.ExerciseSource, log.ExerciseID); !seenExercises[key] {
+			seenExercises[key] = true
+			exerciseIDs[log.ExerciseSource] = append(exerciseIDs[log.ExerciseSource], log.ExerciseID)
+		}
+		logs = append(logs, log)
+	}
+	rows.Close()
+
+	shares := make(map[string]exerciseVolumeShares)
+	for source, ids := range exerciseIDs {
+		taxonomies, err := fetchExerciseTaxonomy(source, ids)
+		if err != nil {
+			fmt.Printf("Get muscle volume error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		for id, taxonomy := range taxonomies {
+			shares[fmt.Sprintf("%s:%d", source, id)] = volumeShares(taxonomy)
+		}
+	}
+
+	if err := attachWorkoutSets(logs); err != nil {
+		fmt.Printf("Get muscle volume error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get muscle volume error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	bodyweights, err := services.LoadBodyweights(database.DB, userID)
+	if err != nil {
+		fmt.Printf("Get muscle volume error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Every week in the range gets an entry so the series has no gaps
+	weeks := []WeeklyMuscleVolume{}
+	weekIndex := make(map[string]int)
+	volumes := []map[string]*MuscleVolume{}
+	for week := start; !week.After(end); week = week.AddDate(0, 0, 7) {
+		key := utils.FormatDate(week)
+		weekIndex[key] = len(weeks)
+		weeks = append(weeks, WeeklyMuscleVolume{WeekStart: key, Muscles: []MuscleVolume{}})
+		volumes = append(volumes, make(map[string]*MuscleVolume))
+	}
+
+	allMuscles := make(map[string]bool)
+	for _, log := range logs {
+		exercise := shares[fmt.Sprintf("%s:%d", log.ExerciseSource, log.ExerciseID)]
+		if len(exercise.muscles) == 0 {
+			exercise.muscles = []muscleShare{{muscle: "unspecified", share: primaryMuscleShare}}
+		}
+		date, err := utils.ParseDate(log.Date)
+		if err != nil {
+			continue
+		}
+		i, ok := weekIndex[utils.FormatDate(utils.WeekStart(date))]
+		if !ok {
+			continue
+		}
+
+		weights, reps := progressLifts(log)
+		sets := float64(len(weights))
+		rules := models.RulesForExerciseType(*log.ExerciseType)
+		bodyweight := bodyweights.On(log.Date)
+		var tonnage float64
+		for j := range weights {
+			load, _ := rules.EffectiveLoad(&weights[j], bodyweight)
+			tonnage += load * float64(reps[j])
+		}
+
+		balance := &weeks[i].Balance
+		if exercise.push {
+			balance.PushSets += sets
+		}
+		if exercise.pull {
+			balance.PullSets += sets
+		}
+
+		for _, s := range exercise.muscles {
+			volume, ok := volumes[i][s.muscle]
+			if !ok {
+				volume = &MuscleVolume{Muscle: s.muscle}
+				volumes[i][s.muscle] = volume
+			}
+			volume.Sets += sets * s.share
+			volume.Tonnage += tonnage * s.share
+			allMuscles[s.muscle] = true
+
+			switch s.region {
+			case models.RegionUpper:
+				balance.UpperSets += sets * s.share
+			case models.RegionLower:
+				balance.LowerSets += sets * s.share
+			}
+		}
+	}
+
+	for i := range weeks {
+		for _, volume := range volumes[i] {
+			volume.Tonnage = units.WeightFromCanonical(volume.Tonnage)
+			weeks[i].Muscles = append(weeks[i].Muscles, *volume)
+		}
+		sort.Slice(weeks[i].Muscles, func(a, b int) bool {
+			return weeks[i].Muscles[a].Muscle < weeks[i].Muscles[b].Muscle
+		})
+	}
+
+	muscles := []string{}
+	for muscle := range allMuscles {
+		muscles = append(muscles, muscle)
+	}
+	sort.Strings(muscles)
+
+	response := MuscleVolumeResponse{Unit: units.WeightLabel(), Muscles: muscles, Weeks: weeks}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
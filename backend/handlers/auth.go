@@ -305,10 +305,11 @@ func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	var createdAtStr string
 	var email sql.NullString
 	var totpEnabled sql.NullBool
+	var weightUnit, distanceUnit sql.NullString
 	err := database.DB.QueryRow(
-		"SELECT id, username, email, totp_enabled, created_at FROM users WHERE id = ?",
+		"SELECT id, username, email, totp_enabled, weight_unit, distance_unit, role, created_at FROM users WHERE id = ?",
 		userID,
-	).Scan(&user.ID, &user.Username, &email, &totpEnabled, &createdAtStr)
+	).Scan(&user.ID, &user.Username, &email, &totpEnabled, &weightUnit, &distanceUnit, &user.Role, &createdAtStr)
 
 	if email.Valid {
 		user.Email = &email.String
@@ -317,6 +318,9 @@ func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 		enabled := totpEnabled.Bool
 		user.TOTPEnabled = &enabled
 	}
+	units := unitsFromColumns(weightUnit, distanceUnit)
+	user.WeightUnit = units.Weight
+	user.DistanceUnit = units.Distance
 
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
@@ -0,0 +1,580 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+type BodyMetricResponse struct {
+	BodyMetric models.BodyMetric `json:"body_metric"`
+}
+
+type BodyMetricsResponse struct {
+	BodyMetrics []models.BodyMetric `json:"body_metrics"`
+}
+
+type CreateBodyMetricRequest struct {
+	Date           string                  `json:"date"`
+	Bodyweight     *float64                `json:"bodyweight"`
+	BodyFatPercent *float64                `json:"body_fat_percent"`
+	Measurements   models.BodyMeasurements `json:"measurements"`
+	Notes          *string                 `json:"notes"`
+}
+
+// UpdateBodyMetricRequest changes the values that are set and keeps the others
+type UpdateBodyMetricRequest struct {
+	Date           *string                 `json:"date"`
+	Bodyweight     *float64                `json:"bodyweight"`
+	BodyFatPercent *float64                `json:"body_fat_percent"`
+	Measurements   models.BodyMeasurements `json:"measurements"`
+	Notes          *string                 `json:"notes"`
+}
+
+type BodyMetricProgressResponse struct {
+	Metric string          `json:"metric"`
+	Bucket string          `json:"bucket"`
+	Unit   string          `json:"unit"`
+	Series []ProgressPoint `json:"series"`
+}
+
+// Body metric progress metrics besides the measurement sites
+const (
+	BodyMetricBodyweight     = "bodyweight"
+	BodyMetricBodyFatPercent = "body_fat_percent"
+)
+
+// bodyMeasurementColumns are the measurement columns, in the order of
+// bodyMeasurementFields. The column names double as progress metric names.
+var bodyMeasurementColumns = []string{"neck", "chest", "waist", "hips", "arm", "forearm", "thigh", "calf"}
+
+// bodyMeasurementFields returns pointers to the measurements in the order of
+// bodyMeasurementColumns
+func bodyMeasurementFields(m *models.BodyMeasurements) []**float64 {
+	return []**float64{&m.Neck, &m.Chest, &m.Waist, &m.Hips, &m.Arm, &m.Forearm, &m.Thigh, &m.Calf}
+}
+
+var bodyMetricSelect = `
+	SELECT id, user_id, date, bodyweight, body_fat_percent, ` + strings.Join(bodyMeasurementColumns, ", ") + `,
+	       notes, created_at, updated_at
+	FROM body_metrics
+`
+
+func scanBodyMetric(row rowScanner) (models.BodyMetric, error) {
+	var metric models.BodyMetric
+	dest := []interface{}{&metric.ID, &metric.UserID, &metric.Date, &metric.Bodyweight, &metric.BodyFatPercent}
+	for _, field := range bodyMeasurementFields(&metric.Measurements) {
+		dest = append(dest, field)
+	}
+	dest = append(dest, &metric.Notes, &metric.CreatedAt, &metric.UpdatedAt)
+
+	err := row.Scan(dest...)
+	if len(metric.Date) > 10 {
+		metric.Date = metric.Date[:10]
+	}
+	return metric, err
+}
+
+// fetchBodyMetric loads a body metric entry owned by the user, in canonical units
+func fetchBodyMetric(metricID, userID int64) (models.BodyMetric, error) {
+	return scanBodyMetric(database.DB.QueryRow(
+		bodyMetricSelect+" WHERE id = ? AND user_id = ?",
+		metricID, userID,
+	))
+}
+
+// fetchBodyMetrics loads the user's body metric entries between two optional
+// dates, oldest first and in canonical units
+func fetchBodyMetrics(userID int64, startDate, endDate string) ([]models.BodyMetric, error) {
+	query := bodyMetricSelect + " WHERE user_id = ?"
+	params := []interface{}{userID}
+	if startDate != "" {
+		query += " AND substr(date, 1, 10) >= ?"
+		params = append(params, startDate)
+	}
+	if endDate != "" {
+		query += " AND substr(date, 1, 10) <= ?"
+		params = append(params, endDate)
+	}
+	query += " ORDER BY date ASC"
+
+	rows, err := database.DB.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	metrics := []models.BodyMetric{}
+	for rows.Next() {
+		metric, err := scanBodyMetric(rows)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, rows.Err()
+}
+
+// validateBodyMetricValues checks the values of a create or update request
+func validateBodyMetricValues(bodyweight, bodyFatPercent *float64, measurements models.BodyMeasurements) error {
+	if bodyweight != nil && *bodyweight <= 0 {
+		return fmt.Errorf("bodyweight must be positive")
+	}
+	if bodyFatPercent != nil && (*bodyFatPercent <= 0 || *bodyFatPercent >= 100) {
+		return fmt.Errorf("body_fat_percent must be between 0 and 100")
+	}
+	for i, field := range bodyMeasurementFields(&measurements) {
+		if *field != nil && **field <= 0 {
+			return fmt.Errorf("%s measurement must be positive", bodyMeasurementColumns[i])
+		}
+	}
+	return nil
+}
+
+// hasBodyMeasurements reports whether any measurement is set
+func hasBodyMeasurements(measurements models.BodyMeasurements) bool {
+	for _, field := range bodyMeasurementFields(&measurements) {
+		if *field != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidBodyMetricDate(date string) bool {
+	_, err := time.Parse(utils.DateLayout, date)
+	return err == nil
+}
+
+func isBodyMetricDateTaken(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func parseBodyMetricID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(strings.TrimSuffix(r.URL.Path[len("/api/body-metrics/"):], "/"), 10, 64)
+}
+
+// GetAllBodyMetrics returns the user's body metric entries, newest first.
+// start_date and end_date limit the range.
+func GetAllBodyMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	startDate, endDate := r.URL.Query().Get("start_date"), r.URL.Query().Get("end_date")
+	if err := validateProgressRange(startDate, endDate); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := fetchBodyMetrics(userID, startDate, endDate)
+	if err != nil {
+		fmt.Printf("Get body metrics error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get body metrics error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeBodyMetrics(metrics, units)
+
+	for i, j := 0, len(metrics)-1; i < j; i, j = i+1, j-1 {
+		metrics[i], metrics[j] = metrics[j], metrics[i]
+	}
+
+	response := BodyMetricsResponse{BodyMetrics: metrics}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBodyMetricById returns a single body metric entry
+func GetBodyMetricById(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	metricID, err := parseBodyMetricID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid body metric ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	writeBodyMetric(w, metricID, userID, http.StatusOK)
+}
+
+// CreateBodyMetric records the body metrics of a date. Each date holds one
+// entry; update it to add values taken later the same day.
+func CreateBodyMetric(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	var req CreateBodyMetricRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Date == "" {
+		http.Error(w, `{"error":"Date is required"}`, http.StatusBadRequest)
+		return
+	}
+	if !isValidBodyMetricDate(req.Date) {
+		http.Error(w, `{"error":"Date must use the YYYY-MM-DD format"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Bodyweight == nil && req.BodyFatPercent == nil && !hasBodyMeasurements(req.Measurements) {
+		http.Error(w, `{"error":"At least one of bodyweight, body_fat_percent or measurements is required"}`, http.StatusBadRequest)
+		return
+	}
+	if err := validateBodyMetricValues(req.Bodyweight, req.BodyFatPercent, req.Measurements); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Create body metric error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	columns := []string{"user_id", "date", "bodyweight", "body_fat_percent"}
+	values := []interface{}{userID, req.Date, weightToCanonical(units, req.Bodyweight), req.BodyFatPercent}
+	for i, field := range bodyMeasurementFields(&req.Measurements) {
+		columns = append(columns, bodyMeasurementColumns[i])
+		values = append(values, lengthToCanonical(units, *field))
+	}
+	columns = append(columns, "notes")
+	values = append(values, req.Notes)
+
+	result, err := database.DB.Exec(
+		fmt.Sprintf("INSERT INTO body_metrics (%s) VALUES (?%s)",
+			strings.Join(columns, ", "), strings.Repeat(", ?", len(columns)-1)),
+		values...,
+	)
+	if isBodyMetricDateTaken(err) {
+		http.Error(w, `{"error":"Body metrics for this date already exist"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		fmt.Printf("Create body metric error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	metricID, _ := result.LastInsertId()
+	writeBodyMetric(w, metricID, userID, http.StatusCreated)
+}
+
+// UpdateBodyMetric updates the values that are set in the request
+func UpdateBodyMetric(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	metricID, err := parseBodyMetricID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid body metric ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Verify the entry belongs to user
+	if _, err := fetchBodyMetric(metricID, userID); err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Body metric not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Update body metric error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var req UpdateBodyMetricRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Date != nil && !isValidBodyMetricDate(*req.Date) {
+		http.Error(w, `{"error":"Date must use the YYYY-MM-DD format"}`, http.StatusBadRequest)
+		return
+	}
+	if err := validateBodyMetricValues(req.Bodyweight, req.BodyFatPercent, req.Measurements); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Update body metric error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Build update query dynamically
+	updates := []string{"updated_at = CURRENT_TIMESTAMP"}
+	values := []interface{}{}
+
+	if req.Date != nil {
+		updates = append(updates, "date = ?")
+		values = append(values, *req.Date)
+	}
+	if req.Bodyweight != nil {
+		updates = append(updates, "bodyweight = ?")
+		values = append(values, units.WeightToCanonical(*req.Bodyweight))
+	}
+	if req.BodyFatPercent != nil {
+		updates = append(updates, "body_fat_percent = ?")
+		values = append(values, *req.BodyFatPercent)
+	}
+	for i, field := range bodyMeasurementFields(&req.Measurements) {
+		if *field != nil {
+			updates = append(updates, bodyMeasurementColumns[i]+" = ?")
+			values = append(values, units.LengthToCanonical(**field))
+		}
+	}
+	if req.Notes != nil {
+		updates = append(updates, "notes = ?")
+		values = append(values, *req.Notes)
+	}
+
+	values = append(values, metricID, userID)
+	query := fmt.Sprintf("UPDATE body_metrics SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
+	_, err = database.DB.Exec(query, values...)
+	if isBodyMetricDateTaken(err) {
+		http.Error(w, `{"error":"Body metrics for this date already exist"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		fmt.Printf("Update body metric error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeBodyMetric(w, metricID, userID, http.StatusOK)
+}
+
+// DeleteBodyMetric deletes a body metric entry
+func DeleteBodyMetric(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	metricID, err := parseBodyMetricID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid body metric ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM body_metrics WHERE id = ? AND user_id = ?", metricID, userID)
+	if err != nil {
+		fmt.Printf("Delete body metric error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, `{"error":"Body metric not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Body metric deleted successfully"})
+}
+
+// GetBodyMetricProgress returns a series of one body metric, averaged over
+// buckets of a day, week or month. start_date and end_date limit the range.
+func GetBodyMetricProgress(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	query := r.URL.Query()
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = BodyMetricBodyweight
+	}
+	column, ok := bodyMetricColumn(metric)
+	if !ok {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`,
+			"metric must be bodyweight, body_fat_percent, "+strings.Join(bodyMeasurementColumns, ", ")), http.StatusBadRequest)
+		return
+	}
+	bucket := query.Get("bucket")
+	if bucket == "" {
+		bucket = BucketDay
+	} else if !isValidProgressBucket(bucket) {
+		http.Error(w, `{"error":"bucket must be day, week or month"}`, http.StatusBadRequest)
+		return
+	}
+	startDate, endDate := query.Get("start_date"), query.Get("end_date")
+	if err := validateProgressRange(startDate, endDate); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	valuesQuery := fmt.Sprintf("SELECT substr(date, 1, 10), %s FROM body_metrics WHERE user_id = ? AND %s IS NOT NULL", column, column)
+	params := []interface{}{userID}
+	if startDate != "" {
+		valuesQuery += " AND substr(date, 1, 10) >= ?"
+		params = append(params, startDate)
+	}
+	if endDate != "" {
+		valuesQuery += " AND substr(date, 1, 10) <= ?"
+		params = append(params, endDate)
+	}
+	valuesQuery += " ORDER BY date ASC"
+
+	rows, err := database.DB.Query(valuesQuery, params...)
+	if err != nil {
+		fmt.Printf("Get body metric progress error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var buckets []*progressBucket
+	byStart := make(map[string]*progressBucket)
+	for rows.Next() {
+		var dateStr string
+		var value float64
+		if err := rows.Scan(&dateStr, &value); err != nil {
+			fmt.Printf("Get body metric progress error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		date, err := utils.ParseDate(dateStr)
+		if err != nil {
+			continue
+		}
+
+		start := bucketStart(date, bucket)
+		key := utils.FormatDate(start)
+		b, ok := byStart[key]
+		if !ok {
+			b = &progressBucket{start: start}
+			byStart[key] = b
+			buckets = append(buckets, b)
+		}
+		b.value += value
+		b.logCount++
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Printf("Get body metric progress error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get body metric progress error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Body metrics are averaged over a bucket rather than summed
+	series := []ProgressPoint{}
+	for _, b := range buckets {
+		series = append(series, ProgressPoint{
+			PeriodStart: utils.FormatDate(b.start),
+			Value:       localizeBodyMetricValue(metric, b.value/float64(b.logCount), units),
+			LogCount:    b.logCount,
+		})
+	}
+
+	response := BodyMetricProgressResponse{
+		Metric: metric,
+		Bucket: bucket,
+		Unit:   bodyMetricUnit(metric, units),
+		Series: series,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// bodyMetricColumn returns the column a progress metric is read from
+func bodyMetricColumn(metric string) (string, bool) {
+	if metric == BodyMetricBodyweight || metric == BodyMetricBodyFatPercent {
+		return metric, true
+	}
+	for _, column := range bodyMeasurementColumns {
+		if metric == column {
+			return column, true
+		}
+	}
+	return "", false
+}
+
+// bodyMetricUnit returns the label of the unit a body metric is reported in
+func bodyMetricUnit(metric string, units utils.Units) string {
+	switch metric {
+	case BodyMetricBodyweight:
+		return units.WeightLabel()
+	case BodyMetricBodyFatPercent:
+		return "%"
+	}
+	return units.LengthLabel()
+}
+
+// localizeBodyMetricValue converts a canonical body metric value to the user's units
+func localizeBodyMetricValue(metric string, value float64, units utils.Units) float64 {
+	switch metric {
+	case BodyMetricBodyweight:
+		return units.WeightFromCanonical(value)
+	case BodyMetricBodyFatPercent:
+		return value
+	}
+	return units.LengthFromCanonical(value)
+}
+
+// writeBodyMetric responds with a body metric entry in the user's units
+func writeBodyMetric(w http.ResponseWriter, metricID, userID int64, status int) {
+	metric, err := fetchBodyMetric(metricID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Body metric not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Get body metric error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get body metric error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	metrics := []models.BodyMetric{metric}
+	localizeBodyMetrics(metrics, units)
+
+	response := BodyMetricResponse{BodyMetric: metrics[0]}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+	"gym-app-backend/utils"
+)
+
+// Limits of the training calendar
+const (
+	defaultCalendarDays = 365
+	maxCalendarDays     = 3 * 366
+	maxRestAllowance    = 30
+)
+
+// weekdayNames maps the accepted spellings of rest_weekdays to weekdays
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// CalendarDay is the training done on one day
+type CalendarDay struct {
+	Date          string  `json:"date"`
+	LogCount      int     `json:"log_count"`
+	Volume        float64 `json:"volume"`         // weight moved for reps, in the user's weight unit
+	CardioMinutes int     `json:"cardio_minutes"` // duration of distance-based logs
+	PlannedRest   bool    `json:"planned_rest,omitempty"`
+}
+
+type CalendarResponse struct {
+	StartDate     string          `json:"start_date"`
+	EndDate       string          `json:"end_date"`
+	Unit          string          `json:"unit"`
+	Days          []CalendarDay   `json:"days"`
+	CurrentStreak services.Streak `json:"current_streak"`
+	LongestStreak services.Streak `json:"longest_streak"`
+	RestAllowance int             `json:"rest_allowance"`
+	RestWeekdays  []string        `json:"rest_weekdays"`
+}
+
+// parseStreakRules reads the rest_allowance and rest_weekdays parameters
+func parseStreakRules(r *http.Request) (services.StreakRules, []string, error) {
+	rules := services.StreakRules{RestWeekdays: make(map[time.Weekday]bool)}
+
+	if allowance := r.URL.Query().Get("rest_allowance"); allowance != "" {
+		n, err := strconv.Atoi(allowance)
+		if err != nil || n < 0 || n > maxRestAllowance {
+			return rules, nil, fmt.Errorf("rest_allowance must be between 0 and %d", maxRestAllowance)
+		}
+		rules.RestAllowance = n
+	}
+
+	restWeekdays := []string{}
+	if weekdays := r.URL.Query().Get("rest_weekdays"); weekdays != "" {
+		for _, name := range strings.Split(weekdays, ",") {
+			day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return rules, nil, fmt.Errorf("rest_weekdays must list weekdays such as sat,sun")
+			}
+			if !rules.RestWeekdays[day] {
+				rules.RestWeekdays[day] = true
+				restWeekdays = append(restWeekdays, strings.ToLower(day.String()))
+			}
+		}
+		if len(rules.RestWeekdays) == 7 {
+			return rules, nil, fmt.Errorf("rest_weekdays cannot include every day of the week")
+		}
+	}
+	return rules, restWeekdays, nil
+}
+
+// GetTrainingCalendar returns per-day training activity for a heatmap along
+// with the current and longest streaks. start_date and end_date default to
+// the last year. A streak survives rest_allowance unplanned rest days in a row,
+// and rest_weekdays (e.g. sat,sun) are planned rest days that never break it.
+// Streaks are computed over the whole history, not only the requested range.
+func GetTrainingCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	startDate, endDate := r.URL.Query().Get("start_date"), r.URL.Query().Get("end_date")
+	if err := validateProgressRange(startDate, endDate); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	streakRules, restWeekdays, err := parseStreakRules(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := today
+	if endDate != "" {
+		end, _ = time.Parse(utils.DateLayout, endDate)
+	}
+	start := end.AddDate(0, 0, -(defaultCalendarDays - 1))
+	if startDate != "" {
+		start, _ = time.Parse(utils.DateLayout, startDate)
+	}
+	if end.Sub(start).Hours()/24 >= maxCalendarDays {
+		http.Error(w, fmt.Sprintf(`{"error":"The date range cannot exceed %d days"}`, maxCalendarDays), http.StatusBadRequest)
+		return
+	}
+
+	rows, err := database.DB.Query(
+		`SELECT wl.id, wl.date, wl.sets, wl.reps, wl.weight, wl.duration,
+		        COALESCE(e.exercise_type, pe.exercise_type) as exercise_type
+		 FROM workout_logs wl
+		 LEFT JOIN exercises e ON wl.exercise_source = 'private' AND wl.exercise_id = e.id AND wl.user_id = e.user_id
+		 LEFT JOIN public_exercises pe ON wl.exercise_source = 'public' AND wl.exercise_id = pe.id
+		 WHERE wl.user_id = ? AND wl.planned = 0 AND substr(wl.date, 1, 10) >= ? AND substr(wl.date, 1, 10) <= ?
+		 ORDER BY wl.date ASC`,
+		userID, utils.FormatDate(start), utils.FormatDate(end),
+	)
+	if err != nil {
+		fmt.Printf("Get training calendar error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	var logs []models.WorkoutLog
+	for rows.Next() {
+		var log models.WorkoutLog
+		if err := rows.Scan(&log.ID, &log.Date, &log.Sets, &log.Reps, &log.Weight, &log.Duration, &log.ExerciseType); err != nil {
+			fmt.Printf("Error scanning log: %v\n", err)
+			continue
+		}
+		logs = append(logs, log)
+	}
+
+	if err := attachWorkoutSets(logs); err != nil {
+		fmt.Printf("Get training calendar error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get training calendar error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	bodyweights, err := services.LoadBodyweights(database.DB, userID)
+	if err != nil {
+		fmt.Printf("Get training calendar error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Every day in the range gets an entry so the heatmap has no gaps
+	days := []CalendarDay{}
+	dayIndex := make(map[string]int)
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		key := utils.FormatDate(day)
+		dayIndex[key] = len(days)
+		days = append(days, CalendarDay{Date: key, PlannedRest: streakRules.RestWeekdays[day.Weekday()]})
+	}
+
+	for _, log := range logs {
+		date, err := utils.ParseDate(log.Date)
+		if err != nil {
+			continue
+		}
+		i, ok := dayIndex[utils.FormatDate(date)]
+		if !ok {
+			continue
+		}
+		day := &days[i]
+		day.LogCount++
+		day.PlannedRest = false
+
+		exerciseType := ""
+		if log.ExerciseType != nil {
+			exerciseType = *log.ExerciseType
+		}
+		rules := models.RulesForExerciseType(exerciseType)
+		if rules.Load != models.LoadNone {
+			weights, reps := progressLifts(log)
+			bodyweight := bodyweights.On(log.Date)
+			for j := range weights {
+				load, _ := rules.EffectiveLoad(&weights[j], bodyweight)
+				day.Volume += load * float64(reps[j])
+			}
+		}
+		if rules.Distance && log.Duration != nil {
+			day.CardioMinutes += *log.Duration
+		}
+	}
+	for i := range days {
+		days[i].Volume = units.WeightFromCanonical(days[i].Volume)
+	}
+
+	active, err := fetchTrainingDays(userID)
+	if err != nil {
+		fmt.Printf("Get training calendar error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	current, longest := services.ComputeStreaks(active, today, streakRules)
+
+	response := CalendarResponse{
+		StartDate:     utils.FormatDate(start),
+		EndDate:       utils.FormatDate(end),
+		Unit:          units.WeightLabel(),
+		Days:          days,
+		CurrentStreak: current,
+		LongestStreak: longest,
+		RestAllowance: streakRules.RestAllowance,
+		RestWeekdays:  restWeekdays,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// fetchTrainingDays returns every distinct day the user logged a performed
+// workout, oldest first
+func fetchTrainingDays(userID int64) ([]time.Time, error) {
+	rows, err := database.DB.Query(
+		"SELECT DISTINCT substr(date, 1, 10) AS day FROM workout_logs WHERE user_id = ? AND planned = 0 ORDER BY day ASC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []time.Time
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			return nil, err
+		}
+		date, err := utils.ParseDate(day)
+		if err != nil {
+			continue
+		}
+		days = append(days, date)
+	}
+	return days, rows.Err()
+}
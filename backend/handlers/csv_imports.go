@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+	"gym-app-backend/utils"
+)
+
+// csvPreviewLimit is the number of logs shown in a dry run
+const csvPreviewLimit = 20
+
+// CSVImportExercise is an exercise named in a CSV file and what it resolves to
+type CSVImportExercise struct {
+	Name         string `json:"name"`
+	ExerciseID   *int64 `json:"exercise_id"` // nil for exercises a dry run would create
+	Source       string `json:"source"`      // private or public
+	ExerciseType string `json:"exercise_type"`
+	Action       string `json:"action"` // matched or created
+	LogCount     int    `json:"log_count"`
+}
+
+type CSVImportResponse struct {
+	DryRun       bool                   `json:"dry_run"`
+	Format       string                 `json:"format"` // strong, hevy or custom
+	Mapping      services.CSVMapping    `json:"mapping"`
+	FileUnits    utils.Units            `json:"file_units"`
+	RowCount     int                    `json:"row_count"`
+	LogCount     int                    `json:"log_count"`
+	SetCount     int                    `json:"set_count"`
+	SessionCount int                    `json:"session_count"`
+	Exercises    []CSVImportExercise    `json:"exercises"`
+	Errors       []services.CSVRowError `json:"errors"`
+	Preview      []models.WorkoutLog    `json:"preview,omitempty"` // first logs of a dry run
+}
+
+// csvExerciseKey is how exercise names are matched
+func csvExerciseKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// csvLogFits reports whether a log read from a CSV file can be stored for an
+// exercise of the given type: intervals need distances, sets need reps and load
+func csvLogFits(log services.CSVLog, exerciseType string) bool {
+	rules := models.RulesForExerciseType(exerciseType)
+	if log.IsCardio() {
+		return rules.Distance
+	}
+	return rules.Reps && rules.Load != models.LoadNone
+}
+
+// matchCSVExercises resolves the exercises of a plan by name: the user's own
+// exercises first, then public exercises that are not deprecated. Unmatched names become exercises to
+// create, typed after their first log. Logs that do not fit the type of their
+// exercise are dropped from the plan and reported.
+func matchCSVExercises(userID int64, plan *services.CSVImportPlan) ([]*CSVImportExercise, map[string]*CSVImportExercise, error) {
+	known := make(map[string]*CSVImportExercise)
+	queries := []struct {
+		source string
+		query  string
+		args   []interface{}
+	}{
+		{"public", "SELECT id, name, exercise_type FROM public_exercises WHERE deprecated_at IS NULL", nil},
+		{"private", "SELECT id, name, exercise_type FROM exercises WHERE user_id = ?", []interface{}{userID}},
+	}
+	// Private exercises are loaded last so they win over public ones
+	for _, q := range queries {
+		rows, err := database.DB.Query(q.query, q.args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		for rows.Next() {
+			var id int64
+			var name string
+			var exerciseType sql.NullString
+			if err := rows.Scan(&id, &name, &exerciseType); err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			ex := &CSVImportExercise{Name: name, ExerciseID: &id, Source: q.source, ExerciseType: "strength", Action: "matched"}
+			if exerciseType.Valid && exerciseType.String != "" {
+				ex.ExerciseType = exerciseType.String
+			}
+			known[csvExerciseKey(name)] = ex
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var used []*CSVImportExercise
+	byKey := make(map[string]*CSVImportExercise)
+	kept := plan.Logs[:0]
+	for _, log := range plan.Logs {
+		key := csvExerciseKey(log.Exercise)
+		logType := "strength"
+		if log.IsCardio() {
+			logType = "cardio"
+		}
+
+		ex, ok := byKey[key]
+		if !ok {
+			ex, ok = known[key]
+			if !ok {
+				ex = &CSVImportExercise{Name: log.Exercise, Source: "private", ExerciseType: logType, Action: "created"}
+			}
+			byKey[key] = ex
+			used = append(used, ex)
+		}
+
+		if !csvLogFits(log, ex.ExerciseType) {
+			for _, row := range log.Rows {
+				plan.Errors = append(plan.Errors, services.CSVRowError{
+					Row:   row,
+					Error: fmt.Sprintf("%s is a %s exercise but the row holds %s data", ex.Name, ex.ExerciseType, logType),
+				})
+			}
+			continue
+		}
+		ex.LogCount++
+		kept = append(kept, log)
+	}
+	plan.Logs = kept
+	return used, byKey, nil
+}
+
+// csvWorkoutLog builds the stored form of a planned log: summary values in
+// canonical units plus its sets and laps
+func csvWorkoutLog(log services.CSVLog, units utils.Units) (models.WorkoutLog, error) {
+	wl := models.WorkoutLog{Date: log.Date, Notes: log.Notes}
+
+	if !log.IsCardio() {
+		sets := len(log.Sets)
+		wl.Sets = &sets
+		if top := log.TopSet(); top != nil {
+			wl.Reps, wl.Weight = top.Reps, top.Weight
+		}
+		wl.WorkoutSets = log.Sets
+		return wl, nil
+	}
+
+	distance, duration := log.CardioTotals()
+	metrics, err := resolveCardioMetrics(cardioMetrics{Distance: distance, Duration: duration}, log.Laps, units)
+	if err != nil {
+		return wl, err
+	}
+	wl.Distance, wl.Duration, wl.Pace = metrics.Distance, metrics.Duration, metrics.Pace
+	// A single interval is the log itself rather than a lap
+	if len(log.Laps) > 1 {
+		wl.Laps = log.Laps
+	}
+	return wl, nil
+}
+
+// ImportWorkoutCSV imports workout history from a CSV export such as Strong's
+// or Hevy's. The multipart form takes the file and optionally a JSON column
+// mapping ({"field": "Column header"}), the weight_unit and distance_unit of
+// the file (defaulting to the user's units) and dry_run. A dry run reports
+// what would be imported without writing anything. Rows that cannot be read
+// are skipped and listed with their errors.
+func ImportWorkoutCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+1<<20)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		http.Error(w, `{"error":"Invalid upload, expected a multipart form with a file under 20 MB"}`, http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, `{"error":"file is required"}`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, `{"error":"Could not read the uploaded file"}`, http.StatusBadRequest)
+		return
+	}
+
+	var mapping services.CSVMapping
+	if v := r.FormValue("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &mapping); err != nil {
+			http.Error(w, `{"error":"mapping must be a JSON object of field to column header"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	dryRun := r.FormValue("dry_run") == "true"
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Import workout CSV error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	fileUnits := units
+	if v := r.FormValue("weight_unit"); v != "" {
+		if !utils.IsValidWeightUnit(v) {
+			http.Error(w, `{"error":"weight_unit must be kg or lb"}`, http.StatusBadRequest)
+			return
+		}
+		fileUnits.Weight = v
+	}
+	if v := r.FormValue("distance_unit"); v != "" {
+		if !utils.IsValidDistanceUnit(v) {
+			http.Error(w, `{"error":"distance_unit must be km or mi"}`, http.StatusBadRequest)
+			return
+		}
+		fileUnits.Distance = v
+	}
+
+	plan, err := services.ParseWorkoutCSV(data, mapping, fileUnits)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	exercises, byKey, err := matchCSVExercises(userID, plan)
+	if err != nil {
+		fmt.Printf("Import workout CSV error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Build the stored logs up front so a dry run reports the same errors
+	var logs []models.WorkoutLog
+	var planned []services.CSVLog
+	for _, log := range plan.Logs {
+		wl, err := csvWorkoutLog(log, units)
+		if err != nil {
+			for _, row := range log.Rows {
+				plan.Errors = append(plan.Errors, services.CSVRowError{Row: row, Error: err.Error()})
+			}
+			byKey[csvExerciseKey(log.Exercise)].LogCount--
+			continue
+		}
+		logs = append(logs, wl)
+		planned = append(planned, log)
+	}
+
+	sort.SliceStable(plan.Errors, func(i, j int) bool { return plan.Errors[i].Row < plan.Errors[j].Row })
+
+	response := CSVImportResponse{
+		DryRun:    dryRun,
+		Format:    plan.Format,
+		Mapping:   plan.Mapping,
+		FileUnits: plan.Units,
+		RowCount:  plan.RowCount,
+		LogCount:  len(logs),
+		Errors:    plan.Errors,
+	}
+	sessions := make(map[string]int64)
+	for i, log := range planned {
+		response.SetCount += len(logs[i].WorkoutSets)
+		if log.Workout != "" {
+			sessions[log.Date+"\x00"+log.Workout] = 0
+		}
+	}
+	response.SessionCount = len(sessions)
+	for _, ex := range exercises {
+		if ex.LogCount > 0 {
+			response.Exercises = append(response.Exercises, *ex)
+		}
+	}
+
+	if dryRun {
+		for i := 0; i < len(logs) && i < csvPreviewLimit; i++ {
+			preview := logs[i]
+			ex := byKey[csvExerciseKey(planned[i].Exercise)]
+			preview.ExerciseName = &ex.Name
+			preview.ExerciseType = &ex.ExerciseType
+			if ex.ExerciseID != nil {
+				preview.ExerciseID = *ex.ExerciseID
+			}
+			// Localizing works in place, so the preview gets its own sets and laps
+			preview.WorkoutSets = append([]models.WorkoutSet(nil), preview.WorkoutSets...)
+			preview.Laps = append([]models.WorkoutLap(nil), preview.Laps...)
+			for j := range preview.Laps {
+				preview.Laps[j].DerivePace()
+			}
+			localizeWorkoutLog(&preview, units)
+			response.Preview = append(response.Preview, preview)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Import workout CSV error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, ex := range response.Exercises {
+		if ex.ExerciseID != nil {
+			continue
+		}
+		result, err := tx.Exec(
+			"INSERT INTO exercises (user_id, name, exercise_type) VALUES (?, ?, ?)",
+			userID, ex.Name, ex.ExerciseType,
+		)
+		if err != nil {
+			fmt.Printf("Import workout CSV error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		id, _ := result.LastInsertId()
+		if err := database.MapExerciseTaxonomy(tx, id, "private", ex.Name, ex.ExerciseType, nil, nil); err != nil {
+			fmt.Printf("Import workout CSV error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		byKey[csvExerciseKey(ex.Name)].ExerciseID = &id
+	}
+
+	// Rows of the same workout on the same day become one session
+	sessionOrders := make(map[int64]int)
+	touched := make(map[*CSVImportExercise]bool)
+	var touchedExercises []*CSVImportExercise
+	for i, log := range planned {
+		exercise := byKey[csvExerciseKey(log.Exercise)]
+		exerciseID := *exercise.ExerciseID
+
+		var sessionID, sessionOrder sql.NullInt64
+		if log.Workout != "" {
+			key := log.Date + "\x00" + log.Workout
+			if sessions[key] == 0 {
+				result, err := tx.Exec(
+					"INSERT INTO workout_sessions (user_id, name, date) VALUES (?, ?, ?)",
+					userID, log.Workout, log.Date,
+				)
+				if err != nil {
+					fmt.Printf("Import workout CSV error: %v\n", err)
+					http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+					return
+				}
+				sessions[key], _ = result.LastInsertId()
+			}
+			sessionOrders[sessions[key]]++
+			sessionID = sql.NullInt64{Int64: sessions[key], Valid: true}
+			sessionOrder = sql.NullInt64{Int64: int64(sessionOrders[sessions[key]]), Valid: true}
+		}
+
+		wl := logs[i]
+		result, err := tx.Exec(
+			`INSERT INTO workout_logs (user_id, exercise_id, exercise_source, session_id, session_order, date, sets, reps, weight, distance, duration, pace, notes)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			userID, exerciseID, exercise.Source, sessionID, sessionOrder, wl.Date, wl.Sets, wl.Reps, wl.Weight,
+			wl.Distance, wl.Duration, wl.Pace, wl.Notes,
+		)
+		if err != nil {
+			fmt.Printf("Import workout CSV error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		logID, _ := result.LastInsertId()
+
+		if err := replaceWorkoutSets(tx, logID, wl.WorkoutSets); err != nil {
+			fmt.Printf("Import workout CSV error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if err := replaceWorkoutLaps(tx, logID, wl.Laps); err != nil {
+			fmt.Printf("Import workout CSV error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		if !touched[exercise] {
+			touched[exercise] = true
+			touchedExercises = append(touchedExercises, exercise)
+		}
+	}
+
+	for _, exercise := range touchedExercises {
+		if _, err := services.RebuildPersonalRecords(tx, userID, *exercise.ExerciseID, exercise.Source); err != nil {
+			fmt.Printf("Import workout CSV error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if _, err := services.EvaluateGoals(tx, userID); err != nil {
+		fmt.Printf("Import workout CSV error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Import workout CSV error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Report the IDs of the exercises that were just created
+	for i := range response.Exercises {
+		response.Exercises[i].ExerciseID = byKey[csvExerciseKey(response.Exercises[i].Name)].ExerciseID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
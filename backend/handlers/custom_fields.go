@@ -0,0 +1,615 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+)
+
+// Limits on custom fields and their values
+const (
+	maxCustomFieldNameLength = 50
+	maxCustomFieldOptions    = 50
+	maxCustomTextLength      = 500
+)
+
+type CustomFieldResponse struct {
+	Field models.CustomField `json:"field"`
+}
+
+type CustomFieldsResponse struct {
+	Fields []models.CustomField `json:"fields"`
+}
+
+type CreateCustomFieldRequest struct {
+	Name      string   `json:"name"`
+	FieldType string   `json:"field_type"`
+	Unit      *string  `json:"unit"`
+	Options   []string `json:"options"`
+}
+
+type UpdateCustomFieldRequest struct {
+	Name *string `json:"name"`
+	// Unit is cleared by an empty string
+	Unit    *string   `json:"unit"`
+	Options *[]string `json:"options"`
+}
+
+// CustomMetricRequest sets the value of a custom field on a workout log. A
+// null value leaves the field unset.
+type CustomMetricRequest struct {
+	FieldID int64       `json:"field_id"`
+	Value   interface{} `json:"value"`
+}
+
+// customFieldSelect selects custom field columns in a fixed order. Use it with scanCustomField.
+const customFieldSelect = `
+	SELECT id, user_id, exercise_id, exercise_source, name, field_type, unit, options, created_at, updated_at
+	FROM exercise_custom_fields
+`
+
+// scanCustomField scans a row selected with customFieldSelect
+func scanCustomField(row rowScanner) (models.CustomField, error) {
+	var field models.CustomField
+	var options sql.NullString
+	err := row.Scan(
+		&field.ID, &field.UserID, &field.ExerciseID, &field.ExerciseSource, &field.Name,
+		&field.FieldType, &field.Unit, &options, &field.CreatedAt, &field.UpdatedAt,
+	)
+	if err != nil {
+		return field, err
+	}
+	if options.Valid && options.String != "" {
+		if err := json.Unmarshal([]byte(options.String), &field.Options); err != nil {
+			return field, err
+		}
+	}
+	return field, nil
+}
+
+// fetchCustomFields loads the custom fields the user defined on an exercise
+func fetchCustomFields(userID int64, exercise exerciseRef) ([]models.CustomField, error) {
+	rows, err := database.DB.Query(
+		customFieldSelect+" WHERE user_id = ? AND exercise_id = ? AND exercise_source = ? ORDER BY id",
+		userID, exercise.ID, exercise.Source,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fields := []models.CustomField{}
+	for rows.Next() {
+		field, err := scanCustomField(rows)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, rows.Err()
+}
+
+// fetchCustomField loads one custom field of an exercise
+func fetchCustomField(userID int64, exercise exerciseRef, fieldID int64) (models.CustomField, error) {
+	return scanCustomField(database.DB.QueryRow(
+		customFieldSelect+" WHERE id = ? AND user_id = ? AND exercise_id = ? AND exercise_source = ?",
+		fieldID, userID, exercise.ID, exercise.Source,
+	))
+}
+
+// parseCustomFieldPath extracts the exercise ID and, when present, the field
+// ID from /api/exercises/:id/custom-fields[/:fieldId]
+func parseCustomFieldPath(r *http.Request) (exerciseID int64, fieldID int64, err error) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/exercises/"), "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "custom-fields" {
+		return 0, 0, fmt.Errorf("invalid custom field path")
+	}
+	exerciseID, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) == 3 {
+		fieldID, err = strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return exerciseID, fieldID, nil
+}
+
+// resolveCustomFieldRequest resolves the exercise and field ID of a custom
+// field request and writes the error response if either is invalid
+func resolveCustomFieldRequest(w http.ResponseWriter, r *http.Request, userID int64, wantField bool) (exerciseRef, int64, bool) {
+	exerciseID, fieldID, err := parseCustomFieldPath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid exercise ID"}`, http.StatusBadRequest)
+		return exerciseRef{}, 0, false
+	}
+	if wantField && fieldID == 0 {
+		http.Error(w, `{"error":"Invalid field ID"}`, http.StatusBadRequest)
+		return exerciseRef{}, 0, false
+	}
+	exercise, ok := resolveRequestExercise(w, r, userID, exerciseID)
+	return exercise, fieldID, ok
+}
+
+// validateCustomFieldName trims a field name and checks its length
+func validateCustomFieldName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	if utf8.RuneCountInString(name) > maxCustomFieldNameLength {
+		return "", fmt.Errorf("name must be at most %d characters", maxCustomFieldNameLength)
+	}
+	return name, nil
+}
+
+// normalizeCustomFieldOptions trims the options of a field and checks that
+// only enum fields have them, each one distinct
+func normalizeCustomFieldOptions(fieldType string, options []string) ([]string, error) {
+	if fieldType != models.CustomFieldEnum {
+		if len(options) > 0 {
+			return nil, fmt.Errorf("options can only be used for enum fields")
+		}
+		return nil, nil
+	}
+	if len(options) == 0 || len(options) > maxCustomFieldOptions {
+		return nil, fmt.Errorf("enum fields need between 1 and %d options", maxCustomFieldOptions)
+	}
+
+	normalized := make([]string, 0, len(options))
+	seen := make(map[string]bool)
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return nil, fmt.Errorf("options cannot be empty")
+		}
+		if utf8.RuneCountInString(option) > maxCustomFieldNameLength {
+			return nil, fmt.Errorf("options must be at most %d characters", maxCustomFieldNameLength)
+		}
+		if seen[option] {
+			return nil, fmt.Errorf("option %q is listed twice", option)
+		}
+		seen[option] = true
+		normalized = append(normalized, option)
+	}
+	return normalized, nil
+}
+
+// customFieldOptionsString returns the options as JSON for storage
+func customFieldOptionsString(options []string) *string {
+	if len(options) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil
+	}
+	str := string(data)
+	return &str
+}
+
+// GetCustomFields returns the custom fields the user defined on an exercise
+func GetCustomFields(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	exercise, _, ok := resolveCustomFieldRequest(w, r, userID, false)
+	if !ok {
+		return
+	}
+
+	fields, err := fetchCustomFields(userID, exercise)
+	if err != nil {
+		fmt.Printf("Get custom fields error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := CustomFieldsResponse{Fields: fields}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateCustomField defines a new custom field on an exercise
+func CreateCustomField(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	exercise, _, ok := resolveCustomFieldRequest(w, r, userID, false)
+	if !ok {
+		return
+	}
+
+	var req CreateCustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	name, err := validateCustomFieldName(req.Name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if !models.IsValidCustomFieldType(req.FieldType) {
+		http.Error(w, `{"error":"field_type must be number, text or enum"}`, http.StatusBadRequest)
+		return
+	}
+	options, err := normalizeCustomFieldOptions(req.FieldType, req.Options)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if req.Unit != nil && strings.TrimSpace(*req.Unit) == "" {
+		req.Unit = nil
+	}
+
+	result, err := database.DB.Exec(
+		`INSERT INTO exercise_custom_fields (user_id, exercise_id, exercise_source, name, field_type, unit, options)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		userID, exercise.ID, exercise.Source, name, req.FieldType, req.Unit, customFieldOptionsString(options),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			http.Error(w, `{"error":"This exercise already has a field with that name"}`, http.StatusConflict)
+			return
+		}
+		fmt.Printf("Create custom field error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	fieldID, _ := result.LastInsertId()
+	field, err := fetchCustomField(userID, exercise, fieldID)
+	if err != nil {
+		fmt.Printf("Error fetching created custom field: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := CustomFieldResponse{Field: field}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateCustomField renames a custom field or changes its unit or options.
+// The type of a field is fixed once created, and options still used by
+// logged values cannot be removed.
+func UpdateCustomField(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	exercise, fieldID, ok := resolveCustomFieldRequest(w, r, userID, true)
+	if !ok {
+		return
+	}
+
+	field, err := fetchCustomField(userID, exercise, fieldID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Custom field not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Update custom field error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var req UpdateCustomFieldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	updates := []string{}
+	values := []interface{}{}
+
+	if req.Name != nil {
+		name, err := validateCustomFieldName(*req.Name)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "name = ?")
+		values = append(values, name)
+	}
+	if req.Unit != nil {
+		var unit *string
+		if strings.TrimSpace(*req.Unit) != "" {
+			unit = req.Unit
+		}
+		updates = append(updates, "unit = ?")
+		values = append(values, unit)
+	}
+	if req.Options != nil {
+		options, err := normalizeCustomFieldOptions(field.FieldType, *req.Options)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		if field.FieldType == models.CustomFieldEnum {
+			inUse, err := customFieldValuesOutside(field.ID, options)
+			if err != nil {
+				fmt.Printf("Update custom field error: %v\n", err)
+				http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+				return
+			}
+			if inUse != "" {
+				http.Error(w, fmt.Sprintf(`{"error":%q}`, fmt.Sprintf("option %q is still used by logged values", inUse)), http.StatusConflict)
+				return
+			}
+		}
+		updates = append(updates, "options = ?")
+		values = append(values, customFieldOptionsString(options))
+	}
+
+	if len(updates) > 0 {
+		updates = append(updates, "updated_at = CURRENT_TIMESTAMP")
+		values = append(values, fieldID, userID)
+		query := fmt.Sprintf("UPDATE exercise_custom_fields SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
+		if _, err := database.DB.Exec(query, values...); err != nil {
+			if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+				http.Error(w, `{"error":"This exercise already has a field with that name"}`, http.StatusConflict)
+				return
+			}
+			fmt.Printf("Update custom field error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	field, err = fetchCustomField(userID, exercise, fieldID)
+	if err != nil {
+		fmt.Printf("Error fetching updated custom field: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := CustomFieldResponse{Field: field}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// customFieldValuesOutside returns a value logged for an enum field that is
+// not among options, or "" when every logged value is
+func customFieldValuesOutside(fieldID int64, options []string) (string, error) {
+	rows, err := database.DB.Query(
+		"SELECT DISTINCT text_value FROM workout_log_custom_values WHERE field_id = ? AND text_value IS NOT NULL",
+		fieldID,
+	)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	allowed := make(map[string]bool, len(options))
+	for _, option := range options {
+		allowed[option] = true
+	}
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return "", err
+		}
+		if !allowed[value] {
+			return value, nil
+		}
+	}
+	return "", rows.Err()
+}
+
+// DeleteCustomField removes a custom field together with the values logged for it
+func DeleteCustomField(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	exercise, fieldID, ok := resolveCustomFieldRequest(w, r, userID, true)
+	if !ok {
+		return
+	}
+
+	if _, err := fetchCustomField(userID, exercise, fieldID); err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Custom field not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Delete custom field error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Delete custom field error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM workout_log_custom_values WHERE field_id = ?", fieldID); err != nil {
+		fmt.Printf("Delete custom field error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM exercise_custom_fields WHERE id = ? AND user_id = ?", fieldID, userID); err != nil {
+		fmt.Printf("Delete custom field error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Delete custom field error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Custom field deleted successfully"})
+}
+
+// buildCustomMetrics checks the custom metrics of a log request against the
+// fields of its exercise and returns the values to store
+func buildCustomMetrics(fields []models.CustomField, items []CustomMetricRequest) ([]models.CustomMetric, error) {
+	byID := make(map[int64]models.CustomField, len(fields))
+	for _, field := range fields {
+		byID[field.ID] = field
+	}
+
+	metrics := make([]models.CustomMetric, 0, len(items))
+	seen := make(map[int64]bool)
+	for _, item := range items {
+		field, ok := byID[item.FieldID]
+		if !ok {
+			return nil, fmt.Errorf("custom field %d is not defined on this exercise", item.FieldID)
+		}
+		if seen[item.FieldID] {
+			return nil, fmt.Errorf("custom field %q is given twice", field.Name)
+		}
+		seen[item.FieldID] = true
+		if item.Value == nil {
+			continue
+		}
+
+		switch field.FieldType {
+		case models.CustomFieldNumber:
+			if _, ok := item.Value.(float64); !ok {
+				return nil, fmt.Errorf("custom field %q must be a number", field.Name)
+			}
+		case models.CustomFieldText:
+			text, ok := item.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("custom field %q must be text", field.Name)
+			}
+			if utf8.RuneCountInString(text) > maxCustomTextLength {
+				return nil, fmt.Errorf("custom field %q must be at most %d characters", field.Name, maxCustomTextLength)
+			}
+		case models.CustomFieldEnum:
+			option, _ := item.Value.(string)
+			valid := false
+			for _, allowed := range field.Options {
+				valid = valid || option == allowed
+			}
+			if !valid {
+				return nil, fmt.Errorf("custom field %q must be one of %s", field.Name, strings.Join(field.Options, ", "))
+			}
+		}
+
+		metrics = append(metrics, models.CustomMetric{
+			FieldID:   field.ID,
+			Name:      field.Name,
+			FieldType: field.FieldType,
+			Unit:      field.Unit,
+			Value:     item.Value,
+		})
+	}
+	return metrics, nil
+}
+
+// replaceCustomMetrics replaces the custom metric values of a workout log inside a transaction
+func replaceCustomMetrics(tx *sql.Tx, logID int64, metrics []models.CustomMetric) error {
+	if _, err := tx.Exec("DELETE FROM workout_log_custom_values WHERE workout_log_id = ?", logID); err != nil {
+		return err
+	}
+
+	for _, metric := range metrics {
+		var number, text interface{}
+		if metric.FieldType == models.CustomFieldNumber {
+			number = metric.Value
+		} else {
+			text = metric.Value
+		}
+		_, err := tx.Exec(
+			"INSERT INTO workout_log_custom_values (workout_log_id, field_id, number_value, text_value) VALUES (?, ?, ?, ?)",
+			logID, metric.FieldID, number, text,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachCustomMetrics loads the custom metric values of each log
+func attachCustomMetrics(logs []models.WorkoutLog) error {
+	index := make(map[int64]int, len(logs))
+	for i, log := range logs {
+		index[log.ID] = i
+	}
+
+	for start := 0; start < len(logs); start += workoutSetsBatchSize {
+		end := start + workoutSetsBatchSize
+		if end > len(logs) {
+			end = len(logs)
+		}
+		batch := logs[start:end]
+
+		placeholders := make([]string, len(batch))
+		params := make([]interface{}, len(batch))
+		for i, log := range batch {
+			placeholders[i] = "?"
+			params[i] = log.ID
+		}
+
+		rows, err := database.DB.Query(
+			fmt.Sprintf(`SELECT v.workout_log_id, f.id, f.name, f.field_type, f.unit, v.number_value, v.text_value
+			 FROM workout_log_custom_values v
+			 JOIN exercise_custom_fields f ON v.field_id = f.id
+			 WHERE v.workout_log_id IN (%s)
+			 ORDER BY v.workout_log_id, f.id`, strings.Join(placeholders, ", ")),
+			params...,
+		)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var logID int64
+			var metric models.CustomMetric
+			var number sql.NullFloat64
+			var text sql.NullString
+			if err := rows.Scan(&logID, &metric.FieldID, &metric.Name, &metric.FieldType, &metric.Unit, &number, &text); err != nil {
+				rows.Close()
+				return err
+			}
+			switch {
+			case number.Valid:
+				metric.Value = number.Float64
+			case text.Valid:
+				metric.Value = text.String
+			default:
+				continue
+			}
+			i := index[logID]
+			logs[i].CustomMetrics = append(logs[i].CustomMetrics, metric)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,611 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+)
+
+type ExerciseGroupResponse struct {
+	Group models.ExerciseGroup `json:"group"`
+}
+
+type ExerciseGroupsResponse struct {
+	Groups []models.ExerciseGroup `json:"groups"`
+}
+
+type CreateExerciseGroupRequest struct {
+	GroupType         string  `json:"group_type"`
+	Rounds            *int    `json:"rounds"`
+	RestBetweenRounds *int    `json:"rest_between_rounds"`
+	Notes             *string `json:"notes"`
+	// LogIDs are logs of the session performed as the group
+	LogIDs []int64 `json:"log_ids"`
+}
+
+type UpdateExerciseGroupRequest struct {
+	GroupType         *string `json:"group_type"`
+	Rounds            *int    `json:"rounds"`
+	RestBetweenRounds *int    `json:"rest_between_rounds"`
+	Notes             *string `json:"notes"`
+	// LogIDs replaces the logs of the group
+	LogIDs *[]int64 `json:"log_ids"`
+}
+
+var (
+	errGroupLogNotInSession = errors.New("workout log is not part of this session")
+	errGroupLogTaken        = errors.New("workout log already belongs to another group")
+)
+
+const exerciseGroupSelect = `
+	SELECT g.id, g.user_id, g.session_id, g.group_type, g.rounds, g.rest_between_rounds, g.notes, g.created_at
+	FROM exercise_groups g
+`
+
+func scanExerciseGroup(row rowScanner) (models.ExerciseGroup, error) {
+	var group models.ExerciseGroup
+	err := row.Scan(
+		&group.ID, &group.UserID, &group.SessionID, &group.GroupType, &group.Rounds,
+		&group.RestBetweenRounds, &group.Notes, &group.CreatedAt,
+	)
+	return group, err
+}
+
+// fetchExerciseGroups loads the groups of a session with their logs, ordered
+// by where each group starts in the session
+func fetchExerciseGroups(sessionID, userID int64) ([]models.ExerciseGroup, error) {
+	rows, err := database.DB.Query(
+		exerciseGroupSelect+` WHERE g.session_id = ? AND g.user_id = ?
+		 ORDER BY (SELECT MIN(wl.session_order) FROM workout_logs wl WHERE wl.group_id = g.id), g.id`,
+		sessionID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []models.ExerciseGroup{}
+	for rows.Next() {
+		group, err := scanExerciseGroup(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := attachGroupLogIDs(groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// fetchExerciseGroup loads one group of a session with its logs
+func fetchExerciseGroup(groupID, sessionID, userID int64) (models.ExerciseGroup, error) {
+	group, err := scanExerciseGroup(database.DB.QueryRow(
+		exerciseGroupSelect+" WHERE g.id = ? AND g.session_id = ? AND g.user_id = ?",
+		groupID, sessionID, userID,
+	))
+	if err != nil {
+		return group, err
+	}
+
+	groups := []models.ExerciseGroup{group}
+	if err := attachGroupLogIDs(groups); err != nil {
+		return group, err
+	}
+	return groups[0], nil
+}
+
+// fetchLogExerciseGroups loads the groups the given logs belong to, keyed by group ID
+func fetchLogExerciseGroups(logs []models.WorkoutLog) (map[int64]models.ExerciseGroup, error) {
+	var params []interface{}
+	seen := make(map[int64]bool)
+	for _, log := range logs {
+		if log.GroupID != nil && !seen[*log.GroupID] {
+			seen[*log.GroupID] = true
+			params = append(params, *log.GroupID)
+		}
+	}
+
+	byID := make(map[int64]models.ExerciseGroup)
+	for start := 0; start < len(params); start += workoutSetsBatchSize {
+		end := start + workoutSetsBatchSize
+		if end > len(params) {
+			end = len(params)
+		}
+		batch := params[start:end]
+
+		rows, err := database.DB.Query(
+			exerciseGroupSelect+fmt.Sprintf(" WHERE g.id IN (?%s)", strings.Repeat(", ?", len(batch)-1)),
+			batch...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		var groups []models.ExerciseGroup
+		for rows.Next() {
+			group, err := scanExerciseGroup(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			groups = append(groups, group)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := attachGroupLogIDs(groups); err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			byID[group.ID] = group
+		}
+	}
+	return byID, nil
+}
+
+// attachGroupLogIDs loads the member logs of each group in session order
+func attachGroupLogIDs(groups []models.ExerciseGroup) error {
+	for i := range groups {
+		rows, err := database.DB.Query(
+			"SELECT id FROM workout_logs WHERE group_id = ? ORDER BY session_order ASC, created_at ASC",
+			groups[i].ID,
+		)
+		if err != nil {
+			return err
+		}
+
+		groups[i].LogIDs = []int64{}
+		for rows.Next() {
+			var logID int64
+			if err := rows.Scan(&logID); err != nil {
+				rows.Close()
+				return err
+			}
+			groups[i].LogIDs = append(groups[i].LogIDs, logID)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assignGroupLogs makes logIDs the logs of a group. Every log must be part of
+// the group's session and not already in another group.
+func assignGroupLogs(tx *sql.Tx, groupID, sessionID, userID int64, logIDs []int64) error {
+	for _, logID := range logIDs {
+		var logSessionID, logGroupID sql.NullInt64
+		err := tx.QueryRow(
+			"SELECT session_id, group_id FROM workout_logs WHERE id = ? AND user_id = ?",
+			logID, userID,
+		).Scan(&logSessionID, &logGroupID)
+		if err == sql.ErrNoRows {
+			return errSessionLogNotFound
+		} else if err != nil {
+			return err
+		}
+		if !logSessionID.Valid || logSessionID.Int64 != sessionID {
+			return errGroupLogNotInSession
+		}
+		if logGroupID.Valid && logGroupID.Int64 != groupID {
+			return errGroupLogTaken
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE workout_logs SET group_id = NULL WHERE group_id = ? AND user_id = ?", groupID, userID); err != nil {
+		return err
+	}
+	for _, logID := range logIDs {
+		if _, err := tx.Exec("UPDATE workout_logs SET group_id = ? WHERE id = ? AND user_id = ?", groupID, logID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneExerciseGroups removes logs from groups of a session they are no
+// longer part of and dissolves groups left with fewer than two logs. Call it
+// whenever logs leave a session or are deleted.
+func pruneExerciseGroups(tx *sql.Tx, userID int64) error {
+	queries := []string{
+		`UPDATE workout_logs SET group_id = NULL
+		 WHERE user_id = ? AND group_id IS NOT NULL AND (session_id IS NULL OR session_id <>
+		   (SELECT g.session_id FROM exercise_groups g WHERE g.id = workout_logs.group_id))`,
+		`UPDATE workout_logs SET group_id = NULL
+		 WHERE user_id = ? AND group_id IN
+		   (SELECT group_id FROM workout_logs WHERE group_id IS NOT NULL GROUP BY group_id HAVING COUNT(*) < 2)`,
+		`DELETE FROM exercise_groups
+		 WHERE user_id = ? AND id NOT IN (SELECT group_id FROM workout_logs WHERE group_id IS NOT NULL)`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateExerciseGroup checks the type, size and rest of a group
+func validateExerciseGroup(groupType string, logIDs []int64, rounds, rest *int) error {
+	if !models.IsValidGroupType(groupType) {
+		return fmt.Errorf("group_type must be superset, circuit or giant_set")
+	}
+	if minSize := models.MinGroupSize(groupType); len(logIDs) < minSize {
+		return fmt.Errorf("a %s needs at least %d workout logs", strings.ReplaceAll(groupType, "_", " "), minSize)
+	}
+	seen := make(map[int64]bool)
+	for _, logID := range logIDs {
+		if seen[logID] {
+			return fmt.Errorf("workout log %d is listed twice", logID)
+		}
+		seen[logID] = true
+	}
+	if rounds != nil && *rounds < 1 {
+		return fmt.Errorf("rounds must be at least 1")
+	}
+	if rest != nil && *rest < 0 {
+		return fmt.Errorf("rest_between_rounds cannot be negative")
+	}
+	return nil
+}
+
+// parseExerciseGroupPath extracts the session ID and, when present, the
+// group ID from /api/workouts/:id/groups[/:groupId]
+func parseExerciseGroupPath(r *http.Request) (sessionID int64, groupID int64, err error) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/workouts/"), "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[1] != "groups" {
+		return 0, 0, fmt.Errorf("invalid exercise group path")
+	}
+	sessionID, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(parts) == 3 {
+		groupID, err = strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	return sessionID, groupID, nil
+}
+
+// resolveExerciseGroupRequest checks the session of a group request and
+// writes the error response if it is invalid
+func resolveExerciseGroupRequest(w http.ResponseWriter, r *http.Request, userID int64, wantGroup bool) (int64, int64, bool) {
+	sessionID, groupID, err := parseExerciseGroupPath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid session ID"}`, http.StatusBadRequest)
+		return 0, 0, false
+	}
+	if wantGroup && groupID == 0 {
+		http.Error(w, `{"error":"Invalid group ID"}`, http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	if _, err := fetchWorkoutSession(sessionID, userID); err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Workout session not found"}`, http.StatusNotFound)
+		return 0, 0, false
+	} else if err != nil {
+		fmt.Printf("Resolve exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	return sessionID, groupID, true
+}
+
+// writeGroupLogsError writes the response for an error of assignGroupLogs
+func writeGroupLogsError(w http.ResponseWriter, err error, context string) {
+	switch err {
+	case errSessionLogNotFound:
+		http.Error(w, `{"error":"Workout log not found"}`, http.StatusNotFound)
+	case errGroupLogNotInSession, errGroupLogTaken:
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusConflict)
+	default:
+		fmt.Printf("%s error: %v\n", context, err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+	}
+}
+
+// GetExerciseGroups returns the supersets, circuits and giant sets of a session
+func GetExerciseGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	sessionID, _, ok := resolveExerciseGroupRequest(w, r, userID, false)
+	if !ok {
+		return
+	}
+
+	groups, err := fetchExerciseGroups(sessionID, userID)
+	if err != nil {
+		fmt.Printf("Get exercise groups error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := ExerciseGroupsResponse{Groups: groups}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateExerciseGroup groups logs of a session into a superset, circuit or giant set
+func CreateExerciseGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	sessionID, _, ok := resolveExerciseGroupRequest(w, r, userID, false)
+	if !ok {
+		return
+	}
+
+	var req CreateExerciseGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if err := validateExerciseGroup(req.GroupType, req.LogIDs, req.Rounds, req.RestBetweenRounds); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Create exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO exercise_groups (user_id, session_id, group_type, rounds, rest_between_rounds, notes)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		userID, sessionID, req.GroupType, req.Rounds, req.RestBetweenRounds, req.Notes,
+	)
+	if err != nil {
+		fmt.Printf("Create exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	groupID, _ := result.LastInsertId()
+	if err := assignGroupLogs(tx, groupID, sessionID, userID, req.LogIDs); err != nil {
+		writeGroupLogsError(w, err, "Create exercise group")
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Create exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	group, err := fetchExerciseGroup(groupID, sessionID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching created exercise group: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := ExerciseGroupResponse{Group: group}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateExerciseGroup updates a group and optionally replaces its logs
+func UpdateExerciseGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	sessionID, groupID, ok := resolveExerciseGroupRequest(w, r, userID, true)
+	if !ok {
+		return
+	}
+
+	group, err := fetchExerciseGroup(groupID, sessionID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Exercise group not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Update exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var req UpdateExerciseGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	// The group is checked as it will be after the update
+	groupType, logIDs := group.GroupType, group.LogIDs
+	if req.GroupType != nil {
+		groupType = *req.GroupType
+	}
+	if req.LogIDs != nil {
+		logIDs = *req.LogIDs
+	}
+	if err := validateExerciseGroup(groupType, logIDs, req.Rounds, req.RestBetweenRounds); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// Build update query dynamically
+	updates := []string{}
+	values := []interface{}{}
+
+	if req.GroupType != nil {
+		updates = append(updates, "group_type = ?")
+		values = append(values, *req.GroupType)
+	}
+	if req.Rounds != nil {
+		updates = append(updates, "rounds = ?")
+		values = append(values, *req.Rounds)
+	}
+	if req.RestBetweenRounds != nil {
+		updates = append(updates, "rest_between_rounds = ?")
+		values = append(values, *req.RestBetweenRounds)
+	}
+	if req.Notes != nil {
+		updates = append(updates, "notes = ?")
+		values = append(values, *req.Notes)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Update exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if len(updates) > 0 {
+		values = append(values, groupID, userID)
+		query := fmt.Sprintf("UPDATE exercise_groups SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
+		if _, err := tx.Exec(query, values...); err != nil {
+			fmt.Printf("Update exercise group error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.LogIDs != nil {
+		if err := assignGroupLogs(tx, groupID, sessionID, userID, *req.LogIDs); err != nil {
+			writeGroupLogsError(w, err, "Update exercise group")
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Update exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	group, err = fetchExerciseGroup(groupID, sessionID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching updated exercise group: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := ExerciseGroupResponse{Group: group}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteExerciseGroup dissolves a group; its logs stay in the session
+func DeleteExerciseGroup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	sessionID, groupID, ok := resolveExerciseGroupRequest(w, r, userID, true)
+	if !ok {
+		return
+	}
+
+	if _, err := fetchExerciseGroup(groupID, sessionID, userID); err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Exercise group not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Delete exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Delete exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE workout_logs SET group_id = NULL WHERE group_id = ? AND user_id = ?", groupID, userID); err != nil {
+		fmt.Printf("Delete exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM exercise_groups WHERE id = ? AND user_id = ?", groupID, userID); err != nil {
+		fmt.Printf("Delete exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Delete exercise group error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Exercise group deleted successfully"})
+}
+
+// logBlock is one log on its own, or the logs of an exercise group rendered as a unit
+type logBlock struct {
+	Group *models.ExerciseGroup
+	Logs  []models.WorkoutLog
+}
+
+// blocksOf arranges logs into blocks. A group is placed where its first log
+// appears and collects all of its logs from the list; logs whose group is not
+// in groups stand alone.
+func blocksOf(logs []models.WorkoutLog, groups map[int64]models.ExerciseGroup) []logBlock {
+	var blocks []logBlock
+	index := make(map[int64]int)
+	for _, log := range logs {
+		group, ok := models.ExerciseGroup{}, false
+		if log.GroupID != nil {
+			group, ok = groups[*log.GroupID]
+		}
+		if !ok {
+			blocks = append(blocks, logBlock{Logs: []models.WorkoutLog{log}})
+			continue
+		}
+
+		i, seen := index[group.ID]
+		if !seen {
+			group := group
+			blocks = append(blocks, logBlock{Group: &group})
+			i = len(blocks) - 1
+			index[group.ID] = i
+		}
+		blocks[i].Logs = append(blocks[i].Logs, log)
+	}
+	return blocks
+}
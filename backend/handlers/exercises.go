@@ -11,6 +11,7 @@ import (
 	"gym-app-backend/database"
 	"gym-app-backend/middleware"
 	"gym-app-backend/models"
+	"gym-app-backend/services"
 )
 
 type ExerciseResponse struct {
@@ -25,7 +26,9 @@ type ProgressResponse struct {
 	Progress []models.WorkoutLog `json:"progress"`
 }
 
-// GetAllExercises returns all exercises for the authenticated user
+// GetAllExercises returns all exercises for the authenticated user. muscle
+// (with muscle_role), equipment_item and movement_pattern filter them by
+// taxonomy slug.
 func GetAllExercises(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
@@ -34,10 +37,18 @@ func GetAllExercises(w http.ResponseWriter, r *http.Request) {
 
 	userID := middleware.GetUserID(r)
 
-	rows, err := database.DB.Query(
-		"SELECT * FROM exercises WHERE user_id = ? ORDER BY created_at DESC",
-		userID,
-	)
+	conditions, params, err := taxonomyFilters(r.URL.Query(), "id", "private")
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	query := exerciseSelect + " WHERE user_id = ?"
+	for _, condition := range conditions {
+		query += " AND " + condition
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := database.DB.Query(query, append([]interface{}{userID}, params...)...)
 	if err != nil {
 		fmt.Printf("Get exercises error: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
@@ -47,26 +58,52 @@ func GetAllExercises(w http.ResponseWriter, r *http.Request) {
 
 	var exercises []models.Exercise
 	for rows.Next() {
-		var ex models.Exercise
-		var createdAtStr string
-		err := rows.Scan(
-			&ex.ID, &ex.UserID, &ex.Name, &ex.ExerciseType, &ex.MuscleGroup,
-			&ex.Equipment, &ex.Description, &ex.Instructions, &ex.VideoLink,
-			&ex.ImageLink, &createdAtStr,
-		)
+		ex, err := scanExercise(rows)
 		if err != nil {
 			fmt.Printf("Error scanning exercise: %v\n", err)
 			continue
 		}
-		// Parse created_at if needed (simplified - you may want proper time parsing)
 		exercises = append(exercises, ex)
 	}
 
+	if err := attachExerciseTaxonomy(exercises); err != nil {
+		fmt.Printf("Get exercises error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
 	response := ExercisesResponse{Exercises: exercises}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+const exerciseSelect = `
+	SELECT id, user_id, name, exercise_type, muscle_group, equipment, description,
+	       instructions, video_link, image_link, source_public_exercise_id, created_at
+	FROM exercises
+`
+
+func scanExercise(row rowScanner) (models.Exercise, error) {
+	var ex models.Exercise
+	err := row.Scan(
+		&ex.ID, &ex.UserID, &ex.Name, &ex.ExerciseType, &ex.MuscleGroup,
+		&ex.Equipment, &ex.Description, &ex.Instructions, &ex.VideoLink,
+		&ex.ImageLink, &ex.SourcePublicExerciseID, &ex.CreatedAt,
+	)
+	return ex, err
+}
+
+// fetchExercise loads one of the user's exercises along with its taxonomy
+func fetchExercise(userID, exerciseID int64) (models.Exercise, error) {
+	ex, err := scanExercise(database.DB.QueryRow(exerciseSelect+" WHERE id = ? AND user_id = ?", exerciseID, userID))
+	if err != nil {
+		return ex, err
+	}
+	exercises := []models.Exercise{ex}
+	err = attachExerciseTaxonomy(exercises)
+	return exercises[0], err
+}
+
 // GetExerciseById returns a single exercise by ID
 func GetExerciseById(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -81,16 +118,7 @@ func GetExerciseById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var ex models.Exercise
-	var createdAtStr string
-	err = database.DB.QueryRow(
-		"SELECT * FROM exercises WHERE id = ? AND user_id = ?",
-		exerciseID, userID,
-	).Scan(
-		&ex.ID, &ex.UserID, &ex.Name, &ex.ExerciseType, &ex.MuscleGroup,
-		&ex.Equipment, &ex.Description, &ex.Instructions, &ex.VideoLink,
-		&ex.ImageLink, &createdAtStr,
-	)
+	ex, err := fetchExercise(userID, exerciseID)
 
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
@@ -115,8 +143,12 @@ type CreateExerciseRequest struct {
 	Instructions *string `json:"instructions"`
 	VideoLink    *string `json:"video_link"`
 	ImageLink    *string `json:"image_link"`
+	ExerciseTaxonomyRequest
 }
 
+// exerciseTypeError is returned for an unknown exercise_type
+var exerciseTypeError = fmt.Sprintf(`{"error":%q}`, "exercise_type must be one of "+strings.Join(models.ExerciseTypes, ", "))
+
 // CreateExercise creates a new exercise
 func CreateExercise(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -138,17 +170,33 @@ func CreateExercise(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validate exercise_type
-	exerciseType := "strength"
+	exerciseType := models.ExerciseTypeStrength
 	if req.ExerciseType != nil {
-		if *req.ExerciseType == "strength" || *req.ExerciseType == "cardio" {
-			exerciseType = *req.ExerciseType
+		if !models.IsValidExerciseType(*req.ExerciseType) {
+			http.Error(w, exerciseTypeError, http.StatusBadRequest)
+			return
 		}
+		exerciseType = *req.ExerciseType
 	}
 
-	result, err := database.DB.Exec(
+	if err := req.ExerciseTaxonomyRequest.validate(); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	muscleGroup, equipment := taxonomyText(req.ExerciseTaxonomyRequest, req.MuscleGroup, req.Equipment)
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Create exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
 		`INSERT INTO exercises (user_id, name, exercise_type, muscle_group, equipment, description, instructions, video_link, image_link)
 		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, req.Name, exerciseType, req.MuscleGroup, req.Equipment,
+		userID, req.Name, exerciseType, muscleGroup, equipment,
 		req.Description, req.Instructions, req.VideoLink, req.ImageLink,
 	)
 	if err != nil {
@@ -159,16 +207,18 @@ func CreateExercise(w http.ResponseWriter, r *http.Request) {
 
 	exerciseID, _ := result.LastInsertId()
 
-	var ex models.Exercise
-	var createdAtStr string
-	err = database.DB.QueryRow(
-		"SELECT * FROM exercises WHERE id = ?",
-		exerciseID,
-	).Scan(
-		&ex.ID, &ex.UserID, &ex.Name, &ex.ExerciseType, &ex.MuscleGroup,
-		&ex.Equipment, &ex.Description, &ex.Instructions, &ex.VideoLink,
-		&ex.ImageLink, &createdAtStr,
-	)
+	inferPattern := func() []string { return models.InferMovementPatterns(req.Name, exerciseType) }
+	err = applyExerciseTaxonomy(tx, exerciseID, "private", req.ExerciseTaxonomyRequest, muscleGroup, equipment, inferPattern)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Create exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ex, err := fetchExercise(userID, exerciseID)
 	if err != nil {
 		fmt.Printf("Error fetching created exercise: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
@@ -190,6 +240,7 @@ type UpdateExerciseRequest struct {
 	Instructions *string `json:"instructions"`
 	VideoLink    *string `json:"video_link"`
 	ImageLink    *string `json:"image_link"`
+	ExerciseTaxonomyRequest
 }
 
 // UpdateExercise updates an existing exercise
@@ -228,6 +279,12 @@ func UpdateExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := req.ExerciseTaxonomyRequest.validate(); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	req.MuscleGroup, req.Equipment = taxonomyText(req.ExerciseTaxonomyRequest, req.MuscleGroup, req.Equipment)
+
 	// Build update query dynamically
 	updates := []string{}
 	values := []interface{}{}
@@ -237,16 +294,12 @@ func UpdateExercise(w http.ResponseWriter, r *http.Request) {
 		values = append(values, *req.Name)
 	}
 	if req.ExerciseType != nil {
-		validTypes := []string{"strength", "cardio"}
-		exerciseType := "strength"
-		for _, vt := range validTypes {
-			if *req.ExerciseType == vt {
-				exerciseType = *req.ExerciseType
-				break
-			}
+		if !models.IsValidExerciseType(*req.ExerciseType) {
+			http.Error(w, exerciseTypeError, http.StatusBadRequest)
+			return
 		}
 		updates = append(updates, "exercise_type = ?")
-		values = append(values, exerciseType)
+		values = append(values, *req.ExerciseType)
 	}
 	if req.MuscleGroup != nil {
 		updates = append(updates, "muscle_group = ?")
@@ -273,11 +326,19 @@ func UpdateExercise(w http.ResponseWriter, r *http.Request) {
 		values = append(values, *req.ImageLink)
 	}
 
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Update exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
 	if len(updates) > 0 {
 		values = append(values, exerciseID, userID)
 		query := fmt.Sprintf("UPDATE exercises SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
 
-		_, err = database.DB.Exec(query, values...)
+		_, err = tx.Exec(query, values...)
 		if err != nil {
 			fmt.Printf("Update exercise error: %v\n", err)
 			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
@@ -285,16 +346,17 @@ func UpdateExercise(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	var ex models.Exercise
-	var createdAtStr string
-	err = database.DB.QueryRow(
-		"SELECT * FROM exercises WHERE id = ?",
-		exerciseID,
-	).Scan(
-		&ex.ID, &ex.UserID, &ex.Name, &ex.ExerciseType, &ex.MuscleGroup,
-		&ex.Equipment, &ex.Description, &ex.Instructions, &ex.VideoLink,
-		&ex.ImageLink, &createdAtStr,
-	)
+	err = applyExerciseTaxonomy(tx, exerciseID, "private", req.ExerciseTaxonomyRequest, req.MuscleGroup, req.Equipment, nil)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		fmt.Printf("Update exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ex, err := fetchExercise(userID, exerciseID)
 	if err != nil {
 		fmt.Printf("Error fetching updated exercise: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
@@ -306,6 +368,27 @@ func UpdateExercise(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// privateExerciseReferences lists the queries that find plans still built on
+// a private exercise, with the conflict reported when one matches. Unlike
+// logged history these are not deleted along with the exercise, since that
+// would silently rewrite the plan.
+var privateExerciseReferences = []struct {
+	query   string
+	message string
+}{
+	{
+		`SELECT 1 FROM workout_template_exercises te JOIN workout_templates t ON te.template_id = t.id
+		 WHERE te.exercise_id = ? AND te.exercise_source = 'private' AND t.user_id = ?`,
+		`{"error":"Exercise is used by a workout template; remove it from the template first"}`,
+	},
+	{
+		`SELECT 1 FROM program_prescriptions pp JOIN program_days pd ON pp.program_day_id = pd.id
+		 JOIN training_programs tp ON pd.program_id = tp.id
+		 WHERE pp.exercise_id = ? AND pp.exercise_source = 'private' AND tp.user_id = ?`,
+		`{"error":"Exercise is used by a training program; remove it from the program first"}`,
+	},
+}
+
 // DeleteExercise deletes an exercise
 func DeleteExercise(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
@@ -336,7 +419,60 @@ func DeleteExercise(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = database.DB.Exec("DELETE FROM exercises WHERE id = ? AND user_id = ?", exerciseID, userID)
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Delete exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	for _, ref := range privateExerciseReferences {
+		var found int
+		err := tx.QueryRow(ref.query+" LIMIT 1", exerciseID, userID).Scan(&found)
+		if err == nil {
+			http.Error(w, ref.message, http.StatusConflict)
+			return
+		} else if err != sql.ErrNoRows {
+			fmt.Printf("Delete exercise error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The exercise's logs are deleted explicitly: workout_logs has no foreign
+	// key to exercises, since a log may point at a public exercise instead
+	queries := []string{
+		"DELETE FROM workout_sets WHERE workout_log_id IN (SELECT id FROM workout_logs WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?)",
+		"DELETE FROM workout_laps WHERE workout_log_id IN (SELECT id FROM workout_logs WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?)",
+		"DELETE FROM workout_log_custom_values WHERE workout_log_id IN (SELECT id FROM workout_logs WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?)",
+		"DELETE FROM workout_imports WHERE workout_log_id IN (SELECT id FROM workout_logs WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?)",
+		"DELETE FROM workout_logs WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?",
+		"DELETE FROM personal_records WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?",
+		"DELETE FROM progression_rules WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?",
+		"DELETE FROM goals WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?",
+		`DELETE FROM program_training_maxes WHERE exercise_id = ? AND exercise_source = 'private'
+		 AND enrollment_id IN (SELECT id FROM program_enrollments WHERE user_id = ?)`,
+		`DELETE FROM workout_log_custom_values WHERE field_id IN (SELECT id FROM exercise_custom_fields
+		 WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?)`,
+		"DELETE FROM exercise_custom_fields WHERE exercise_id = ? AND exercise_source = 'private' AND user_id = ?",
+		"DELETE FROM exercises WHERE id = ? AND user_id = ?",
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query, exerciseID, userID); err != nil {
+			fmt.Printf("Delete exercise error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = database.DeleteExerciseTaxonomy(tx, exerciseID, "private")
+	if err == nil {
+		err = pruneExerciseGroups(tx, userID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 	if err != nil {
 		fmt.Printf("Delete exercise error: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
@@ -347,7 +483,159 @@ func DeleteExercise(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Exercise deleted successfully"})
 }
 
-// GetExerciseProgress returns workout logs for an exercise ordered by date
+type CopyPublicExerciseRequest struct {
+	Name         *string `json:"name"`          // defaults to the public exercise's name
+	ReassignLogs bool    `json:"reassign_logs"` // move the user's history of the public exercise to the copy
+}
+
+type CopyPublicExerciseResponse struct {
+	Exercise       models.Exercise `json:"exercise"`
+	ReassignedLogs int64           `json:"reassigned_logs"`
+}
+
+// CopyPublicExercise copies a public exercise, taxonomy included, into the
+// user's exercises so it can be edited. With reassign_logs the user's workout
+// logs of the public exercise move to the copy, along with the custom fields,
+// progression rule and goals that belong to that history.
+func CopyPublicExercise(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	// Extract the public exercise ID from a path like /api/public-exercises/3/copy
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/public-exercises/"), "/copy")
+	publicID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid exercise ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req CopyPublicExerciseRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		http.Error(w, `{"error":"Exercise name is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	public, err := scanPublicExercise(database.DB.QueryRow(publicExerciseSelect+" WHERE pe.id = ?", publicID))
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Public exercise not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Copy public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	name := public.Name
+	if req.Name != nil {
+		name = strings.TrimSpace(*req.Name)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Copy public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO exercises (user_id, name, exercise_type, muscle_group, equipment, description, instructions,
+		                        video_link, image_link, source_public_exercise_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, name, public.ExerciseType, public.MuscleGroup, public.Equipment, public.Description,
+		public.Instructions, public.VideoLink, public.ImageLink, public.ID,
+	)
+	if err != nil {
+		fmt.Printf("Copy public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	exerciseID, _ := result.LastInsertId()
+
+	if err := database.CopyExerciseTaxonomy(tx, public.ID, "public", exerciseID, "private"); err != nil {
+		fmt.Printf("Copy public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var reassigned int64
+	if req.ReassignLogs {
+		reassigned, err = reassignPublicExerciseHistory(tx, userID, public.ID, exerciseID)
+		if err != nil {
+			fmt.Printf("Copy public exercise error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Copy public exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	ex, err := fetchExercise(userID, exerciseID)
+	if err != nil {
+		fmt.Printf("Error fetching copied exercise: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := CopyPublicExerciseResponse{Exercise: ex, ReassignedLogs: reassigned}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// reassignPublicExerciseHistory moves the user's logs of a public exercise,
+// and the custom fields, progression rule and goals tied to them, to one of
+// the user's exercises, then rebuilds records and goals. Returns the number
+// of logs moved.
+func reassignPublicExerciseHistory(tx *sql.Tx, userID, publicID, exerciseID int64) (int64, error) {
+	result, err := tx.Exec(
+		"UPDATE workout_logs SET exercise_id = ?, exercise_source = 'private' WHERE exercise_id = ? AND exercise_source = 'public' AND user_id = ?",
+		exerciseID, publicID, userID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	moved, _ := result.RowsAffected()
+
+	for _, table := range []string{"exercise_custom_fields", "progression_rules", "goals"} {
+		_, err := tx.Exec(
+			"UPDATE "+table+" SET exercise_id = ?, exercise_source = 'private' WHERE exercise_id = ? AND exercise_source = 'public' AND user_id = ?",
+			exerciseID, publicID, userID,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := services.RebuildPersonalRecords(tx, userID, publicID, "public"); err != nil {
+		return 0, err
+	}
+	if _, err := services.RebuildPersonalRecords(tx, userID, exerciseID, "private"); err != nil {
+		return 0, err
+	}
+	if _, err := services.EvaluateGoals(tx, userID); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+// GetExerciseProgress returns workout logs for an exercise ordered by date.
+// Pass metric to receive an aggregated series instead, bucketed by day, week
+// or month; metric=custom charts the number custom field given by field_id.
+// source selects between private and public exercises with the same ID, and
+// start_date/end_date limit the range.
 func GetExerciseProgress(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
@@ -381,13 +669,40 @@ func GetExerciseProgress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify exercise belongs to user
-	var exerciseExists int64
-	err = database.DB.QueryRow(
-		"SELECT id FROM exercises WHERE id = ? AND user_id = ?",
-		exerciseID, userID,
-	).Scan(&exerciseExists)
+	query := r.URL.Query()
+	source := query.Get("source")
+	if source != "" && source != "private" && source != "public" {
+		http.Error(w, `{"error":"source must be private or public"}`, http.StatusBadRequest)
+		return
+	}
+	metric := query.Get("metric")
+	if metric != "" && !isValidProgressMetric(metric) {
+		http.Error(w, `{"error":"metric must be max_weight, volume, estimated_1rm, relative_1rm, reps, distance, pace, duration, hold_time or custom"}`, http.StatusBadRequest)
+		return
+	}
+	var fieldID int64
+	if metric == ProgressCustom {
+		fieldID, err = strconv.ParseInt(query.Get("field_id"), 10, 64)
+		if err != nil {
+			http.Error(w, `{"error":"field_id is required for the custom metric"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	bucket := query.Get("bucket")
+	if bucket == "" {
+		bucket = BucketDay
+	} else if !isValidProgressBucket(bucket) {
+		http.Error(w, `{"error":"bucket must be day, week or month"}`, http.StatusBadRequest)
+		return
+	}
+	startDate, endDate := query.Get("start_date"), query.Get("end_date")
+	if err := validateProgressRange(startDate, endDate); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
 
+	// Verify the exercise is one of the user's or a public one
+	exercise, err := resolveExercise(userID, exerciseID, source)
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
 		return
@@ -397,14 +712,22 @@ func GetExerciseProgress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get all workout logs for this exercise
-	rows, err := database.DB.Query(
-		`SELECT id, date, weight, weight_per_set, rest_time, distance, duration, pace, lap_times, sets, reps, notes
+	// Get the workout logs for this exercise in the requested range
+	logsQuery := `SELECT id, date, weight, weight_per_set, rest_time, distance, duration, pace, lap_times, sets, reps, notes
 		 FROM workout_logs
-		 WHERE exercise_id = ? AND user_id = ?
-		 ORDER BY date ASC`,
-		exerciseID, userID,
-	)
+		 WHERE exercise_id = ? AND exercise_source = ? AND user_id = ?`
+	params := []interface{}{exerciseID, exercise.Source, userID}
+	if startDate != "" {
+		logsQuery += " AND substr(date, 1, 10) >= ?"
+		params = append(params, startDate)
+	}
+	if endDate != "" {
+		logsQuery += " AND substr(date, 1, 10) <= ?"
+		params = append(params, endDate)
+	}
+	logsQuery += " ORDER BY date ASC, created_at ASC"
+
+	rows, err := database.DB.Query(logsQuery, params...)
 	if err != nil {
 		fmt.Printf("Get progress error: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
@@ -443,7 +766,142 @@ func GetExerciseProgress(w http.ResponseWriter, r *http.Request) {
 		logs = append(logs, log)
 	}
 
+	if err := attachLogDetails(logs); err != nil {
+		fmt.Printf("Get progress error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get progress error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if metric == ProgressCustom {
+		field, err := fetchCustomField(userID, exercise, fieldID)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Custom field not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Printf("Get progress error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if field.FieldType != models.CustomFieldNumber {
+			http.Error(w, `{"error":"Only number custom fields can be charted"}`, http.StatusBadRequest)
+			return
+		}
+
+		unit := ""
+		if field.Unit != nil {
+			unit = *field.Unit
+		}
+		response := ProgressSeriesResponse{
+			ExerciseID:     exercise.ID,
+			ExerciseSource: exercise.Source,
+			Metric:         metric,
+			Bucket:         bucket,
+			Unit:           unit,
+			FieldID:        &field.ID,
+			Series:         buildCustomMetricSeries(logs, field.ID, bucket),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if metric != "" {
+		var bodyweights services.Bodyweights
+		if models.RulesForExerciseType(exercise.Type).UsesBodyweight() || metric == ProgressRelative1RM {
+			bodyweights, err = services.LoadBodyweights(database.DB, userID)
+			if err != nil {
+				fmt.Printf("Get progress error: %v\n", err)
+				http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+				return
+			}
+		}
+
+		response := ProgressSeriesResponse{
+			ExerciseID:     exercise.ID,
+			ExerciseSource: exercise.Source,
+			Metric:         metric,
+			Bucket:         bucket,
+			Unit:           progressUnit(metric, units),
+			Series:         buildProgressSeries(logs, metric, bucket, exercise.Type, bodyweights, units),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	localizeWorkoutLogs(logs, units)
+
 	response := ProgressResponse{Progress: logs}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
+
+// exerciseRef identifies an exercise from either the user's library or the public library
+type exerciseRef struct {
+	ID     int64
+	Source string
+	Name   string
+	Type   string
+}
+
+// resolveExercise finds an exercise the user can log. source is "private",
+// "public", or empty to try the user's exercises before public ones.
+// Returns sql.ErrNoRows if no matching exercise exists.
+func resolveExercise(userID, exerciseID int64, source string) (exerciseRef, error) {
+	ref := exerciseRef{ID: exerciseID}
+
+	if source == "" || source == "private" {
+		err := database.DB.QueryRow(
+			"SELECT name, exercise_type FROM exercises WHERE id = ? AND user_id = ?",
+			exerciseID, userID,
+		).Scan(&ref.Name, &ref.Type)
+		if err == nil {
+			ref.Source = "private"
+			return ref, nil
+		} else if err != sql.ErrNoRows || source == "private" {
+			return ref, err
+		}
+	}
+
+	if source == "" || source == "public" {
+		err := database.DB.QueryRow(
+			"SELECT name, exercise_type FROM public_exercises WHERE id = ?",
+			exerciseID,
+		).Scan(&ref.Name, &ref.Type)
+		if err != nil {
+			return ref, err
+		}
+		ref.Source = "public"
+		return ref, nil
+	}
+
+	return ref, sql.ErrNoRows
+}
+
+// resolveRequestExercise resolves an exercise addressed by ID and the optional
+// source query parameter, writing the error response if it cannot be found
+func resolveRequestExercise(w http.ResponseWriter, r *http.Request, userID, exerciseID int64) (exerciseRef, bool) {
+	source := r.URL.Query().Get("source")
+	if source != "" && source != "private" && source != "public" {
+		http.Error(w, `{"error":"source must be private or public"}`, http.StatusBadRequest)
+		return exerciseRef{}, false
+	}
+
+	exercise, err := resolveExercise(userID, exerciseID, source)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
+		return exercise, false
+	} else if err != nil {
+		fmt.Printf("Resolve exercise error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return exercise, false
+	}
+	return exercise, true
+}
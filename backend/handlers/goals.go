@@ -0,0 +1,596 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+	"gym-app-backend/utils"
+)
+
+type GoalResponse struct {
+	Goal models.Goal `json:"goal"`
+}
+
+type GoalsResponse struct {
+	Goals []models.Goal `json:"goals"`
+}
+
+// CreateGoalRequest creates a goal. target_value is a weight in the user's
+// weight unit, a distance in their distance unit or a number of training
+// days, depending on goal_type.
+type CreateGoalRequest struct {
+	GoalType       string  `json:"goal_type"`
+	ExerciseID     *int64  `json:"exercise_id"`
+	ExerciseSource string  `json:"exercise_source"` // "private" or "public", empty to find the exercise
+	TargetValue    float64 `json:"target_value"`
+	TargetReps     *int    `json:"target_reps"`
+	TargetDuration *int    `json:"target_duration"`
+	StartDate      string  `json:"start_date"` // defaults to today
+	Deadline       string  `json:"deadline"`
+	Notes          *string `json:"notes"`
+}
+
+// UpdateGoalRequest changes the values that are set; the type and exercise of
+// a goal are fixed
+type UpdateGoalRequest struct {
+	TargetValue    *float64 `json:"target_value"`
+	TargetReps     *int     `json:"target_reps"`
+	TargetDuration *int     `json:"target_duration"`
+	StartDate      *string  `json:"start_date"`
+	Deadline       *string  `json:"deadline"`
+	Notes          *string  `json:"notes"`
+}
+
+const goalSelect = `
+	SELECT g.id, g.user_id, g.exercise_id, g.exercise_source, COALESCE(e.name, pe.name), g.goal_type,
+	       g.target_value, g.target_reps, g.target_duration, substr(g.start_date, 1, 10),
+	       substr(g.deadline, 1, 10), g.notes, substr(g.achieved_on, 1, 10), g.achieved_log_id,
+	       g.created_at, g.updated_at
+	FROM goals g
+	LEFT JOIN exercises e ON g.exercise_source = 'private' AND g.exercise_id = e.id AND g.user_id = e.user_id
+	LEFT JOIN public_exercises pe ON g.exercise_source = 'public' AND g.exercise_id = pe.id
+`
+
+func scanGoal(row rowScanner) (models.Goal, error) {
+	var goal models.Goal
+	err := row.Scan(
+		&goal.ID, &goal.UserID, &goal.ExerciseID, &goal.ExerciseSource, &goal.ExerciseName, &goal.GoalType,
+		&goal.TargetValue, &goal.TargetReps, &goal.TargetDuration, &goal.StartDate,
+		&goal.Deadline, &goal.Notes, &goal.AchievedOn, &goal.AchievedLogID,
+		&goal.CreatedAt, &goal.UpdatedAt,
+	)
+	return goal, err
+}
+
+// fetchGoals loads the user's goals by deadline, in canonical units and without progress
+func fetchGoals(userID int64) ([]models.Goal, error) {
+	rows, err := database.DB.Query(goalSelect+" WHERE g.user_id = ? ORDER BY g.deadline ASC, g.id ASC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []models.Goal{}
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+// fetchGoal loads a goal owned by the user, in canonical units and without progress
+func fetchGoal(goalID, userID int64) (models.Goal, error) {
+	return scanGoal(database.DB.QueryRow(goalSelect+" WHERE g.id = ? AND g.user_id = ?", goalID, userID))
+}
+
+// measureGoals fills in the progress of goals and converts them to the user's units
+func measureGoals(goals []models.Goal, units utils.Units) error {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for i := range goals {
+		if err := services.MeasureGoalProgress(database.DB, &goals[i], today); err != nil {
+			return err
+		}
+		localizeGoal(&goals[i], units)
+	}
+	return nil
+}
+
+// localizeGoal converts a measured goal to the user's units in place. Times
+// of distance goals are minutes and need no conversion.
+func localizeGoal(goal *models.Goal, units utils.Units) {
+	switch goal.GoalType {
+	case models.GoalWeight:
+		goal.TargetValue = units.WeightFromCanonical(goal.TargetValue)
+		goal.Progress.CurrentValue = weightFromCanonical(units, goal.Progress.CurrentValue)
+		goal.Progress.BaselineValue = weightFromCanonical(units, goal.Progress.BaselineValue)
+	case models.GoalDistanceTime:
+		goal.TargetValue = units.DistanceFromCanonical(goal.TargetValue)
+	}
+}
+
+// validateGoalTargets checks the targets of a goal against its type and the
+// rules of its exercise
+func validateGoalTargets(goalType string, rules *models.ExerciseTypeRules, targetValue float64, targetReps, targetDuration *int) error {
+	if targetValue <= 0 {
+		return fmt.Errorf("target_value must be positive")
+	}
+	if targetReps != nil && goalType != models.GoalWeight {
+		return fmt.Errorf("target_reps can only be set on weight goals")
+	}
+	if targetDuration != nil && goalType != models.GoalDistanceTime {
+		return fmt.Errorf("target_duration can only be set on distance_time goals")
+	}
+
+	switch goalType {
+	case models.GoalWeight:
+		if rules == nil {
+			return fmt.Errorf("weight goals need an exercise")
+		}
+		if rules.Load == models.LoadNone || rules.Load == models.LoadAssistance {
+			return fmt.Errorf("weight goals need an exercise lifted with a load")
+		}
+		if targetReps != nil && *targetReps < 1 {
+			return fmt.Errorf("target_reps must be at least 1")
+		}
+	case models.GoalDistanceTime:
+		if rules == nil {
+			return fmt.Errorf("distance_time goals need an exercise")
+		}
+		if !rules.Distance {
+			return fmt.Errorf("distance_time goals need an exercise logged with distance")
+		}
+		if targetDuration == nil || *targetDuration <= 0 {
+			return fmt.Errorf("target_duration must be a positive number of minutes")
+		}
+	case models.GoalWorkoutCount:
+		if targetValue != math.Trunc(targetValue) {
+			return fmt.Errorf("target_value of workout_count goals must be a whole number")
+		}
+	}
+	return nil
+}
+
+// validateGoalDates checks the start date and deadline of a goal
+func validateGoalDates(startDate, deadline string) error {
+	if deadline == "" {
+		return fmt.Errorf("deadline is required")
+	}
+	if _, err := time.Parse(utils.DateLayout, deadline); err != nil {
+		return fmt.Errorf("deadline must use the YYYY-MM-DD format")
+	}
+	if _, err := time.Parse(utils.DateLayout, startDate); err != nil {
+		return fmt.Errorf("start_date must use the YYYY-MM-DD format")
+	}
+	if startDate > deadline {
+		return fmt.Errorf("start_date must not be after the deadline")
+	}
+	return nil
+}
+
+// goalExerciseRules returns the rules of a goal's exercise, or nil for goals
+// across all exercises. Returns sql.ErrNoRows if the exercise is gone.
+func goalExerciseRules(goal models.Goal) (*models.ExerciseTypeRules, error) {
+	if goal.ExerciseID == nil {
+		return nil, nil
+	}
+	source := ""
+	if goal.ExerciseSource != nil {
+		source = *goal.ExerciseSource
+	}
+	exercise, err := resolveExercise(goal.UserID, *goal.ExerciseID, source)
+	if err != nil {
+		return nil, err
+	}
+	rules := models.RulesForExerciseType(exercise.Type)
+	return &rules, nil
+}
+
+func parseGoalID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(strings.TrimSuffix(r.URL.Path[len("/api/goals/"):], "/"), 10, 64)
+}
+
+// writeGoal writes a goal with its progress
+func writeGoal(w http.ResponseWriter, goalID, userID int64, status int) {
+	goal, err := fetchGoal(goalID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Goal not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Error fetching goal: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Error fetching goal: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	goals := []models.Goal{goal}
+	if err := measureGoals(goals, units); err != nil {
+		fmt.Printf("Error fetching goal: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := GoalResponse{Goal: goals[0]}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetGoals returns the user's goals with their progress, earliest deadline
+// first. Pass status to only return goals that are achieved, on_track,
+// behind or missed.
+func GetGoals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.GoalAchieved, models.GoalOnTrack, models.GoalBehind, models.GoalMissed:
+	default:
+		http.Error(w, `{"error":"status must be achieved, on_track, behind or missed"}`, http.StatusBadRequest)
+		return
+	}
+
+	goals, err := fetchGoals(userID)
+	if err != nil {
+		fmt.Printf("Get goals error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get goals error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := measureGoals(goals, units); err != nil {
+		fmt.Printf("Get goals error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if status != "" {
+		filtered := []models.Goal{}
+		for _, goal := range goals {
+			if goal.Progress.Status == status {
+				filtered = append(filtered, goal)
+			}
+		}
+		goals = filtered
+	}
+
+	response := GoalsResponse{Goals: goals}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetGoalById returns a single goal with its progress
+func GetGoalById(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	goalID, err := parseGoalID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid goal ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	writeGoal(w, goalID, userID, http.StatusOK)
+}
+
+// CreateGoal creates a goal. Logs already recorded between its start date
+// and deadline count, so a goal can be achieved as soon as it is created.
+func CreateGoal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	var req CreateGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if !models.IsValidGoalType(req.GoalType) {
+		http.Error(w, `{"error":"goal_type must be weight, distance_time or workout_count"}`, http.StatusBadRequest)
+		return
+	}
+	if req.ExerciseSource != "" && req.ExerciseSource != "private" && req.ExerciseSource != "public" {
+		http.Error(w, `{"error":"exercise_source must be private or public"}`, http.StatusBadRequest)
+		return
+	}
+	if req.StartDate == "" {
+		req.StartDate = utils.FormatDate(time.Now())
+	}
+	if err := validateGoalDates(req.StartDate, req.Deadline); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var rules *models.ExerciseTypeRules
+	var exerciseSource *string
+	if req.ExerciseID != nil {
+		exercise, err := resolveExercise(userID, *req.ExerciseID, req.ExerciseSource)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Printf("Create goal error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		exerciseRules := models.RulesForExerciseType(exercise.Type)
+		rules, exerciseSource = &exerciseRules, &exercise.Source
+	}
+	if err := validateGoalTargets(req.GoalType, rules, req.TargetValue, req.TargetReps, req.TargetDuration); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Create goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	targetValue := req.TargetValue
+	switch req.GoalType {
+	case models.GoalWeight:
+		targetValue = units.WeightToCanonical(targetValue)
+	case models.GoalDistanceTime:
+		targetValue = units.DistanceToCanonical(targetValue)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Create goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO goals (user_id, exercise_id, exercise_source, goal_type, target_value, target_reps,
+		                    target_duration, start_date, deadline, notes)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, req.ExerciseID, exerciseSource, req.GoalType, targetValue, req.TargetReps,
+		req.TargetDuration, req.StartDate, req.Deadline, req.Notes,
+	)
+	if err != nil {
+		fmt.Printf("Create goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	goalID, _ := result.LastInsertId()
+
+	if _, err := services.EvaluateGoals(tx, userID); err != nil {
+		fmt.Printf("Create goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Create goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeGoal(w, goalID, userID, http.StatusCreated)
+}
+
+// UpdateGoal updates the targets, dates and notes of a goal
+func UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	goalID, err := parseGoalID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid goal ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	goal, err := fetchGoal(goalID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Goal not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Update goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var req UpdateGoalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Update goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// The goal is checked as it will be after the update
+	localizeGoal(&goal, units)
+	if req.TargetValue != nil {
+		goal.TargetValue = *req.TargetValue
+	}
+	if req.TargetReps != nil {
+		goal.TargetReps = req.TargetReps
+	}
+	if req.TargetDuration != nil {
+		goal.TargetDuration = req.TargetDuration
+	}
+	if req.StartDate != nil {
+		goal.StartDate = *req.StartDate
+	}
+	if req.Deadline != nil {
+		goal.Deadline = *req.Deadline
+	}
+	if err := validateGoalDates(goal.StartDate, goal.Deadline); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	rules, err := goalExerciseRules(goal)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"The exercise of this goal no longer exists"}`, http.StatusConflict)
+		return
+	} else if err != nil {
+		fmt.Printf("Update goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := validateGoalTargets(goal.GoalType, rules, goal.TargetValue, goal.TargetReps, goal.TargetDuration); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	// Build update query dynamically
+	updates := []string{"updated_at = CURRENT_TIMESTAMP"}
+	values := []interface{}{}
+
+	if req.TargetValue != nil {
+		targetValue := *req.TargetValue
+		switch goal.GoalType {
+		case models.GoalWeight:
+			targetValue = units.WeightToCanonical(targetValue)
+		case models.GoalDistanceTime:
+			targetValue = units.DistanceToCanonical(targetValue)
+		}
+		updates = append(updates, "target_value = ?")
+		values = append(values, targetValue)
+	}
+	if req.TargetReps != nil {
+		updates = append(updates, "target_reps = ?")
+		values = append(values, *req.TargetReps)
+	}
+	if req.TargetDuration != nil {
+		updates = append(updates, "target_duration = ?")
+		values = append(values, *req.TargetDuration)
+	}
+	if req.StartDate != nil {
+		updates = append(updates, "start_date = ?")
+		values = append(values, *req.StartDate)
+	}
+	if req.Deadline != nil {
+		updates = append(updates, "deadline = ?")
+		values = append(values, *req.Deadline)
+	}
+	if req.Notes != nil {
+		updates = append(updates, "notes = ?")
+		values = append(values, *req.Notes)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Update goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	values = append(values, goalID, userID)
+	query := fmt.Sprintf("UPDATE goals SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
+	if _, err := tx.Exec(query, values...); err != nil {
+		fmt.Printf("Update goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// New targets or dates may achieve the goal or reopen it
+	if _, err := services.EvaluateGoals(tx, userID); err != nil {
+		fmt.Printf("Update goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Update goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	writeGoal(w, goalID, userID, http.StatusOK)
+}
+
+// DeleteGoal deletes a goal
+func DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	goalID, err := parseGoalID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid goal ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	result, err := database.DB.Exec("DELETE FROM goals WHERE id = ? AND user_id = ?", goalID, userID)
+	if err != nil {
+		fmt.Printf("Delete goal error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		http.Error(w, `{"error":"Goal not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Goal deleted successfully"})
+}
+
+// goalsAchievedBy returns the goals in achieved whose qualifying log is logID,
+// in the user's units
+func goalsAchievedBy(achieved []int64, logID, userID int64, units utils.Units) []models.Goal {
+	var goals []models.Goal
+	for _, goalID := range achieved {
+		goal, err := fetchGoal(goalID, userID)
+		if err != nil || goal.AchievedLogID == nil || *goal.AchievedLogID != logID {
+			continue
+		}
+		goals = append(goals, goal)
+	}
+	if err := measureGoals(goals, units); err != nil {
+		fmt.Printf("Error measuring achieved goals: %v\n", err)
+	}
+	return goals
+}
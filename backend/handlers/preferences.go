@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/utils"
+)
+
+type PreferencesResponse struct {
+	Preferences utils.Units `json:"preferences"`
+}
+
+type UpdatePreferencesRequest struct {
+	WeightUnit   *string `json:"weight_unit"`
+	DistanceUnit *string `json:"distance_unit"`
+}
+
+// GetPreferences returns the unit preferences of the authenticated user
+func GetPreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get preferences error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := PreferencesResponse{Preferences: units}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdatePreferences changes the units the user reads and writes values in.
+// Stored values are canonical, so existing logs are simply shown in the new units.
+func UpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	var req UpdatePreferencesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Build update query dynamically
+	updates := []string{}
+	values := []interface{}{}
+
+	if req.WeightUnit != nil {
+		if !utils.IsValidWeightUnit(*req.WeightUnit) {
+			http.Error(w, `{"error":"weight_unit must be kg or lb"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "weight_unit = ?")
+		values = append(values, *req.WeightUnit)
+	}
+	if req.DistanceUnit != nil {
+		if !utils.IsValidDistanceUnit(*req.DistanceUnit) {
+			http.Error(w, `{"error":"distance_unit must be km or mi"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "distance_unit = ?")
+		values = append(values, *req.DistanceUnit)
+	}
+
+	if len(updates) > 0 {
+		values = append(values, userID)
+		query := fmt.Sprintf("UPDATE users SET %s WHERE id = ?", strings.Join(updates, ", "))
+		if _, err := database.DB.Exec(query, values...); err != nil {
+			fmt.Printf("Update preferences error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Update preferences error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := PreferencesResponse{Preferences: units}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
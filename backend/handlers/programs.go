@@ -0,0 +1,1309 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+type ProgramResponse struct {
+	Program models.Program `json:"program"`
+}
+
+type ProgramsResponse struct {
+	Programs []models.Program `json:"programs"`
+}
+
+type ProgramEnrollmentResponse struct {
+	Enrollment    models.ProgramEnrollment     `json:"enrollment"`
+	Schedule      []models.ScheduledProgramDay `json:"schedule,omitempty"`
+	CompletedDays int                          `json:"completed_days"`
+	TotalDays     int                          `json:"total_days"`
+}
+
+type ProgramEnrollmentsResponse struct {
+	Enrollments []ProgramEnrollmentResponse `json:"enrollments"`
+}
+
+type ScheduledTodayResponse struct {
+	Date string                       `json:"date"`
+	Days []models.ScheduledProgramDay `json:"days"`
+}
+
+type ProgramPrescriptionRequest struct {
+	ExerciseID        int64    `json:"exercise_id"`
+	ExerciseSource    string   `json:"exercise_source"`
+	Method            string   `json:"method"` // percentage or increment
+	Sets              *int     `json:"sets"`
+	Reps              *int     `json:"reps"`
+	Percentage        *float64 `json:"percentage"`
+	BaseWeight        *float64 `json:"base_weight"`
+	WeightIncrement   *float64 `json:"weight_increment"`
+	BaseDistance      *float64 `json:"base_distance"`
+	DistanceIncrement *float64 `json:"distance_increment"`
+	BaseDuration      *int     `json:"base_duration"`
+	DurationIncrement *int     `json:"duration_increment"`
+}
+
+type ProgramDayRequest struct {
+	// Week is 1-based; omit it for a day that repeats every week
+	Week          *int                         `json:"week"`
+	Day           int                          `json:"day"`
+	Name          *string                      `json:"name"`
+	TemplateID    *int64                       `json:"template_id"`
+	Prescriptions []ProgramPrescriptionRequest `json:"prescriptions"`
+}
+
+type CreateProgramRequest struct {
+	Name          string              `json:"name"`
+	Description   *string             `json:"description"`
+	DurationWeeks int                 `json:"duration_weeks"`
+	Days          []ProgramDayRequest `json:"days"`
+}
+
+type UpdateProgramRequest struct {
+	Name          *string `json:"name"`
+	Description   *string `json:"description"`
+	DurationWeeks *int    `json:"duration_weeks"`
+	// Days replaces the program's days when present
+	Days *[]ProgramDayRequest `json:"days"`
+}
+
+type EnrollProgramRequest struct {
+	StartDate     string                      `json:"start_date"` // defaults to today
+	TrainingMaxes []models.ProgramTrainingMax `json:"training_maxes"`
+}
+
+// parseProgramPath extracts the program ID and optional action from paths
+// like /api/programs/:id and /api/programs/:id/enroll
+func parseProgramPath(r *http.Request) (int64, string, error) {
+	parts := strings.SplitN(strings.Trim(r.URL.Path[len("/api/programs/"):], "/"), "/", 2)
+	programID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(parts) == 2 {
+		return programID, parts[1], nil
+	}
+	return programID, "", nil
+}
+
+func fetchProgram(programID, userID int64) (models.Program, error) {
+	var program models.Program
+	err := database.DB.QueryRow(
+		"SELECT id, user_id, name, description, duration_weeks, created_at FROM training_programs WHERE id = ? AND user_id = ?",
+		programID, userID,
+	).Scan(&program.ID, &program.UserID, &program.Name, &program.Description, &program.DurationWeeks, &program.CreatedAt)
+	if err != nil {
+		return program, err
+	}
+
+	program.Days, err = fetchProgramDays(programID)
+	return program, err
+}
+
+// fetchProgramDays loads a program's days with their prescriptions
+func fetchProgramDays(programID int64) ([]models.ProgramDay, error) {
+	rows, err := database.DB.Query(
+		"SELECT id, program_id, week, day, name, template_id FROM program_days WHERE program_id = ? ORDER BY COALESCE(week, 0), day, id",
+		programID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	days := []models.ProgramDay{}
+	for rows.Next() {
+		var day models.ProgramDay
+		if err := rows.Scan(&day.ID, &day.ProgramID, &day.Week, &day.Day, &day.Name, &day.TemplateID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		days = append(days, day)
+	}
+	rows.Close()
+
+	for i := range days {
+		prescriptions, err := fetchProgramPrescriptions(days[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		days[i].Prescriptions = prescriptions
+	}
+	return days, nil
+}
+
+func fetchProgramPrescriptions(programDayID int64) ([]models.ProgramPrescription, error) {
+	rows, err := database.DB.Query(
+		`SELECT id, program_day_id, exercise_id, exercise_source, method, sets, reps, percentage,
+		        base_weight, weight_increment, base_distance, distance_increment, base_duration, duration_increment
+		 FROM program_prescriptions
+		 WHERE program_day_id = ?
+		 ORDER BY id`,
+		programDayID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prescriptions := []models.ProgramPrescription{}
+	for rows.Next() {
+		var p models.ProgramPrescription
+		err := rows.Scan(
+			&p.ID, &p.ProgramDayID, &p.ExerciseID, &p.ExerciseSource, &p.Method, &p.Sets, &p.Reps, &p.Percentage,
+			&p.BaseWeight, &p.WeightIncrement, &p.BaseDistance, &p.DistanceIncrement, &p.BaseDuration, &p.DurationIncrement,
+		)
+		if err != nil {
+			return nil, err
+		}
+		prescriptions = append(prescriptions, p)
+	}
+	return prescriptions, rows.Err()
+}
+
+// validateProgramDays checks the days of a program and resolves the exercises
+// of their prescriptions. It writes the error response and returns false on failure.
+func validateProgramDays(w http.ResponseWriter, userID int64, durationWeeks int, days []ProgramDayRequest) ([][]exerciseRef, bool) {
+	refs := make([][]exerciseRef, len(days))
+	for i, day := range days {
+		if day.Day < 1 || day.Day > 7 {
+			http.Error(w, `{"error":"Program day must be between 1 and 7"}`, http.StatusBadRequest)
+			return nil, false
+		}
+		if day.Week != nil && (*day.Week < 1 || *day.Week > durationWeeks) {
+			http.Error(w, `{"error":"Program week must be within the program duration"}`, http.StatusBadRequest)
+			return nil, false
+		}
+
+		if day.TemplateID != nil {
+			var existingID int64
+			err := database.DB.QueryRow(
+				"SELECT id FROM workout_templates WHERE id = ? AND user_id = ?",
+				*day.TemplateID, userID,
+			).Scan(&existingID)
+			if err == sql.ErrNoRows {
+				http.Error(w, `{"error":"Workout template not found"}`, http.StatusNotFound)
+				return nil, false
+			} else if err != nil {
+				fmt.Printf("Validate program day error: %v\n", err)
+				http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+				return nil, false
+			}
+		}
+
+		for _, p := range day.Prescriptions {
+			switch p.Method {
+			case "percentage":
+				if p.Percentage == nil {
+					http.Error(w, `{"error":"Percentage prescriptions require a percentage"}`, http.StatusBadRequest)
+					return nil, false
+				}
+			case "increment":
+			default:
+				http.Error(w, `{"error":"Prescription method must be percentage or increment"}`, http.StatusBadRequest)
+				return nil, false
+			}
+
+			if p.ExerciseSource != "" && p.ExerciseSource != "private" && p.ExerciseSource != "public" {
+				http.Error(w, `{"error":"exercise_source must be private or public"}`, http.StatusBadRequest)
+				return nil, false
+			}
+
+			ref, err := resolveExercise(userID, p.ExerciseID, p.ExerciseSource)
+			if err == sql.ErrNoRows {
+				http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
+				return nil, false
+			} else if err != nil {
+				fmt.Printf("Validate program day error: %v\n", err)
+				http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+				return nil, false
+			}
+			refs[i] = append(refs[i], ref)
+		}
+	}
+	return refs, true
+}
+
+// replaceProgramDays stores days as the days of a program, converting
+// prescribed weights and distances from the user's units
+func replaceProgramDays(tx *sql.Tx, programID int64, days []ProgramDayRequest, refs [][]exerciseRef, units utils.Units) error {
+	_, err := tx.Exec(
+		"DELETE FROM program_prescriptions WHERE program_day_id IN (SELECT id FROM program_days WHERE program_id = ?)",
+		programID,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM program_days WHERE program_id = ?", programID); err != nil {
+		return err
+	}
+
+	for i, day := range days {
+		result, err := tx.Exec(
+			"INSERT INTO program_days (program_id, week, day, name, template_id) VALUES (?, ?, ?, ?, ?)",
+			programID, day.Week, day.Day, day.Name, day.TemplateID,
+		)
+		if err != nil {
+			return err
+		}
+		dayID, _ := result.LastInsertId()
+
+		for j, p := range day.Prescriptions {
+			_, err := tx.Exec(
+				`INSERT INTO program_prescriptions (program_day_id, exercise_id, exercise_source, method, sets, reps, percentage, base_weight, weight_increment, base_distance, distance_increment, base_duration, duration_increment)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				dayID, refs[i][j].ID, refs[i][j].Source, p.Method, p.Sets, p.Reps, p.Percentage,
+				weightToCanonical(units, p.BaseWeight), weightToCanonical(units, p.WeightIncrement),
+				distanceToCanonical(units, p.BaseDistance), distanceToCanonical(units, p.DistanceIncrement),
+				p.BaseDuration, p.DurationIncrement,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func fetchProgramEnrollment(enrollmentID, userID int64) (models.ProgramEnrollment, error) {
+	var enrollment models.ProgramEnrollment
+	err := database.DB.QueryRow(
+		`SELECT pe.id, pe.user_id, pe.program_id, tp.name, pe.start_date, pe.status, pe.created_at
+		 FROM program_enrollments pe
+		 JOIN training_programs tp ON pe.program_id = tp.id
+		 WHERE pe.id = ? AND pe.user_id = ?`,
+		enrollmentID, userID,
+	).Scan(
+		&enrollment.ID, &enrollment.UserID, &enrollment.ProgramID, &enrollment.ProgramName,
+		&enrollment.StartDate, &enrollment.Status, &enrollment.CreatedAt,
+	)
+	if err != nil {
+		return enrollment, err
+	}
+
+	enrollment.TrainingMaxes, err = fetchTrainingMaxes(enrollmentID)
+	return enrollment, err
+}
+
+func fetchTrainingMaxes(enrollmentID int64) ([]models.ProgramTrainingMax, error) {
+	rows, err := database.DB.Query(
+		"SELECT exercise_id, exercise_source, weight FROM program_training_maxes WHERE enrollment_id = ? ORDER BY id",
+		enrollmentID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	maxes := []models.ProgramTrainingMax{}
+	for rows.Next() {
+		var tm models.ProgramTrainingMax
+		if err := rows.Scan(&tm.ExerciseID, &tm.ExerciseSource, &tm.Weight); err != nil {
+			return nil, err
+		}
+		maxes = append(maxes, tm)
+	}
+	return maxes, rows.Err()
+}
+
+// buildProgramSchedule places every program day of an enrollment on the
+// calendar, computes its targets in the user's units and marks it complete when
+// the user logged each of its exercises between the scheduled date and the
+// next scheduled day.
+func buildProgramSchedule(userID int64, enrollment models.ProgramEnrollment, program models.Program, units utils.Units) ([]models.ScheduledProgramDay, error) {
+	start, err := utils.ParseDate(enrollment.StartDate)
+	if err != nil {
+		return nil, err
+	}
+
+	trainingMaxes := make(map[string]float64)
+	for _, tm := range enrollment.TrainingMaxes {
+		trainingMaxes[fmt.Sprintf("%s:%d", tm.ExerciseSource, tm.ExerciseID)] = tm.Weight
+	}
+
+	templateExercises := make(map[int64][]models.WorkoutTemplateExercise)
+	exerciseRefs := make(map[string]exerciseRef)
+	schedule := []models.ScheduledProgramDay{}
+
+	for week := 1; week <= program.DurationWeeks; week++ {
+		for _, day := range program.Days {
+			if day.Week != nil && *day.Week != week {
+				continue
+			}
+
+			scheduled := models.ScheduledProgramDay{
+				EnrollmentID: enrollment.ID,
+				ProgramID:    program.ID,
+				ProgramDayID: day.ID,
+				Date:         utils.FormatDate(start.AddDate(0, 0, (week-1)*7+day.Day-1)),
+				Week:         week,
+				Day:          day.Day,
+				Name:         day.Name,
+				TemplateID:   day.TemplateID,
+				Exercises:    []models.ScheduledExercise{},
+			}
+
+			if day.TemplateID != nil {
+				exercises, ok := templateExercises[*day.TemplateID]
+				if !ok {
+					exercises, err = fetchTemplateExercises(*day.TemplateID, userID)
+					if err != nil {
+						return nil, err
+					}
+					templateExercises[*day.TemplateID] = exercises
+				}
+				for _, te := range exercises {
+					scheduled.Exercises = append(scheduled.Exercises, models.ScheduledExercise{
+						ExerciseID:     te.ExerciseID,
+						ExerciseSource: te.ExerciseSource,
+						ExerciseName:   te.ExerciseName,
+						ExerciseType:   te.ExerciseType,
+						Sets:           te.TargetSets,
+						Reps:           te.TargetReps,
+						Weight:         weightFromCanonical(units, te.TargetWeight),
+						Distance:       distanceFromCanonical(units, te.TargetDistance),
+						Duration:       te.TargetDuration,
+					})
+				}
+			}
+
+			for _, p := range day.Prescriptions {
+				applyPrescription(&scheduled, p, week, trainingMaxes, units)
+			}
+
+			// Exercises added by prescriptions alone carry no name yet
+			for i := range scheduled.Exercises {
+				exercise := &scheduled.Exercises[i]
+				if exercise.ExerciseName != nil {
+					continue
+				}
+				key := fmt.Sprintf("%s:%d", exercise.ExerciseSource, exercise.ExerciseID)
+				ref, ok := exerciseRefs[key]
+				if !ok {
+					ref, err = resolveExercise(userID, exercise.ExerciseID, exercise.ExerciseSource)
+					if err != nil && err != sql.ErrNoRows {
+						return nil, err
+					}
+					exerciseRefs[key] = ref
+				}
+				if ref.Name != "" {
+					exercise.ExerciseName = &ref.Name
+					exercise.ExerciseType = &ref.Type
+				}
+			}
+
+			schedule = append(schedule, scheduled)
+		}
+	}
+
+	sort.SliceStable(schedule, func(a, b int) bool {
+		return schedule[a].Date < schedule[b].Date
+	})
+
+	if err := markScheduleCompletion(userID, schedule); err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// applyPrescription computes the targets of a prescription for a program week
+// and applies them to the matching exercise of the day, adding it if needed.
+// Percentage weights are rounded to the plate increment in the user's units.
+func applyPrescription(day *models.ScheduledProgramDay, p models.ProgramPrescription, week int, trainingMaxes map[string]float64, units utils.Units) {
+	var exercise *models.ScheduledExercise
+	for i := range day.Exercises {
+		if day.Exercises[i].ExerciseID == p.ExerciseID && day.Exercises[i].ExerciseSource == p.ExerciseSource {
+			exercise = &day.Exercises[i]
+			break
+		}
+	}
+	if exercise == nil {
+		day.Exercises = append(day.Exercises, models.ScheduledExercise{
+			ExerciseID:     p.ExerciseID,
+			ExerciseSource: p.ExerciseSource,
+		})
+		exercise = &day.Exercises[len(day.Exercises)-1]
+	}
+
+	if p.Sets != nil {
+		exercise.Sets = p.Sets
+	}
+	if p.Reps != nil {
+		exercise.Reps = p.Reps
+	}
+
+	progressions := float64(week - 1)
+	switch p.Method {
+	case "percentage":
+		if tm, ok := trainingMaxes[fmt.Sprintf("%s:%d", p.ExerciseSource, p.ExerciseID)]; ok && p.Percentage != nil {
+			weight := units.WeightFromCanonical(tm * *p.Percentage / 100)
+			increment := units.PlateIncrement()
+			weight = math.Round(weight/increment) * increment
+			exercise.Weight = &weight
+		}
+	case "increment":
+		if p.BaseWeight != nil {
+			weight := *p.BaseWeight
+			if p.WeightIncrement != nil {
+				weight += *p.WeightIncrement * progressions
+			}
+			exercise.Weight = weightFromCanonical(units, &weight)
+		}
+		if p.BaseDistance != nil {
+			distance := *p.BaseDistance
+			if p.DistanceIncrement != nil {
+				distance += *p.DistanceIncrement * progressions
+			}
+			exercise.Distance = distanceFromCanonical(units, &distance)
+		}
+		if p.BaseDuration != nil {
+			duration := *p.BaseDuration
+			if p.DurationIncrement != nil {
+				duration += *p.DurationIncrement * (week - 1)
+			}
+			exercise.Duration = &duration
+		}
+	}
+}
+
+// markScheduleCompletion matches the schedule against the user's performed
+// workout logs. A scheduled day may be made up until the day before the next
+// scheduled day. The last day, which has no next day, gets a window as long as
+// the gap before it, or a week when the schedule has a single date.
+func markScheduleCompletion(userID int64, schedule []models.ScheduledProgramDay) error {
+	if len(schedule) == 0 {
+		return nil
+	}
+
+	rows, err := database.DB.Query(
+		`SELECT DISTINCT substr(date, 1, 10), exercise_id, exercise_source
+		 FROM workout_logs
+		 WHERE user_id = ? AND date >= ? AND planned = 0`,
+		userID, schedule[0].Date,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	logged := make(map[string]map[exerciseRef]bool)
+	for rows.Next() {
+		var date string
+		var ex exerciseRef
+		if err := rows.Scan(&date, &ex.ID, &ex.Source); err != nil {
+			return err
+		}
+		if logged[date] == nil {
+			logged[date] = make(map[exerciseRef]bool)
+		}
+		logged[date][ex] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Make-up days of the last date: the gap from the date before it
+	lastDate, _ := utils.ParseDate(schedule[len(schedule)-1].Date)
+	lastGap := 7
+	for i := len(schedule) - 1; i >= 0; i-- {
+		if schedule[i].Date < schedule[len(schedule)-1].Date {
+			previous, _ := utils.ParseDate(schedule[i].Date)
+			lastGap = int(lastDate.Sub(previous).Hours() / 24)
+			break
+		}
+	}
+
+	for i := range schedule {
+		windowEnd := utils.FormatDate(lastDate.AddDate(0, 0, lastGap-1))
+		for j := i + 1; j < len(schedule); j++ {
+			if schedule[j].Date > schedule[i].Date {
+				next, _ := utils.ParseDate(schedule[j].Date)
+				windowEnd = utils.FormatDate(next.AddDate(0, 0, -1))
+				break
+			}
+		}
+
+		completed := 0
+		for k := range schedule[i].Exercises {
+			exercise := &schedule[i].Exercises[k]
+			ref := exerciseRef{ID: exercise.ExerciseID, Source: exercise.ExerciseSource}
+			for date, exercises := range logged {
+				if date >= schedule[i].Date && date <= windowEnd && exercises[ref] {
+					exercise.Completed = true
+					break
+				}
+			}
+			if exercise.Completed {
+				completed++
+			}
+		}
+		schedule[i].CompletedExercises = completed
+		schedule[i].Completed = len(schedule[i].Exercises) > 0 && completed == len(schedule[i].Exercises)
+	}
+	return nil
+}
+
+// localizeProgram converts the prescriptions of a stored program to the user's units
+func localizeProgram(program *models.Program, units utils.Units) {
+	for i := range program.Days {
+		for j := range program.Days[i].Prescriptions {
+			p := &program.Days[i].Prescriptions[j]
+			p.BaseWeight = weightFromCanonical(units, p.BaseWeight)
+			p.WeightIncrement = weightFromCanonical(units, p.WeightIncrement)
+			p.BaseDistance = distanceFromCanonical(units, p.BaseDistance)
+			p.DistanceIncrement = distanceFromCanonical(units, p.DistanceIncrement)
+		}
+	}
+}
+
+// buildEnrollmentResponse computes the schedule of an enrollment and marks the
+// enrollment completed once every scheduled day is done
+func buildEnrollmentResponse(userID int64, enrollment models.ProgramEnrollment) (ProgramEnrollmentResponse, error) {
+	response := ProgramEnrollmentResponse{Enrollment: enrollment}
+
+	program, err := fetchProgram(enrollment.ProgramID, userID)
+	if err != nil {
+		return response, err
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		return response, err
+	}
+
+	schedule, err := buildProgramSchedule(userID, enrollment, program, units)
+	if err != nil {
+		return response, err
+	}
+
+	trainingMaxes := make([]models.ProgramTrainingMax, len(enrollment.TrainingMaxes))
+	for i, tm := range enrollment.TrainingMaxes {
+		tm.Weight = units.WeightFromCanonical(tm.Weight)
+		trainingMaxes[i] = tm
+	}
+	response.Enrollment.TrainingMaxes = trainingMaxes
+
+	for _, day := range schedule {
+		if day.Completed {
+			response.CompletedDays++
+		}
+	}
+	response.TotalDays = len(schedule)
+	response.Schedule = schedule
+
+	if enrollment.Status == "active" && response.TotalDays > 0 && response.CompletedDays == response.TotalDays {
+		_, err := database.DB.Exec("UPDATE program_enrollments SET status = 'completed' WHERE id = ?", enrollment.ID)
+		if err != nil {
+			return response, err
+		}
+		response.Enrollment.Status = "completed"
+	}
+	return response, nil
+}
+
+// GetAllPrograms returns all training programs for the authenticated user
+func GetAllPrograms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	rows, err := database.DB.Query(
+		"SELECT id FROM training_programs WHERE user_id = ? ORDER BY name ASC",
+		userID,
+	)
+	if err != nil {
+		fmt.Printf("Get programs error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var programIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			fmt.Printf("Error scanning program: %v\n", err)
+			continue
+		}
+		programIDs = append(programIDs, id)
+	}
+	rows.Close()
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get programs error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var programs []models.Program
+	for _, id := range programIDs {
+		program, err := fetchProgram(id, userID)
+		if err != nil {
+			fmt.Printf("Get programs error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		localizeProgram(&program, units)
+		programs = append(programs, program)
+	}
+
+	response := ProgramsResponse{Programs: programs}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetProgramById returns a single training program by ID
+func GetProgramById(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	programID, _, err := parseProgramPath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid program ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	program, err := fetchProgram(programID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Program not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Get program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeProgram(&program, units)
+
+	response := ProgramResponse{Program: program}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateProgram creates a new training program
+func CreateProgram(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	var req CreateProgramRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.DurationWeeks < 1 {
+		http.Error(w, `{"error":"Program name and duration in weeks are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	refs, ok := validateProgramDays(w, userID, req.DurationWeeks, req.Days)
+	if !ok {
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Create program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Create program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO training_programs (user_id, name, description, duration_weeks) VALUES (?, ?, ?, ?)",
+		userID, req.Name, req.Description, req.DurationWeeks,
+	)
+	if err != nil {
+		fmt.Printf("Create program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	programID, _ := result.LastInsertId()
+
+	if err := replaceProgramDays(tx, programID, req.Days, refs, units); err != nil {
+		fmt.Printf("Create program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Create program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	program, err := fetchProgram(programID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching created program: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeProgram(&program, units)
+
+	response := ProgramResponse{Program: program}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateProgram updates a training program and optionally replaces its days
+func UpdateProgram(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	programID, _, err := parseProgramPath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid program ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	existing, err := fetchProgram(programID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Program not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Update program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Update program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var req UpdateProgramRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Build update query dynamically
+	updates := []string{}
+	values := []interface{}{}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			http.Error(w, `{"error":"Program name is required"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "name = ?")
+		values = append(values, *req.Name)
+	}
+	if req.Description != nil {
+		updates = append(updates, "description = ?")
+		values = append(values, *req.Description)
+	}
+
+	durationWeeks := existing.DurationWeeks
+	if req.DurationWeeks != nil {
+		if *req.DurationWeeks < 1 {
+			http.Error(w, `{"error":"Program duration must be at least one week"}`, http.StatusBadRequest)
+			return
+		}
+		durationWeeks = *req.DurationWeeks
+		updates = append(updates, "duration_weeks = ?")
+		values = append(values, durationWeeks)
+	}
+
+	var refs [][]exerciseRef
+	if req.Days != nil {
+		var ok bool
+		refs, ok = validateProgramDays(w, userID, durationWeeks, *req.Days)
+		if !ok {
+			return
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Update program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if len(updates) > 0 {
+		values = append(values, programID, userID)
+		query := fmt.Sprintf("UPDATE training_programs SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
+		if _, err := tx.Exec(query, values...); err != nil {
+			fmt.Printf("Update program error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.Days != nil {
+		if err := replaceProgramDays(tx, programID, *req.Days, refs, units); err != nil {
+			fmt.Printf("Update program error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Update program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	program, err := fetchProgram(programID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching updated program: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeProgram(&program, units)
+
+	response := ProgramResponse{Program: program}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteProgram deletes a training program along with its days and enrollments
+func DeleteProgram(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	programID, _, err := parseProgramPath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid program ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Verify program belongs to user
+	var existingID int64
+	err = database.DB.QueryRow(
+		"SELECT id FROM training_programs WHERE id = ? AND user_id = ?",
+		programID, userID,
+	).Scan(&existingID)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Program not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Delete program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Delete program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"DELETE FROM program_training_maxes WHERE enrollment_id IN (SELECT id FROM program_enrollments WHERE program_id = ?)",
+		"DELETE FROM program_enrollments WHERE program_id = ?",
+		"DELETE FROM program_prescriptions WHERE program_day_id IN (SELECT id FROM program_days WHERE program_id = ?)",
+		"DELETE FROM program_days WHERE program_id = ?",
+		"DELETE FROM training_programs WHERE id = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, programID); err != nil {
+			fmt.Printf("Delete program error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Delete program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Program deleted successfully"})
+}
+
+// EnrollInProgram starts following a program from a start date
+func EnrollInProgram(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	programID, _, err := parseProgramPath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid program ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req EnrollProgramRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.StartDate == "" {
+		req.StartDate = utils.FormatDate(time.Now())
+	}
+	if _, err := utils.ParseDate(req.StartDate); err != nil {
+		http.Error(w, `{"error":"Invalid start date"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Verify program belongs to user
+	var existingID int64
+	err = database.DB.QueryRow(
+		"SELECT id FROM training_programs WHERE id = ? AND user_id = ?",
+		programID, userID,
+	).Scan(&existingID)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Program not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Enroll in program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Only one active enrollment per program
+	var activeID int64
+	err = database.DB.QueryRow(
+		"SELECT id FROM program_enrollments WHERE program_id = ? AND user_id = ? AND status = 'active'",
+		programID, userID,
+	).Scan(&activeID)
+	if err == nil {
+		http.Error(w, `{"error":"Already enrolled in this program"}`, http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		fmt.Printf("Enroll in program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	refs := make([]exerciseRef, len(req.TrainingMaxes))
+	for i, tm := range req.TrainingMaxes {
+		ref, err := resolveExercise(userID, tm.ExerciseID, tm.ExerciseSource)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Printf("Enroll in program error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		refs[i] = ref
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Enroll in program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Enroll in program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO program_enrollments (user_id, program_id, start_date) VALUES (?, ?, ?)",
+		userID, programID, req.StartDate,
+	)
+	if err != nil {
+		fmt.Printf("Enroll in program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	enrollmentID, _ := result.LastInsertId()
+
+	for i, tm := range req.TrainingMaxes {
+		_, err := tx.Exec(
+			"INSERT INTO program_training_maxes (enrollment_id, exercise_id, exercise_source, weight) VALUES (?, ?, ?, ?)",
+			enrollmentID, refs[i].ID, refs[i].Source, units.WeightToCanonical(tm.Weight),
+		)
+		if err != nil {
+			fmt.Printf("Enroll in program error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Enroll in program error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	enrollment, err := fetchProgramEnrollment(enrollmentID, userID)
+	var response ProgramEnrollmentResponse
+	if err == nil {
+		response, err = buildEnrollmentResponse(userID, enrollment)
+	}
+	if err != nil {
+		fmt.Printf("Error fetching created enrollment: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetScheduledToday returns the program days scheduled for a date (default
+// today) across the user's active enrollments
+func GetScheduledToday(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = utils.FormatDate(time.Now())
+	}
+	if _, err := utils.ParseDate(date); err != nil {
+		http.Error(w, `{"error":"Invalid date"}`, http.StatusBadRequest)
+		return
+	}
+
+	enrollments, err := fetchEnrollmentIDs(userID, "active")
+	if err != nil {
+		fmt.Printf("Get scheduled workouts error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	days := []models.ScheduledProgramDay{}
+	for _, enrollmentID := range enrollments {
+		enrollment, err := fetchProgramEnrollment(enrollmentID, userID)
+		var response ProgramEnrollmentResponse
+		if err == nil {
+			response, err = buildEnrollmentResponse(userID, enrollment)
+		}
+		if err != nil {
+			fmt.Printf("Get scheduled workouts error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		for _, day := range response.Schedule {
+			if day.Date == date {
+				days = append(days, day)
+			}
+		}
+	}
+
+	response := ScheduledTodayResponse{Date: date, Days: days}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func fetchEnrollmentIDs(userID int64, status string) ([]int64, error) {
+	query := "SELECT id FROM program_enrollments WHERE user_id = ?"
+	params := []interface{}{userID}
+	if status != "" {
+		query += " AND status = ?"
+		params = append(params, status)
+	}
+	query += " ORDER BY start_date DESC, id DESC"
+
+	rows, err := database.DB.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetAllProgramEnrollments returns the user's enrollments with their completion summary
+func GetAllProgramEnrollments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	enrollmentIDs, err := fetchEnrollmentIDs(userID, r.URL.Query().Get("status"))
+	if err != nil {
+		fmt.Printf("Get enrollments error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var enrollments []ProgramEnrollmentResponse
+	for _, enrollmentID := range enrollmentIDs {
+		enrollment, err := fetchProgramEnrollment(enrollmentID, userID)
+		var response ProgramEnrollmentResponse
+		if err == nil {
+			response, err = buildEnrollmentResponse(userID, enrollment)
+		}
+		if err != nil {
+			fmt.Printf("Get enrollments error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		// The list only carries the summary; fetch a single enrollment for its schedule
+		response.Schedule = nil
+		enrollments = append(enrollments, response)
+	}
+
+	response := ProgramEnrollmentsResponse{Enrollments: enrollments}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetProgramEnrollmentById returns an enrollment with its full schedule and completion
+func GetProgramEnrollmentById(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	enrollmentID, err := strconv.ParseInt(strings.TrimSuffix(r.URL.Path[len("/api/program-enrollments/"):], "/"), 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid enrollment ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	enrollment, err := fetchProgramEnrollment(enrollmentID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Enrollment not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Get enrollment error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response, err := buildEnrollmentResponse(userID, enrollment)
+	if err != nil {
+		fmt.Printf("Get enrollment error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteProgramEnrollment removes an enrollment. Workout logs are not affected.
+func DeleteProgramEnrollment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	enrollmentID, err := strconv.ParseInt(strings.TrimSuffix(r.URL.Path[len("/api/program-enrollments/"):], "/"), 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid enrollment ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Verify enrollment belongs to user
+	var existingID int64
+	err = database.DB.QueryRow(
+		"SELECT id FROM program_enrollments WHERE id = ? AND user_id = ?",
+		enrollmentID, userID,
+	).Scan(&existingID)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Enrollment not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Delete enrollment error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Delete enrollment error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM program_training_maxes WHERE enrollment_id = ?", enrollmentID); err != nil {
+		fmt.Printf("Delete enrollment error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM program_enrollments WHERE id = ? AND user_id = ?", enrollmentID, userID); err != nil {
+		fmt.Printf("Delete enrollment error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Delete enrollment error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Enrollment deleted successfully"})
+}
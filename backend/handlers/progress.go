@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+	"gym-app-backend/utils"
+)
+
+// Progress metrics
+const (
+	ProgressMaxWeight    = "max_weight"
+	ProgressVolume       = "volume"
+	ProgressEstimated1RM = "estimated_1rm"
+	ProgressRelative1RM  = "relative_1rm" // estimated 1RM in multiples of bodyweight
+	ProgressReps         = "reps"
+	ProgressDistance     = "distance"
+	ProgressPace         = "pace"
+	ProgressDuration     = "duration"
+	ProgressHoldTime     = "hold_time"
+	ProgressCustom       = "custom" // a number custom field, chosen by field_id
+)
+
+// Progress bucket sizes
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// ProgressPoint is the value of a metric over one bucket
+type ProgressPoint struct {
+	PeriodStart string  `json:"period_start"`
+	Value       float64 `json:"value"`
+	LogCount    int     `json:"log_count"`
+}
+
+type ProgressSeriesResponse struct {
+	ExerciseID     int64           `json:"exercise_id"`
+	ExerciseSource string          `json:"exercise_source"`
+	Metric         string          `json:"metric"`
+	Bucket         string          `json:"bucket"`
+	Unit           string          `json:"unit"`
+	FieldID        *int64          `json:"field_id,omitempty"` // custom metric only
+	Series         []ProgressPoint `json:"series"`
+}
+
+func isValidProgressMetric(metric string) bool {
+	switch metric {
+	case ProgressMaxWeight, ProgressVolume, ProgressEstimated1RM, ProgressRelative1RM, ProgressReps,
+		ProgressDistance, ProgressPace, ProgressDuration, ProgressHoldTime, ProgressCustom:
+		return true
+	}
+	return false
+}
+
+func isValidProgressBucket(bucket string) bool {
+	return bucket == BucketDay || bucket == BucketWeek || bucket == BucketMonth
+}
+
+// bucketStart returns the first day of the bucket a date falls in
+func bucketStart(date time.Time, bucket string) time.Time {
+	switch bucket {
+	case BucketWeek:
+		return utils.WeekStart(date)
+	case BucketMonth:
+		return utils.MonthStart(date)
+	}
+	return date
+}
+
+// progressUnit returns the label of the unit a metric is reported in
+func progressUnit(metric string, units utils.Units) string {
+	switch metric {
+	case ProgressMaxWeight, ProgressVolume, ProgressEstimated1RM:
+		return units.WeightLabel()
+	case ProgressDistance:
+		return units.DistanceLabel()
+	case ProgressPace:
+		return units.PaceLabel()
+	case ProgressDuration:
+		return "min"
+	case ProgressHoldTime:
+		return "s"
+	case ProgressRelative1RM:
+		return "x bodyweight"
+	}
+	return "reps"
+}
+
+// progressLifts returns the working sets of a strength log as weight/reps pairs,
+// falling back to the log's own values for logs without sets
+func progressLifts(log models.WorkoutLog) (weights []float64, reps []int) {
+	for _, set := range log.WorkoutSets {
+		if set.SetType == models.SetTypeWarmup {
+			continue
+		}
+		var w float64
+		var r int
+		if set.Weight != nil {
+			w = *set.Weight
+		}
+		if set.Reps != nil {
+			r = *set.Reps
+		}
+		weights = append(weights, w)
+		reps = append(reps, r)
+	}
+	if len(log.WorkoutSets) > 0 {
+		return weights, reps
+	}
+
+	count := 1
+	if log.Sets != nil && *log.Sets > 0 {
+		count = *log.Sets
+	}
+	for i := 0; i < count; i++ {
+		var w float64
+		var r int
+		if log.Weight != nil {
+			w = *log.Weight
+		}
+		if log.Reps != nil {
+			r = *log.Reps
+		}
+		weights = append(weights, w)
+		reps = append(reps, r)
+	}
+	return weights, reps
+}
+
+// progressHold returns the longest working set a log was held for, in seconds
+func progressHold(log models.WorkoutLog) (int, bool) {
+	longest := 0
+	for _, set := range log.WorkoutSets {
+		if set.SetType != models.SetTypeWarmup && set.DurationSeconds != nil && *set.DurationSeconds > longest {
+			longest = *set.DurationSeconds
+		}
+	}
+	return longest, longest > 0
+}
+
+// logPace returns the pace of a cardio log, derived from duration and distance
+// when it was not logged
+func logPace(log models.WorkoutLog) (float64, bool) {
+	if log.Pace != nil && *log.Pace > 0 {
+		return *log.Pace, true
+	}
+	if log.Duration != nil && *log.Duration > 0 && log.Distance != nil && *log.Distance > 0 {
+		return float64(*log.Duration) / *log.Distance, true
+	}
+	return 0, false
+}
+
+// progressBucket accumulates the logs of one bucket
+type progressBucket struct {
+	start     time.Time
+	value     float64
+	weightSum float64 // pace only: distance the value is weighted by
+	logCount  int
+}
+
+// buildProgressSeries aggregates canonical logs into one point per bucket.
+// Logs without a value for the metric are skipped; buckets without logs are
+// left out of the series. Volume and estimated maxes of bodyweight and
+// assisted movements count the lifter's bodyweight on the day, as does the
+// heaviest set of assisted movements, where less assistance is progress.
+// Relative 1RM divides the estimated max by that bodyweight, so it skips logs
+// of users who never recorded one.
+func buildProgressSeries(logs []models.WorkoutLog, metric, bucket, exerciseType string, bodyweights services.Bodyweights, units utils.Units) []ProgressPoint {
+	rules := models.RulesForExerciseType(exerciseType)
+
+	var buckets []*progressBucket
+	byStart := make(map[string]*progressBucket)
+
+	for _, log := range logs {
+		date, err := utils.ParseDate(log.Date)
+		if err != nil {
+			continue
+		}
+
+		var value, weight float64
+		found := false
+		switch metric {
+		case ProgressMaxWeight, ProgressEstimated1RM, ProgressRelative1RM, ProgressVolume, ProgressReps:
+			weights, reps := progressLifts(log)
+			bodyweight := bodyweights.On(log.Date)
+			if metric == ProgressRelative1RM && (bodyweight == nil || *bodyweight <= 0) {
+				continue
+			}
+			for i := range weights {
+				load, _ := rules.EffectiveLoad(&weights[i], bodyweight)
+				switch metric {
+				case ProgressMaxWeight:
+					heaviest := weights[i]
+					if rules.Load == models.LoadAssistance {
+						heaviest = load
+					}
+					if heaviest > 0 && heaviest > value {
+						value, found = heaviest, true
+					}
+				case ProgressEstimated1RM:
+					if e1rm := services.EstimatedOneRepMax(load, reps[i]); load > 0 && e1rm > value {
+						value, found = e1rm, true
+					}
+				case ProgressRelative1RM:
+					if relative := services.EstimatedOneRepMax(load, reps[i]) / *bodyweight; load > 0 && relative > value {
+						value, found = relative, true
+					}
+				case ProgressVolume:
+					if load > 0 && reps[i] > 0 {
+						value += load * float64(reps[i])
+						found = true
+					}
+				case ProgressReps:
+					if reps[i] > 0 {
+						value += float64(reps[i])
+						found = true
+					}
+				}
+			}
+		case ProgressDistance:
+			if log.Distance != nil && *log.Distance > 0 {
+				value, found = *log.Distance, true
+			}
+		case ProgressDuration:
+			if log.Duration != nil && *log.Duration > 0 {
+				value, found = float64(*log.Duration), true
+			}
+		case ProgressHoldTime:
+			var seconds int
+			seconds, found = progressHold(log)
+			value = float64(seconds)
+		case ProgressPace:
+			value, found = logPace(log)
+			weight = 1
+			if log.Distance != nil && *log.Distance > 0 {
+				weight = *log.Distance
+			}
+		}
+		if !found {
+			continue
+		}
+
+		start := bucketStart(date, bucket)
+		key := utils.FormatDate(start)
+		b, ok := byStart[key]
+		if !ok {
+			b = &progressBucket{start: start}
+			byStart[key] = b
+			buckets = append(buckets, b)
+		}
+		b.logCount++
+
+		switch metric {
+		case ProgressMaxWeight, ProgressEstimated1RM, ProgressRelative1RM, ProgressHoldTime:
+			if value > b.value {
+				b.value = value
+			}
+		case ProgressPace:
+			// Distance-weighted average, so long runs count for more
+			b.value += value * weight
+			b.weightSum += weight
+		default:
+			b.value += value
+		}
+	}
+
+	series := []ProgressPoint{}
+	for _, b := range buckets {
+		value := b.value
+		if metric == ProgressPace && b.weightSum > 0 {
+			value /= b.weightSum
+		}
+		series = append(series, ProgressPoint{
+			PeriodStart: utils.FormatDate(b.start),
+			Value:       localizeProgressValue(metric, value, units),
+			LogCount:    b.logCount,
+		})
+	}
+	return series
+}
+
+// buildCustomMetricSeries averages the values a number custom field recorded
+// over each bucket. Custom values are not converted between units.
+func buildCustomMetricSeries(logs []models.WorkoutLog, fieldID int64, bucket string) []ProgressPoint {
+	var buckets []*progressBucket
+	byStart := make(map[string]*progressBucket)
+
+	for _, log := range logs {
+		date, err := utils.ParseDate(log.Date)
+		if err != nil {
+			continue
+		}
+
+		var value float64
+		found := false
+		for _, metric := range log.CustomMetrics {
+			if number, ok := metric.Value.(float64); ok && metric.FieldID == fieldID {
+				value, found = number, true
+			}
+		}
+		if !found {
+			continue
+		}
+
+		start := bucketStart(date, bucket)
+		key := utils.FormatDate(start)
+		b, ok := byStart[key]
+		if !ok {
+			b = &progressBucket{start: start}
+			byStart[key] = b
+			buckets = append(buckets, b)
+		}
+		b.logCount++
+		b.value += value
+	}
+
+	series := []ProgressPoint{}
+	for _, b := range buckets {
+		series = append(series, ProgressPoint{
+			PeriodStart: utils.FormatDate(b.start),
+			Value:       b.value / float64(b.logCount),
+			LogCount:    b.logCount,
+		})
+	}
+	return series
+}
+
+// localizeProgressValue converts a canonical metric value to the user's units
+func localizeProgressValue(metric string, value float64, units utils.Units) float64 {
+	switch metric {
+	case ProgressMaxWeight, ProgressVolume, ProgressEstimated1RM:
+		return units.WeightFromCanonical(value)
+	case ProgressDistance:
+		return units.DistanceFromCanonical(value)
+	case ProgressPace:
+		return units.PaceFromCanonical(value)
+	}
+	return value
+}
+
+// validateProgressRange checks the optional start_date and end_date parameters
+func validateProgressRange(startDate, endDate string) error {
+	for _, date := range []string{startDate, endDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(utils.DateLayout, date); err != nil {
+			return fmt.Errorf("dates must use the YYYY-MM-DD format")
+		}
+	}
+	if startDate != "" && endDate != "" && startDate > endDate {
+		return fmt.Errorf("start_date must not be after end_date")
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+	"gym-app-backend/utils"
+)
+
+// progressionHistoryLimit is the number of recent logs suggestions are based on
+const progressionHistoryLimit = 5
+
+// Defaults used for exercises without a configured rule
+const (
+	defaultProgressionMinReps = 8
+	defaultProgressionMaxReps = 12
+)
+
+type ProgressionRuleResponse struct {
+	Rule       models.ProgressionRule `json:"rule"`
+	Configured bool                   `json:"configured"` // false when the default rule applies
+}
+
+type UpdateProgressionRuleRequest struct {
+	Method            string   `json:"method"`
+	MinReps           *int     `json:"min_reps"`
+	MaxReps           *int     `json:"max_reps"`
+	TargetReps        *int     `json:"target_reps"`
+	Increment         *float64 `json:"increment"`
+	HoldOnMissedReps  *bool    `json:"hold_on_missed_reps"`
+	DeloadAfterMisses *int     `json:"deload_after_misses"`
+	DeloadPercent     *float64 `json:"deload_percent"`
+}
+
+// defaultIncrement is the smallest common plate jump in the user's units
+func defaultIncrement(units utils.Units) float64 {
+	if units.Weight == utils.WeightUnitKg {
+		return 2.5
+	}
+	return 5
+}
+
+// defaultProgressionRule is the rule used until the user configures one:
+// double progression over 8-12 reps
+func defaultProgressionRule(userID int64, exercise exerciseRef, units utils.Units) models.ProgressionRule {
+	minReps, maxReps := defaultProgressionMinReps, defaultProgressionMaxReps
+	increment := defaultIncrement(units)
+	return models.ProgressionRule{
+		UserID:           userID,
+		ExerciseID:       exercise.ID,
+		ExerciseSource:   exercise.Source,
+		Method:           models.ProgressionDouble,
+		MinReps:          &minReps,
+		MaxReps:          &maxReps,
+		Increment:        &increment,
+		HoldOnMissedReps: true,
+	}
+}
+
+// fetchProgressionRule returns the user's rule for an exercise in the user's
+// units, or the default rule when none is configured
+func fetchProgressionRule(userID int64, exercise exerciseRef, units utils.Units) (models.ProgressionRule, bool, error) {
+	var rule models.ProgressionRule
+	err := database.DB.QueryRow(
+		`SELECT id, user_id, exercise_id, exercise_source, method, min_reps, max_reps, target_reps,
+		        increment, hold_on_missed_reps, deload_after_misses, deload_percent, created_at, updated_at
+		 FROM progression_rules
+		 WHERE user_id = ? AND exercise_id = ? AND exercise_source = ?`,
+		userID, exercise.ID, exercise.Source,
+	).Scan(
+		&rule.ID, &rule.UserID, &rule.ExerciseID, &rule.ExerciseSource, &rule.Method,
+		&rule.MinReps, &rule.MaxReps, &rule.TargetReps, &rule.Increment, &rule.HoldOnMissedReps,
+		&rule.DeloadAfterMisses, &rule.DeloadPercent, &rule.CreatedAt, &rule.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return defaultProgressionRule(userID, exercise, units), false, nil
+	} else if err != nil {
+		return rule, false, err
+	}
+
+	rule.Increment = weightFromCanonical(units, rule.Increment)
+	if rule.Increment == nil {
+		increment := defaultIncrement(units)
+		rule.Increment = &increment
+	}
+	return rule, true, nil
+}
+
+// suggestNextPrescription suggests the next prescription of an exercise from
+// its recent logs. Returns nil when there is nothing to progress from.
+func suggestNextPrescription(userID int64, exercise exerciseRef, units utils.Units) (*services.ProgressionSuggestion, error) {
+	// Progression adds weight and reps, which only fits loads that go up
+	rules := models.RulesForExerciseType(exercise.Type)
+	if !rules.Reps || (rules.Load != models.LoadAbsolute && rules.Load != models.LoadAdded) {
+		return nil, nil
+	}
+
+	rule, _, err := fetchProgressionRule(userID, exercise, units)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := database.DB.Query(
+		workoutLogSelect+" WHERE wl.user_id = ? AND wl.exercise_id = ? AND wl.exercise_source = ? AND wl.planned = 0 ORDER BY wl.date DESC, wl.created_at DESC LIMIT ?",
+		userID, exercise.ID, exercise.Source, progressionHistoryLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.WorkoutLog
+	for rows.Next() {
+		log, err := scanWorkoutLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := attachWorkoutSets(history); err != nil {
+		return nil, err
+	}
+	localizeWorkoutLogs(history, units)
+
+	return services.SuggestProgression(rule, history, units.WeightLabel()), nil
+}
+
+// parseProgressionPath extracts the exercise ID from /api/exercises/:id/progression
+func parseProgressionPath(r *http.Request) (int64, error) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/exercises/"), "/progression")
+	return strconv.ParseInt(path, 10, 64)
+}
+
+// resolveProgressionExercise resolves the exercise of a progression request and
+// writes the error response if it cannot be found
+func resolveProgressionExercise(w http.ResponseWriter, r *http.Request, userID int64) (exerciseRef, bool) {
+	exerciseID, err := parseProgressionPath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid exercise ID"}`, http.StatusBadRequest)
+		return exerciseRef{}, false
+	}
+	return resolveRequestExercise(w, r, userID, exerciseID)
+}
+
+// GetProgressionRule returns the progression rule of an exercise, falling back
+// to the default rule when none is configured
+func GetProgressionRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	exercise, ok := resolveProgressionExercise(w, r, userID)
+	if !ok {
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get progression rule error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rule, configured, err := fetchProgressionRule(userID, exercise, units)
+	if err != nil {
+		fmt.Printf("Get progression rule error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := ProgressionRuleResponse{Rule: rule, Configured: configured}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateProgressionRule checks that a rule has what its method needs
+func validateProgressionRule(req UpdateProgressionRuleRequest) error {
+	if !models.IsValidProgressionMethod(req.Method) {
+		return fmt.Errorf("method must be %s or %s", models.ProgressionDouble, models.ProgressionFixed)
+	}
+	if req.Method == models.ProgressionDouble {
+		if req.MinReps == nil || req.MaxReps == nil {
+			return fmt.Errorf("min_reps and max_reps are required for double progression")
+		}
+		if *req.MinReps < 1 || *req.MaxReps < *req.MinReps {
+			return fmt.Errorf("min_reps must be at least 1 and no more than max_reps")
+		}
+		// A miss below the rep range always holds the load
+		if req.HoldOnMissedReps != nil && !*req.HoldOnMissedReps {
+			return fmt.Errorf("hold_on_missed_reps cannot be false for double progression")
+		}
+	}
+	if req.TargetReps != nil && *req.TargetReps < 1 {
+		return fmt.Errorf("target_reps must be at least 1")
+	}
+	if req.Increment != nil && *req.Increment <= 0 {
+		return fmt.Errorf("increment must be greater than 0")
+	}
+	if req.DeloadAfterMisses != nil && *req.DeloadAfterMisses < 0 {
+		return fmt.Errorf("deload_after_misses must not be negative")
+	}
+	if req.DeloadPercent != nil && (*req.DeloadPercent <= 0 || *req.DeloadPercent >= 100) {
+		return fmt.Errorf("deload_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// UpdateProgressionRule creates or replaces the progression rule of an exercise
+func UpdateProgressionRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	exercise, ok := resolveProgressionExercise(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req UpdateProgressionRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if err := validateProgressionRule(req); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	holdOnMissedReps := true
+	if req.HoldOnMissedReps != nil {
+		holdOnMissedReps = *req.HoldOnMissedReps
+	}
+
+	// Increments arrive in the user's units and are stored canonically
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Update progression rule error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	increment := weightToCanonical(units, req.Increment)
+
+	_, err = database.DB.Exec(
+		`INSERT INTO progression_rules (user_id, exercise_id, exercise_source, method, min_reps, max_reps, target_reps,
+		                                increment, hold_on_missed_reps, deload_after_misses, deload_percent)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (user_id, exercise_id, exercise_source) DO UPDATE SET
+		   method = excluded.method, min_reps = excluded.min_reps, max_reps = excluded.max_reps,
+		   target_reps = excluded.target_reps, increment = excluded.increment,
+		   hold_on_missed_reps = excluded.hold_on_missed_reps, deload_after_misses = excluded.deload_after_misses,
+		   deload_percent = excluded.deload_percent, updated_at = CURRENT_TIMESTAMP`,
+		userID, exercise.ID, exercise.Source, req.Method, req.MinReps, req.MaxReps, req.TargetReps,
+		increment, holdOnMissedReps, req.DeloadAfterMisses, req.DeloadPercent,
+	)
+	if err != nil {
+		fmt.Printf("Update progression rule error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rule, configured, err := fetchProgressionRule(userID, exercise, units)
+	if err != nil {
+		fmt.Printf("Error fetching updated progression rule: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := ProgressionRuleResponse{Rule: rule, Configured: configured}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteProgressionRule removes the rule of an exercise so the default applies again
+func DeleteProgressionRule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	exercise, ok := resolveProgressionExercise(w, r, userID)
+	if !ok {
+		return
+	}
+
+	_, err := database.DB.Exec(
+		"DELETE FROM progression_rules WHERE user_id = ? AND exercise_id = ? AND exercise_source = ?",
+		userID, exercise.ID, exercise.Source,
+	)
+	if err != nil {
+		fmt.Printf("Delete progression rule error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Progression rule deleted successfully"})
+}
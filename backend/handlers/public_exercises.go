@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"unicode"
 
 	"gym-app-backend/database"
 	"gym-app-backend/models"
@@ -16,19 +19,194 @@ type PublicExerciseResponse struct {
 }
 
 type PublicExercisesResponse struct {
-	Exercises []models.PublicExercise `json:"exercises"`
+	Exercises  []models.PublicExercise `json:"exercises"`
+	NextCursor *string                 `json:"next_cursor,omitempty"` // pass as cursor to fetch the next page
 }
 
-// GetAllPublicExercises returns all public exercises
+// maxPublicExercisePage is the largest page of public exercises served at once
+const maxPublicExercisePage = 200
+
+// publicExerciseSort is a sort order of the public exercise library. Rows with
+// the same key are ordered by ID so pages never overlap.
+type publicExerciseSort struct {
+	column string
+	desc   bool
+}
+
+var publicExerciseSorts = map[string]publicExerciseSort{
+	"name":        {column: "pe.name COLLATE NOCASE"},
+	"-name":       {column: "pe.name COLLATE NOCASE", desc: true},
+	"created_at":  {column: "pe.created_at"},
+	"-created_at": {column: "pe.created_at", desc: true},
+}
+
+// publicExerciseCursor marks the last row of a page: its sort key and ID
+type publicExerciseCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+const publicExerciseSelect = `
+	SELECT pe.id, pe.slug, pe.name, pe.exercise_type, pe.muscle_group, pe.equipment, pe.description,
+	       pe.instructions, pe.video_link, pe.image_link, pe.deprecated_at, pe.replaced_by_id, pe.created_at
+	FROM public_exercises pe
+`
+
+func scanPublicExercise(row rowScanner) (models.PublicExercise, error) {
+	var ex models.PublicExercise
+	err := row.Scan(
+		&ex.ID, &ex.Slug, &ex.Name, &ex.ExerciseType, &ex.MuscleGroup,
+		&ex.Equipment, &ex.Description, &ex.Instructions, &ex.VideoLink,
+		&ex.ImageLink, &ex.DeprecatedAt, &ex.ReplacedByID, &ex.CreatedAt,
+	)
+	return ex, err
+}
+
+// fetchPublicExercise loads a public exercise along with its taxonomy
+func fetchPublicExercise(exerciseID int64) (models.PublicExercise, error) {
+	ex, err := scanPublicExercise(database.DB.QueryRow(publicExerciseSelect+" WHERE pe.id = ?", exerciseID))
+	if err != nil {
+		return ex, err
+	}
+	exercises := []models.PublicExercise{ex}
+	err = attachPublicExerciseTaxonomy(exercises)
+	return exercises[0], err
+}
+
+func encodePublicExerciseCursor(cursor publicExerciseCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePublicExerciseCursor(s string) (publicExerciseCursor, error) {
+	var cursor publicExerciseCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	return cursor, err
+}
+
+// searchTerms splits free text into lowercase words. Everything but letters
+// and digits is dropped, so the words are safe to use in an FTS query.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// publicExerciseMatch builds the full-text query of a library search. Every
+// word of search must start a word of the name or description, and every word
+// of muscleGroup and equipment must appear in that column. Returns "" when
+// there is nothing to match.
+func publicExerciseMatch(search, muscleGroup, equipment string) string {
+	var clauses []string
+	for _, term := range searchTerms(search) {
+		clauses = append(clauses, fmt.Sprintf("(name:%s* OR description:%s*)", term, term))
+	}
+	for _, term := range searchTerms(muscleGroup) {
+		clauses = append(clauses, "muscle_group:"+term)
+	}
+	for _, term := range searchTerms(equipment) {
+		clauses = append(clauses, "equipment:"+term)
+	}
+	return strings.Join(clauses, " AND ")
+}
+
+// GetAllPublicExercises returns the public exercise library. q searches names
+// and descriptions by word prefix; exercise_type, muscle_group and equipment
+// filter the results, as do the taxonomy slugs muscle (with muscle_role),
+// equipment_item and movement_pattern. Deprecated exercises are left out
+// unless include_deprecated=true. sort is name (the default), -name,
+// created_at or -created_at. Pass limit to page through the results, then the returned
+// next_cursor as cursor for each following page; without a limit every
+// matching exercise is returned.
 func GetAllPublicExercises(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
 		return
 	}
 
-	rows, err := database.DB.Query(
-		"SELECT * FROM public_exercises ORDER BY name ASC",
-	)
+	query := r.URL.Query()
+
+	sortName := query.Get("sort")
+	if sortName == "" {
+		sortName = "name"
+	}
+	order, ok := publicExerciseSorts[sortName]
+	if !ok {
+		http.Error(w, `{"error":"sort must be name, -name, created_at or -created_at"}`, http.StatusBadRequest)
+		return
+	}
+
+	exerciseType := query.Get("exercise_type")
+	if exerciseType != "" && !models.IsValidExerciseType(exerciseType) {
+		http.Error(w, `{"error":"Invalid exercise type"}`, http.StatusBadRequest)
+		return
+	}
+
+	limit := 0
+	if limitStr := query.Get("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 || n > maxPublicExercisePage {
+			http.Error(w, fmt.Sprintf(`{"error":"limit must be between 1 and %d"}`, maxPublicExercisePage), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	var cursor *publicExerciseCursor
+	if cursorStr := query.Get("cursor"); cursorStr != "" {
+		c, err := decodePublicExerciseCursor(cursorStr)
+		if err != nil || c.Sort != sortName {
+			http.Error(w, `{"error":"Invalid cursor"}`, http.StatusBadRequest)
+			return
+		}
+		cursor = &c
+		if limit == 0 {
+			limit = maxPublicExercisePage
+		}
+	}
+
+	conditions, params, err := taxonomyFilters(query, "pe.id", "public")
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if match := publicExerciseMatch(query.Get("q"), query.Get("muscle_group"), query.Get("equipment")); match != "" {
+		conditions = append(conditions, "pe.id IN (SELECT docid FROM public_exercises_fts WHERE public_exercises_fts MATCH ?)")
+		params = append(params, match)
+	}
+	if exerciseType != "" {
+		conditions = append(conditions, "pe.exercise_type = ?")
+		params = append(params, exerciseType)
+	}
+	if query.Get("include_deprecated") != "true" {
+		conditions = append(conditions, "pe.deprecated_at IS NULL")
+	}
+
+	direction, comparison := "ASC", ">"
+	if order.desc {
+		direction, comparison = "DESC", "<"
+	}
+	if cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND pe.id %[2]s ?))", order.column, comparison))
+		params = append(params, cursor.Value, cursor.Value, cursor.ID)
+	}
+
+	sqlQuery := publicExerciseSelect
+	if len(conditions) > 0 {
+		sqlQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	sqlQuery += fmt.Sprintf(" ORDER BY %s %s, pe.id %s", order.column, direction, direction)
+	if limit > 0 {
+		// One extra row tells whether another page follows
+		sqlQuery += " LIMIT ?"
+		params = append(params, limit+1)
+	}
+
+	rows, err := database.DB.Query(sqlQuery, params...)
 	if err != nil {
 		fmt.Printf("Get public exercises error: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
@@ -36,15 +214,9 @@ func GetAllPublicExercises(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	var exercises []models.PublicExercise
+	exercises := []models.PublicExercise{}
 	for rows.Next() {
-		var ex models.PublicExercise
-		var createdAtStr string
-		err := rows.Scan(
-			&ex.ID, &ex.Name, &ex.ExerciseType, &ex.MuscleGroup,
-			&ex.Equipment, &ex.Description, &ex.Instructions, &ex.VideoLink,
-			&ex.ImageLink, &createdAtStr,
-		)
+		ex, err := scanPublicExercise(rows)
 		if err != nil {
 			fmt.Printf("Error scanning public exercise: %v\n", err)
 			continue
@@ -53,6 +225,22 @@ func GetAllPublicExercises(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := PublicExercisesResponse{Exercises: exercises}
+	if limit > 0 && len(exercises) > limit {
+		response.Exercises = exercises[:limit]
+		last := exercises[limit-1]
+		next := publicExerciseCursor{Sort: sortName, Value: last.Name, ID: last.ID}
+		if order.column == "pe.created_at" {
+			next.Value = last.CreatedAt.UTC().Format("2006-01-02 15:04:05")
+		}
+		encoded := encodePublicExerciseCursor(next)
+		response.NextCursor = &encoded
+	}
+
+	if err := attachPublicExerciseTaxonomy(response.Exercises); err != nil {
+		fmt.Printf("Get public exercises error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -70,16 +258,7 @@ func GetPublicExerciseById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var ex models.PublicExercise
-	var createdAtStr string
-	err = database.DB.QueryRow(
-		"SELECT * FROM public_exercises WHERE id = ?",
-		exerciseID,
-	).Scan(
-		&ex.ID, &ex.Name, &ex.ExerciseType, &ex.MuscleGroup,
-		&ex.Equipment, &ex.Description, &ex.Instructions, &ex.VideoLink,
-		&ex.ImageLink, &createdAtStr,
-	)
+	ex, err := fetchPublicExercise(exerciseID)
 
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Public exercise not found"}`, http.StatusNotFound)
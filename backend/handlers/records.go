@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+type ExerciseRecords struct {
+	ExerciseID     int64                   `json:"exercise_id"`
+	ExerciseSource string                  `json:"exercise_source"`
+	ExerciseName   *string                 `json:"exercise_name"`
+	Current        []models.PersonalRecord `json:"current"`
+	History        []models.PersonalRecord `json:"history,omitempty"`
+}
+
+type PersonalRecordsResponse struct {
+	Records []ExerciseRecords `json:"records"`
+}
+
+// GetPersonalRecords returns the current personal records of each exercise.
+// Pass exercise_id (and source when a private and a public exercise share the
+// ID) to limit the result to one exercise and history=true to include every
+// record ever set, oldest first.
+func GetPersonalRecords(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	query := `
+		SELECT pr.id, pr.user_id, pr.exercise_id, pr.exercise_source, COALESCE(e.name, pe.name), pr.record_type,
+		       pr.value, pr.weight, pr.reps, pr.previous_value, pr.workout_log_id, substr(pr.achieved_on, 1, 10)
+		FROM personal_records pr
+		LEFT JOIN exercises e ON pr.exercise_source = 'private' AND pr.exercise_id = e.id AND pr.user_id = e.user_id
+		LEFT JOIN public_exercises pe ON pr.exercise_source = 'public' AND pr.exercise_id = pe.id
+		WHERE pr.user_id = ?`
+	params := []interface{}{userID}
+
+	if exerciseIDStr := r.URL.Query().Get("exercise_id"); exerciseIDStr != "" {
+		exerciseID, err := strconv.ParseInt(exerciseIDStr, 10, 64)
+		if err != nil {
+			http.Error(w, `{"error":"Invalid exercise ID"}`, http.StatusBadRequest)
+			return
+		}
+		source := r.URL.Query().Get("source")
+		if source != "" && source != "private" && source != "public" {
+			http.Error(w, `{"error":"source must be private or public"}`, http.StatusBadRequest)
+			return
+		}
+		exercise, err := resolveExercise(userID, exerciseID, source)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Printf("Get personal records error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		query += " AND pr.exercise_id = ? AND pr.exercise_source = ?"
+		params = append(params, exercise.ID, exercise.Source)
+	}
+	includeHistory := r.URL.Query().Get("history") == "true"
+
+	query += " ORDER BY pr.exercise_source, pr.exercise_id, pr.id"
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get personal records error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	rows, err := database.DB.Query(query, params...)
+	if err != nil {
+		fmt.Printf("Get personal records error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	records := []ExerciseRecords{}
+	var current map[string]int // record key -> index in Current
+	for rows.Next() {
+		var pr models.PersonalRecord
+		err := rows.Scan(
+			&pr.ID, &pr.UserID, &pr.ExerciseID, &pr.ExerciseSource, &pr.ExerciseName, &pr.RecordType,
+			&pr.Value, &pr.Weight, &pr.Reps, &pr.PreviousValue, &pr.WorkoutLogID, &pr.AchievedOn,
+		)
+		if err != nil {
+			fmt.Printf("Error scanning personal record: %v\n", err)
+			continue
+		}
+		localizePersonalRecord(&pr, units)
+
+		last := len(records) - 1
+		if last < 0 || records[last].ExerciseID != pr.ExerciseID || records[last].ExerciseSource != pr.ExerciseSource {
+			records = append(records, ExerciseRecords{
+				ExerciseID:     pr.ExerciseID,
+				ExerciseSource: pr.ExerciseSource,
+				ExerciseName:   pr.ExerciseName,
+				Current:        []models.PersonalRecord{},
+			})
+			current = make(map[string]int)
+		}
+		group := &records[len(records)-1]
+
+		// Rows arrive oldest first, so a later record replaces its predecessor
+		key := personalRecordKey(pr)
+		if i, ok := current[key]; ok {
+			group.Current[i] = pr
+		} else {
+			current[key] = len(group.Current)
+			group.Current = append(group.Current, pr)
+		}
+		if includeHistory {
+			group.History = append(group.History, pr)
+		}
+	}
+
+	response := PersonalRecordsResponse{Records: records}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// personalRecordKey identifies the history a record belongs to. Rep records
+// are kept per weight, every other type has a single history per exercise.
+func personalRecordKey(pr models.PersonalRecord) string {
+	if pr.RecordType == models.RecordMaxReps && pr.Weight != nil {
+		return fmt.Sprintf("%s:%.3f", pr.RecordType, *pr.Weight)
+	}
+	return pr.RecordType
+}
+
+// recordsSetByLog returns the records of a rebuilt history that were set by
+// the given log, converted to the user's units
+func recordsSetByLog(records []models.PersonalRecord, logID int64, units utils.Units) []models.PersonalRecord {
+	var set []models.PersonalRecord
+	for _, pr := range records {
+		if pr.WorkoutLogID == logID {
+			localizePersonalRecord(&pr, units)
+			set = append(set, pr)
+		}
+	}
+	return set
+}
+
+// localizePersonalRecord converts a stored record to the user's units in place
+func localizePersonalRecord(pr *models.PersonalRecord, units utils.Units) {
+	switch pr.RecordType {
+	case models.RecordMaxWeight, models.RecordEstimated1RM:
+		pr.Value = units.WeightFromCanonical(pr.Value)
+		pr.PreviousValue = weightFromCanonical(units, pr.PreviousValue)
+	case models.RecordMaxDistance:
+		pr.Value = units.DistanceFromCanonical(pr.Value)
+		pr.PreviousValue = distanceFromCanonical(units, pr.PreviousValue)
+	case models.RecordBestPace:
+		pr.Value = units.PaceFromCanonical(pr.Value)
+		pr.PreviousValue = paceFromCanonical(units, pr.PreviousValue)
+	}
+	pr.Weight = weightFromCanonical(units, pr.Weight)
+}
+
+// sessionExercises returns the distinct exercises logged in a session
+func sessionExercises(tx *sql.Tx, sessionID, userID int64) ([]exerciseRef, error) {
+	rows, err := tx.Query(
+		"SELECT DISTINCT exercise_id, exercise_source FROM workout_logs WHERE session_id = ? AND user_id = ?",
+		sessionID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exercises []exerciseRef
+	for rows.Next() {
+		var ex exerciseRef
+		if err := rows.Scan(&ex.ID, &ex.Source); err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, ex)
+	}
+	return exercises, rows.Err()
+}
@@ -5,12 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"gym-app-backend/database"
 	"gym-app-backend/middleware"
 	"gym-app-backend/models"
 	"gym-app-backend/services"
+	"gym-app-backend/utils"
 )
 
 // SendWeeklyReport sends a weekly workout report via email
@@ -62,15 +64,18 @@ func SendWeeklyReport(w http.ResponseWriter, r *http.Request) {
 	startDate := weekStart.Format("2006-01-02")
 	endDate := weekEnd.Format("2006-01-02")
 
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy == "" {
+		groupBy = "date"
+	}
+	if groupBy != "date" && groupBy != "session" {
+		http.Error(w, `{"error":"group_by must be date or session"}`, http.StatusBadRequest)
+		return
+	}
+
 	// Get workout logs for the week
 	rows, err := database.DB.Query(
-		`SELECT wl.*, 
-		       COALESCE(e.name, pe.name) as exercise_name,
-		       COALESCE(e.exercise_type, pe.exercise_type) as exercise_type
-		 FROM workout_logs wl
-		 LEFT JOIN exercises e ON wl.exercise_id = e.id AND wl.user_id = e.user_id
-		 LEFT JOIN public_exercises pe ON wl.exercise_id = pe.id
-		 WHERE wl.user_id = ? AND wl.date >= ? AND wl.date <= ?
+		workoutLogSelect+` WHERE wl.user_id = ? AND wl.date >= ? AND wl.date <= ?
 		 ORDER BY wl.date ASC, wl.created_at ASC`,
 		userID, startDate, endDate,
 	)
@@ -83,38 +88,53 @@ func SendWeeklyReport(w http.ResponseWriter, r *http.Request) {
 
 	var logs []models.WorkoutLog
 	for rows.Next() {
-		var log models.WorkoutLog
-		var weightPerSetStr, lapTimesStr sql.NullString
-		var createdAtStr string
-		err := rows.Scan(
-			&log.ID, &log.UserID, &log.ExerciseID, &log.Date, &log.Sets, &log.Reps,
-			&log.Weight, &weightPerSetStr, &log.RestTime, &log.Distance, &log.Duration,
-			&log.Pace, &lapTimesStr, &log.Notes, &createdAtStr, &log.ExerciseName, &log.ExerciseType,
-		)
+		log, err := scanWorkoutLog(rows)
 		if err != nil {
 			fmt.Printf("Error scanning log: %v\n", err)
 			continue
 		}
+		logs = append(logs, log)
+	}
 
-		// Parse JSON fields
-		if weightPerSetStr.Valid && weightPerSetStr.String != "" {
-			var parsed interface{}
-			if err := json.Unmarshal([]byte(weightPerSetStr.String), &parsed); err == nil {
-				log.WeightPerSet = parsed
-			}
-		}
-		if lapTimesStr.Valid && lapTimesStr.String != "" {
-			var parsed interface{}
-			if err := json.Unmarshal([]byte(lapTimesStr.String), &parsed); err == nil {
-				log.LapTimes = parsed
-			}
-		}
+	if err := attachLogDetails(logs); err != nil {
+		fmt.Printf("Get workout logs error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
 
-		logs = append(logs, log)
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get workout logs error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeWorkoutLogs(logs, units)
+
+	groups, err := groupWorkoutLogs(userID, logs, groupBy)
+	if err != nil {
+		fmt.Printf("Group workout logs error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Supersets and circuits are rendered as one unit
+	exerciseGroups, err := fetchLogExerciseGroups(logs)
+	if err != nil {
+		fmt.Printf("Get exercise groups error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	bodyMetrics, err := fetchBodyMetrics(userID, startDate, endDate)
+	if err != nil {
+		fmt.Printf("Get body metrics error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
+	localizeBodyMetrics(bodyMetrics, units)
 
 	// Generate HTML report
-	reportHTML := generateWeeklyReportHTML(logs, weekStart, weekEnd)
+	reportHTML := generateWeeklyReportHTML(groups, exerciseGroups, len(logs), bodyMetrics, units, weekStart, weekEnd)
 
 	// Send email
 	if services.EmailService != nil {
@@ -132,7 +152,7 @@ func SendWeeklyReport(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Weekly report sent successfully"})
 }
 
-func generateWeeklyReportHTML(logs []models.WorkoutLog, weekStart, weekEnd time.Time) string {
+func generateWeeklyReportHTML(groups []WorkoutLogGroup, exerciseGroups map[int64]models.ExerciseGroup, totalLogs int, bodyMetrics []models.BodyMetric, units utils.Units, weekStart, weekEnd time.Time) string {
 	html := fmt.Sprintf(`<!DOCTYPE html>
 <html>
 <head>
@@ -144,6 +164,8 @@ func generateWeeklyReportHTML(logs []models.WorkoutLog, weekStart, weekEnd time.
 		.header { background: #4CAF50; color: white; padding: 20px; border-radius: 5px; margin-bottom: 20px; }
 		.workout { background: #f9f9f9; padding: 15px; margin-bottom: 10px; border-radius: 5px; border-left: 4px solid #4CAF50; }
 		.exercise-name { font-weight: bold; font-size: 1.1em; margin-bottom: 5px; }
+		.exercise-group { border: 2px dashed #4CAF50; padding: 10px; margin-bottom: 10px; border-radius: 5px; }
+		.group-name { font-weight: bold; color: #4CAF50; margin-bottom: 8px; }
 		.stats { color: #666; font-size: 0.9em; }
 		.footer { margin-top: 20px; padding-top: 20px; border-top: 1px solid #ddd; color: #666; font-size: 0.9em; }
 	</style>
@@ -156,71 +178,25 @@ func generateWeeklyReportHTML(logs []models.WorkoutLog, weekStart, weekEnd time.
 		</div>
 	`, weekStart.Format("January 2, 2006"), weekEnd.Format("January 2, 2006"))
 
-	if len(logs) == 0 {
+	if totalLogs == 0 {
 		html += `<p>No workouts logged this week. Keep pushing!</p>`
 	} else {
-		html += fmt.Sprintf(`<p><strong>Total Workouts:</strong> %d</p>`, len(logs))
-		
-		// Group by date
-		logsByDate := make(map[string][]models.WorkoutLog)
-		for _, log := range logs {
-			date := log.Date
-			logsByDate[date] = append(logsByDate[date], log)
-		}
+		html += fmt.Sprintf(`<p><strong>Total Workouts:</strong> %d</p>`, totalLogs)
 
-		for date, dayLogs := range logsByDate {
-			html += fmt.Sprintf(`<h2>%s</h2>`, formatDateForReport(date))
-			for _, log := range dayLogs {
-				html += `<div class="workout">`
-				html += fmt.Sprintf(`<div class="exercise-name">%s</div>`, *log.ExerciseName)
-				
-				if log.ExerciseType != nil && *log.ExerciseType == "strength" {
-					if log.WeightPerSet != nil {
-						if sets, ok := log.WeightPerSet.([]interface{}); ok {
-							for i, set := range sets {
-								if setMap, ok := set.(map[string]interface{}); ok {
-									html += fmt.Sprintf(`<div class="stats">Set %d: `, i+1)
-									if reps, ok := setMap["reps"].(float64); ok {
-										html += fmt.Sprintf(`%.0f reps`, reps)
-									}
-									if weight, ok := setMap["weight"].(float64); ok {
-										html += fmt.Sprintf(` @ %.1flbs`, weight)
-									}
-									html += `</div>`
-								}
-							}
-						}
-					} else {
-						if log.Sets != nil && log.Reps != nil {
-							html += fmt.Sprintf(`<div class="stats">Sets: %d, Reps: %d</div>`, *log.Sets, *log.Reps)
-						}
-						if log.Weight != nil {
-							html += fmt.Sprintf(`<div class="stats">Weight: %.1flbs</div>`, *log.Weight)
-						}
-					}
-				} else {
-					if log.Distance != nil {
-						html += fmt.Sprintf(`<div class="stats">Distance: %.2f miles</div>`, *log.Distance)
-					}
-					if log.Duration != nil {
-						hours := *log.Duration / 60
-						minutes := *log.Duration % 60
-						html += fmt.Sprintf(`<div class="stats">Duration: %dh %dm</div>`, hours, minutes)
-					}
-					if log.Pace != nil {
-						html += fmt.Sprintf(`<div class="stats">Pace: %.1f min/mile</div>`, *log.Pace)
-					}
-				}
-				
-				if log.Notes != nil && *log.Notes != "" {
-					html += fmt.Sprintf(`<div class="stats" style="margin-top: 5px; font-style: italic;">Notes: %s</div>`, *log.Notes)
+		for _, group := range groups {
+			html += generateGroupHeadingHTML(group)
+			for _, block := range blocksOf(group.Logs, exerciseGroups) {
+				if block.Group == nil {
+					html += generateLogHTML(block.Logs[0], units)
+					continue
 				}
-				
-				html += `</div>`
+				html += generateExerciseGroupHTML(*block.Group, block.Logs, units)
 			}
 		}
 	}
 
+	html += generateBodyMetricsHTML(bodyMetrics, units)
+
 	html += `
 		<div class="footer">
 			<p>Keep up the great work! 💪</p>
@@ -233,6 +209,203 @@ func generateWeeklyReportHTML(logs []models.WorkoutLog, weekStart, weekEnd time.
 	return html
 }
 
+// generateLogHTML renders one workout log
+func generateLogHTML(log models.WorkoutLog, units utils.Units) string {
+	html := `<div class="workout">`
+	html += fmt.Sprintf(`<div class="exercise-name">%s</div>`, *log.ExerciseName)
+	
+	exerciseType := ""
+	if log.ExerciseType != nil {
+		exerciseType = *log.ExerciseType
+	}
+	rules := models.RulesForExerciseType(exerciseType)
+
+	if rules.Sets() {
+		if len(log.WorkoutSets) > 0 {
+			for _, set := range log.WorkoutSets {
+				html += fmt.Sprintf(`<div class="stats">Set %d: `, set.SetIndex)
+				if set.Reps != nil {
+					html += fmt.Sprintf(`%d reps`, *set.Reps)
+				}
+				if set.DurationSeconds != nil {
+					if set.Reps != nil {
+						html += `, `
+					}
+					html += fmt.Sprintf(`held %s`, models.FormatLapTime(float64(*set.DurationSeconds)))
+				}
+				if set.Weight != nil {
+					html += formatReportLoad(*set.Weight, rules, units)
+				}
+				if set.SetType != models.SetTypeWorking {
+					html += fmt.Sprintf(` (%s)`, set.SetType)
+				}
+				if set.RPE != nil {
+					html += fmt.Sprintf(` RPE %g`, *set.RPE)
+				}
+				html += `</div>`
+			}
+		} else {
+			if log.Sets != nil && log.Reps != nil {
+				html += fmt.Sprintf(`<div class="stats">Sets: %d, Reps: %d</div>`, *log.Sets, *log.Reps)
+			}
+			if log.Weight != nil {
+				label := "Weight"
+				switch rules.Load {
+				case models.LoadAdded:
+					label = "Added weight"
+				case models.LoadAssistance:
+					label = "Assistance"
+				}
+				html += fmt.Sprintf(`<div class="stats">%s: %.1f%s</div>`, label, *log.Weight, units.WeightLabel())
+			}
+		}
+	}
+	if rules.Distance || rules.Duration {
+		if log.Distance != nil {
+			html += fmt.Sprintf(`<div class="stats">Distance: %.2f %s</div>`, *log.Distance, units.DistanceLabel())
+		}
+		if log.Duration != nil {
+			hours := *log.Duration / 60
+			minutes := *log.Duration % 60
+			html += fmt.Sprintf(`<div class="stats">Duration: %dh %dm</div>`, hours, minutes)
+		}
+		if log.Pace != nil {
+			html += fmt.Sprintf(`<div class="stats">Pace: %.1f %s</div>`, *log.Pace, units.PaceLabel())
+		}
+		for _, lap := range log.Laps {
+			html += fmt.Sprintf(`<div class="stats">Lap %d`, lap.LapIndex)
+			if lap.Label != nil && *lap.Label != "" {
+				html += fmt.Sprintf(` (%s)`, *lap.Label)
+			}
+			html += `:`
+			if lap.Distance != nil {
+				html += fmt.Sprintf(` %.2f %s`, *lap.Distance, units.DistanceLabel())
+			}
+			if lap.DurationSeconds != nil {
+				html += fmt.Sprintf(` in %s`, models.FormatLapTime(*lap.DurationSeconds))
+			}
+			if lap.Pace != nil {
+				html += fmt.Sprintf(`, %.1f %s`, *lap.Pace, units.PaceLabel())
+			}
+			if lap.HeartRate != nil {
+				html += fmt.Sprintf(`, %d bpm`, *lap.HeartRate)
+			}
+			html += `</div>`
+		}
+	}
+	
+	if log.Notes != nil && *log.Notes != "" {
+		html += fmt.Sprintf(`<div class="stats" style="margin-top: 5px; font-style: italic;">Notes: %s</div>`, *log.Notes)
+	}
+	
+	html += `</div>`
+	return html
+}
+
+// generateExerciseGroupHTML renders the logs of a superset, circuit or giant
+// set as one unit under a heading describing the group
+func generateExerciseGroupHTML(group models.ExerciseGroup, logs []models.WorkoutLog, units utils.Units) string {
+	title := strings.ToUpper(group.GroupType[:1]) + strings.ReplaceAll(group.GroupType[1:], "_", " ")
+	if group.Rounds != nil {
+		title += fmt.Sprintf(` &middot; %d rounds`, *group.Rounds)
+	}
+	if group.RestBetweenRounds != nil {
+		title += fmt.Sprintf(` &middot; %s rest between rounds`, models.FormatLapTime(float64(*group.RestBetweenRounds)))
+	}
+
+	html := `<div class="exercise-group">`
+	html += fmt.Sprintf(`<div class="group-name">%s</div>`, title)
+	if group.Notes != nil && *group.Notes != "" {
+		html += fmt.Sprintf(`<div class="stats" style="font-style: italic;">%s</div>`, *group.Notes)
+	}
+	for _, log := range logs {
+		html += generateLogHTML(log, units)
+	}
+	html += `</div>`
+	return html
+}
+
+// formatReportLoad describes the weight of a set the way its exercise type
+// reads it: the load lifted, load added to bodyweight, or assistance
+func formatReportLoad(weight float64, rules models.ExerciseTypeRules, units utils.Units) string {
+	switch rules.Load {
+	case models.LoadAdded:
+		return fmt.Sprintf(` +%.1f%s`, weight, units.WeightLabel())
+	case models.LoadAssistance:
+		return fmt.Sprintf(` with %.1f%s assistance`, weight, units.WeightLabel())
+	}
+	return fmt.Sprintf(` @ %.1f%s`, weight, units.WeightLabel())
+}
+
+// generateBodyMetricsHTML renders the latest body metrics of the week and how
+// much each one changed over the week
+func generateBodyMetricsHTML(metrics []models.BodyMetric, units utils.Units) string {
+	if len(metrics) == 0 {
+		return ""
+	}
+
+	type bodyValue struct {
+		label string
+		unit  string
+		value func(m *models.BodyMetric) *float64
+	}
+	values := []bodyValue{
+		{"Bodyweight", " " + units.WeightLabel(), func(m *models.BodyMetric) *float64 { return m.Bodyweight }},
+		{"Body fat", "%", func(m *models.BodyMetric) *float64 { return m.BodyFatPercent }},
+	}
+	for i, column := range bodyMeasurementColumns {
+		i := i
+		values = append(values, bodyValue{
+			strings.ToUpper(column[:1]) + column[1:], " " + units.LengthLabel(),
+			func(m *models.BodyMetric) *float64 { return *bodyMeasurementFields(&m.Measurements)[i] },
+		})
+	}
+
+	html := `<h2>Body Metrics</h2><div class="workout">`
+	for _, v := range values {
+		var first, last *float64
+		for i := range metrics {
+			if value := v.value(&metrics[i]); value != nil {
+				if first == nil {
+					first = value
+				}
+				last = value
+			}
+		}
+		if last == nil {
+			continue
+		}
+
+		html += fmt.Sprintf(`<div class="stats">%s: %.1f%s`, v.label, *last, v.unit)
+		if change := *last - *first; first != last && change != 0 {
+			html += fmt.Sprintf(` (%+.1f%s this week)`, change, v.unit)
+		}
+		html += `</div>`
+	}
+	html += `</div>`
+	return html
+}
+
+// generateGroupHeadingHTML renders the heading for a date or session group
+func generateGroupHeadingHTML(group WorkoutLogGroup) string {
+	if group.Session == nil {
+		return fmt.Sprintf(`<h2>%s</h2>`, formatDateForReport(group.Date))
+	}
+
+	title := "Workout"
+	if group.Session.Name != nil && *group.Session.Name != "" {
+		title = *group.Session.Name
+	}
+	html := fmt.Sprintf(`<h2>%s &ndash; %s</h2>`, title, formatDateForReport(group.Date))
+	if group.Session.StartedAt != nil && group.Session.EndedAt != nil {
+		html += fmt.Sprintf(`<div class="stats">%s - %s</div>`, *group.Session.StartedAt, *group.Session.EndedAt)
+	}
+	if group.Session.Notes != nil && *group.Session.Notes != "" {
+		html += fmt.Sprintf(`<div class="stats" style="font-style: italic;">%s</div>`, *group.Session.Notes)
+	}
+	return html
+}
+
 func formatDateForReport(dateStr string) string {
 	t, err := time.Parse("2006-01-02", dateStr)
 	if err != nil {
@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/models"
+)
+
+// exerciseTaxonomyBatchSize bounds the exercise IDs bound to one taxonomy query
+const exerciseTaxonomyBatchSize = 500
+
+type TaxonomyResponse struct {
+	Muscles          []models.Muscle       `json:"muscles"`
+	Equipment        []models.TaxonomyItem `json:"equipment"`
+	MovementPatterns []models.TaxonomyItem `json:"movement_patterns"`
+}
+
+// GetTaxonomy returns every muscle, piece of equipment and movement pattern
+// exercises can be linked to
+func GetTaxonomy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := TaxonomyResponse{
+		Muscles:          []models.Muscle{},
+		Equipment:        []models.TaxonomyItem{},
+		MovementPatterns: []models.TaxonomyItem{},
+	}
+
+	rows, err := database.DB.Query("SELECT slug, name, region FROM muscles ORDER BY id")
+	if err != nil {
+		fmt.Printf("Get taxonomy error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	for rows.Next() {
+		var m models.Muscle
+		if err := rows.Scan(&m.Slug, &m.Name, &m.Region); err != nil {
+			fmt.Printf("Error scanning muscle: %v\n", err)
+			continue
+		}
+		response.Muscles = append(response.Muscles, m)
+	}
+	rows.Close()
+
+	for table, items := range map[string]*[]models.TaxonomyItem{
+		"equipment":         &response.Equipment,
+		"movement_patterns": &response.MovementPatterns,
+	} {
+		rows, err := database.DB.Query("SELECT slug, name FROM " + table + " ORDER BY id")
+		if err != nil {
+			fmt.Printf("Get taxonomy error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		for rows.Next() {
+			var item models.TaxonomyItem
+			if err := rows.Scan(&item.Slug, &item.Name); err != nil {
+				fmt.Printf("Error scanning %s: %v\n", table, err)
+				continue
+			}
+			*items = append(*items, item)
+		}
+		rows.Close()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// MuscleLinkRequest links an exercise to a muscle. Role defaults to primary.
+type MuscleLinkRequest struct {
+	Slug string `json:"slug"`
+	Role string `json:"role"`
+}
+
+// ExerciseTaxonomyRequest sets the taxonomy of an exercise by slug. Each list
+// given replaces the exercise's links of that kind.
+type ExerciseTaxonomyRequest struct {
+	Muscles          *[]MuscleLinkRequest `json:"muscles"`
+	EquipmentItems   *[]string            `json:"equipment_items"`
+	MovementPatterns *[]string            `json:"movement_patterns"`
+}
+
+// validate checks every slug and role, filling in default roles and
+// dropping duplicates
+func (req *ExerciseTaxonomyRequest) validate() error {
+	if req.Muscles != nil {
+		seen := make(map[string]bool)
+		muscles := []MuscleLinkRequest{}
+		for _, m := range *req.Muscles {
+			if !models.IsValidMuscle(m.Slug) {
+				return fmt.Errorf("unknown muscle %q", m.Slug)
+			}
+			if m.Role == "" {
+				m.Role = models.MuscleRolePrimary
+			} else if !models.IsValidMuscleRole(m.Role) {
+				return fmt.Errorf("muscle role must be primary or secondary")
+			}
+			if !seen[m.Slug] {
+				seen[m.Slug] = true
+				muscles = append(muscles, m)
+			}
+		}
+		req.Muscles = &muscles
+	}
+
+	lists := []struct {
+		slugs *[]string
+		kind  string
+		valid func(string) bool
+	}{
+		{req.EquipmentItems, "equipment", models.IsValidEquipment},
+		{req.MovementPatterns, "movement pattern", models.IsValidMovementPattern},
+	}
+	for _, list := range lists {
+		if list.slugs == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		slugs := []string{}
+		for _, slug := range *list.slugs {
+			if !list.valid(slug) {
+				return fmt.Errorf("unknown %s %q", list.kind, slug)
+			}
+			if !seen[slug] {
+				seen[slug] = true
+				slugs = append(slugs, slug)
+			}
+		}
+		*list.slugs = slugs
+	}
+	return nil
+}
+
+// exerciseMuscles converts the requested muscles to exercise muscles
+func (req *ExerciseTaxonomyRequest) exerciseMuscles() []models.ExerciseMuscle {
+	muscles := make([]models.ExerciseMuscle, len(*req.Muscles))
+	for i, m := range *req.Muscles {
+		muscles[i] = models.ExerciseMuscle{Slug: m.Slug, Role: m.Role}
+	}
+	return muscles
+}
+
+// applyExerciseTaxonomy links an exercise to the taxonomy. Lists given in the
+// request replace the links of their kind; otherwise muscles and equipment
+// are re-mapped when their free text changed. A movement pattern is only
+// inferred from the name when the exercise is new.
+func applyExerciseTaxonomy(e database.Execer, exerciseID int64, source string, req ExerciseTaxonomyRequest,
+	muscleGroup, equipment *string, inferPattern func() []string) error {
+	switch {
+	case req.Muscles != nil:
+		if err := database.SetExerciseMuscles(e, exerciseID, source, req.exerciseMuscles()); err != nil {
+			return err
+		}
+	case muscleGroup != nil:
+		if err := database.SetExerciseMuscles(e, exerciseID, source, models.MapMuscleGroup(*muscleGroup)); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case req.EquipmentItems != nil:
+		if err := database.SetExerciseEquipment(e, exerciseID, source, *req.EquipmentItems); err != nil {
+			return err
+		}
+	case equipment != nil:
+		if err := database.SetExerciseEquipment(e, exerciseID, source, models.MapEquipment(*equipment)); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case req.MovementPatterns != nil:
+		return database.SetExerciseMovementPatterns(e, exerciseID, source, *req.MovementPatterns)
+	case inferPattern != nil:
+		return database.SetExerciseMovementPatterns(e, exerciseID, source, inferPattern())
+	}
+	return nil
+}
+
+// taxonomyText fills in free-text muscle_group and equipment from the
+// requested taxonomy when the request sets the lists but not the text, so
+// the two never disagree
+func taxonomyText(req ExerciseTaxonomyRequest, muscleGroup, equipment *string) (*string, *string) {
+	if muscleGroup == nil && req.Muscles != nil {
+		text := models.MuscleGroupText(req.exerciseMuscles())
+		muscleGroup = &text
+	}
+	if equipment == nil && req.EquipmentItems != nil {
+		text := models.EquipmentText(*req.EquipmentItems)
+		equipment = &text
+	}
+	return muscleGroup, equipment
+}
+
+// fetchExerciseTaxonomy loads the taxonomy links of exercises from one source
+func fetchExerciseTaxonomy(source string, exerciseIDs []int64) (map[int64]*models.ExerciseTaxonomy, error) {
+	taxonomies := make(map[int64]*models.ExerciseTaxonomy, len(exerciseIDs))
+	for _, id := range exerciseIDs {
+		taxonomies[id] = &models.ExerciseTaxonomy{
+			Muscles:          []models.ExerciseMuscle{},
+			EquipmentItems:   []models.TaxonomyItem{},
+			MovementPatterns: []models.TaxonomyItem{},
+		}
+	}
+
+	for start := 0; start < len(exerciseIDs); start += exerciseTaxonomyBatchSize {
+		end := start + exerciseTaxonomyBatchSize
+		if end > len(exerciseIDs) {
+			end = len(exerciseIDs)
+		}
+		batch := exerciseIDs[start:end]
+
+		params := []interface{}{source}
+		for _, id := range batch {
+			params = append(params, id)
+		}
+		in := fmt.Sprintf("(?%s)", strings.Repeat(", ?", len(batch)-1))
+
+		rows, err := database.DB.Query(
+			`SELECT em.exercise_id, m.slug, m.name, m.region, em.role
+			 FROM exercise_muscles em
+			 JOIN muscles m ON m.id = em.muscle_id
+			 WHERE em.exercise_source = ? AND em.exercise_id IN `+in+`
+			 ORDER BY em.exercise_id, em.role = 'secondary', m.id`,
+			params...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var exerciseID int64
+			var m models.ExerciseMuscle
+			if err := rows.Scan(&exerciseID, &m.Slug, &m.Name, &m.Region, &m.Role); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			taxonomies[exerciseID].Muscles = append(taxonomies[exerciseID].Muscles, m)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		links := []struct {
+			query string
+			items func(t *models.ExerciseTaxonomy) *[]models.TaxonomyItem
+		}{
+			{
+				`SELECT ee.exercise_id, e.slug, e.name FROM exercise_equipment ee
+				 JOIN equipment e ON e.id = ee.equipment_id
+				 WHERE ee.exercise_source = ? AND ee.exercise_id IN ` + in + ` ORDER BY ee.exercise_id, e.id`,
+				func(t *models.ExerciseTaxonomy) *[]models.TaxonomyItem { return &t.EquipmentItems },
+			},
+			{
+				`SELECT emp.exercise_id, mp.slug, mp.name FROM exercise_movement_patterns emp
+				 JOIN movement_patterns mp ON mp.id = emp.movement_pattern_id
+				 WHERE emp.exercise_source = ? AND emp.exercise_id IN ` + in + ` ORDER BY emp.exercise_id, mp.id`,
+				func(t *models.ExerciseTaxonomy) *[]models.TaxonomyItem { return &t.MovementPatterns },
+			},
+		}
+		for _, link := range links {
+			rows, err := database.DB.Query(link.query, params...)
+			if err != nil {
+				return nil, err
+			}
+			for rows.Next() {
+				var exerciseID int64
+				var item models.TaxonomyItem
+				if err := rows.Scan(&exerciseID, &item.Slug, &item.Name); err != nil {
+					rows.Close()
+					return nil, err
+				}
+				items := link.items(taxonomies[exerciseID])
+				*items = append(*items, item)
+			}
+			err = rows.Err()
+			rows.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return taxonomies, nil
+}
+
+// taxonomyFilters builds conditions limiting exercises, identified by
+// idColumn, to those linked to the muscle, equipment_item and
+// movement_pattern query parameters. muscle_role=primary only matches
+// exercises that work the muscle as a prime mover.
+func taxonomyFilters(query url.Values, idColumn, source string) ([]string, []interface{}, error) {
+	var conditions []string
+	var params []interface{}
+
+	if muscle := query.Get("muscle"); muscle != "" {
+		if !models.IsValidMuscle(muscle) {
+			return nil, nil, fmt.Errorf("unknown muscle %q", muscle)
+		}
+		condition := `SELECT em.exercise_id FROM exercise_muscles em JOIN muscles m ON m.id = em.muscle_id
+			WHERE em.exercise_source = ? AND m.slug = ?`
+		params = append(params, source, muscle)
+		if role := query.Get("muscle_role"); role != "" {
+			if !models.IsValidMuscleRole(role) {
+				return nil, nil, fmt.Errorf("muscle_role must be primary or secondary")
+			}
+			condition += " AND em.role = ?"
+			params = append(params, role)
+		}
+		conditions = append(conditions, idColumn+" IN ("+condition+")")
+	}
+
+	if slug := query.Get("equipment_item"); slug != "" {
+		if !models.IsValidEquipment(slug) {
+			return nil, nil, fmt.Errorf("unknown equipment %q", slug)
+		}
+		conditions = append(conditions, idColumn+` IN (SELECT ee.exercise_id FROM exercise_equipment ee
+			JOIN equipment e ON e.id = ee.equipment_id WHERE ee.exercise_source = ? AND e.slug = ?)`)
+		params = append(params, source, slug)
+	}
+
+	if slug := query.Get("movement_pattern"); slug != "" {
+		if !models.IsValidMovementPattern(slug) {
+			return nil, nil, fmt.Errorf("unknown movement pattern %q", slug)
+		}
+		conditions = append(conditions, idColumn+` IN (SELECT emp.exercise_id FROM exercise_movement_patterns emp
+			JOIN movement_patterns mp ON mp.id = emp.movement_pattern_id WHERE emp.exercise_source = ? AND mp.slug = ?)`)
+		params = append(params, source, slug)
+	}
+	return conditions, params, nil
+}
+
+// attachExerciseTaxonomy loads the taxonomy of each private exercise
+func attachExerciseTaxonomy(exercises []models.Exercise) error {
+	ids := make([]int64, len(exercises))
+	for i, ex := range exercises {
+		ids[i] = ex.ID
+	}
+	taxonomies, err := fetchExerciseTaxonomy("private", ids)
+	if err != nil {
+		return err
+	}
+	for i := range exercises {
+		exercises[i].ExerciseTaxonomy = *taxonomies[exercises[i].ID]
+	}
+	return nil
+}
+
+// attachPublicExerciseTaxonomy loads the taxonomy of each public exercise
+func attachPublicExerciseTaxonomy(exercises []models.PublicExercise) error {
+	ids := make([]int64, len(exercises))
+	for i, ex := range exercises {
+		ids[i] = ex.ID
+	}
+	taxonomies, err := fetchExerciseTaxonomy("public", ids)
+	if err != nil {
+		return err
+	}
+	for i := range exercises {
+		exercises[i].ExerciseTaxonomy = *taxonomies[exercises[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"database/sql"
+
+	"gym-app-backend/database"
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+// unitsFromColumns builds a unit system from the users columns, falling back to
+// the defaults for missing or unknown values
+func unitsFromColumns(weightUnit, distanceUnit sql.NullString) utils.Units {
+	units := utils.DefaultUnits
+	if utils.IsValidWeightUnit(weightUnit.String) {
+		units.Weight = weightUnit.String
+	}
+	if utils.IsValidDistanceUnit(distanceUnit.String) {
+		units.Distance = distanceUnit.String
+	}
+	return units
+}
+
+// fetchUserUnits returns the unit system the user reads and writes values in
+func fetchUserUnits(userID int64) (utils.Units, error) {
+	var weightUnit, distanceUnit sql.NullString
+	err := database.DB.QueryRow(
+		"SELECT weight_unit, distance_unit FROM users WHERE id = ?",
+		userID,
+	).Scan(&weightUnit, &distanceUnit)
+	if err != nil {
+		return utils.DefaultUnits, err
+	}
+	return unitsFromColumns(weightUnit, distanceUnit), nil
+}
+
+// weightToCanonical converts an optional weight from the user's units to kilograms
+func weightToCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.WeightToCanonical(*v)
+	return &converted
+}
+
+// weightFromCanonical converts an optional weight from kilograms to the user's units
+func weightFromCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.WeightFromCanonical(*v)
+	return &converted
+}
+
+// distanceToCanonical converts an optional distance from the user's units to kilometers
+func distanceToCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.DistanceToCanonical(*v)
+	return &converted
+}
+
+// distanceFromCanonical converts an optional distance from kilometers to the user's units
+func distanceFromCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.DistanceFromCanonical(*v)
+	return &converted
+}
+
+// paceToCanonical converts an optional pace from the user's units to minutes per kilometer
+func paceToCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.PaceToCanonical(*v)
+	return &converted
+}
+
+// paceFromCanonical converts an optional pace from minutes per kilometer to the user's units
+func paceFromCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.PaceFromCanonical(*v)
+	return &converted
+}
+
+// elevationToCanonical converts an optional elevation from the user's units to meters
+func elevationToCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.ElevationToCanonical(*v)
+	return &converted
+}
+
+// elevationFromCanonical converts an optional elevation from meters to the user's units
+func elevationFromCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.ElevationFromCanonical(*v)
+	return &converted
+}
+
+// lengthToCanonical converts an optional body measurement from the user's units to centimeters
+func lengthToCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.LengthToCanonical(*v)
+	return &converted
+}
+
+// lengthFromCanonical converts an optional body measurement from centimeters to the user's units
+func lengthFromCanonical(units utils.Units, v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	converted := units.LengthFromCanonical(*v)
+	return &converted
+}
+
+// canonicalizeWorkoutSets converts set weights from the user's units to kilograms
+func canonicalizeWorkoutSets(sets []models.WorkoutSet, units utils.Units) {
+	for i := range sets {
+		sets[i].Weight = weightToCanonical(units, sets[i].Weight)
+	}
+}
+
+// canonicalizeWorkoutLaps converts lap distances from the user's units to kilometers
+func canonicalizeWorkoutLaps(laps []models.WorkoutLap, units utils.Units) {
+	for i := range laps {
+		laps[i].Distance = distanceToCanonical(units, laps[i].Distance)
+	}
+}
+
+// localizeWorkoutLog converts a stored log to the user's units in place
+func localizeWorkoutLog(log *models.WorkoutLog, units utils.Units) {
+	log.Weight = weightFromCanonical(units, log.Weight)
+	log.Distance = distanceFromCanonical(units, log.Distance)
+	log.Pace = paceFromCanonical(units, log.Pace)
+	log.ElevationGain = elevationFromCanonical(units, log.ElevationGain)
+	for i := range log.WorkoutSets {
+		log.WorkoutSets[i].Weight = weightFromCanonical(units, log.WorkoutSets[i].Weight)
+	}
+	if len(log.WorkoutSets) > 0 {
+		log.WeightPerSet = models.WeightPerSetFromWorkoutSets(log.WorkoutSets)
+	}
+	for i := range log.Laps {
+		log.Laps[i].Distance = distanceFromCanonical(units, log.Laps[i].Distance)
+		log.Laps[i].Pace = paceFromCanonical(units, log.Laps[i].Pace)
+	}
+}
+
+// localizeWorkoutLogs converts stored logs to the user's units in place
+func localizeWorkoutLogs(logs []models.WorkoutLog, units utils.Units) {
+	for i := range logs {
+		localizeWorkoutLog(&logs[i], units)
+	}
+}
+
+// localizeBodyMetrics converts stored body metrics to the user's units in place
+func localizeBodyMetrics(metrics []models.BodyMetric, units utils.Units) {
+	for i := range metrics {
+		metrics[i].Bodyweight = weightFromCanonical(units, metrics[i].Bodyweight)
+		for _, field := range bodyMeasurementFields(&metrics[i].Measurements) {
+			*field = lengthFromCanonical(units, *field)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+	"gym-app-backend/utils"
+)
+
+// maxImportFileSize caps the size of uploaded activity files
+const maxImportFileSize = 20 << 20
+
+type WorkoutImportResponse struct {
+	Log             models.WorkoutLog       `json:"log"`
+	PersonalRecords []models.PersonalRecord `json:"personal_records,omitempty"` // records set by the imported log
+	Import          models.WorkoutImport    `json:"import"`
+	Duplicate       bool                    `json:"duplicate"` // true when the file was imported before
+}
+
+// fetchWorkoutImport returns the user's import of a file. Imports whose log
+// has since been deleted are removed so the file can be imported again.
+func fetchWorkoutImport(userID int64, fileHash string) (models.WorkoutImport, error) {
+	var imp models.WorkoutImport
+	var logExists bool
+	err := database.DB.QueryRow(
+		`SELECT wi.id, wi.user_id, wi.file_hash, wi.format, wi.filename, wi.workout_log_id, wi.created_at,
+		        EXISTS (SELECT 1 FROM workout_logs WHERE id = wi.workout_log_id AND user_id = wi.user_id)
+		 FROM workout_imports wi
+		 WHERE wi.user_id = ? AND wi.file_hash = ?`,
+		userID, fileHash,
+	).Scan(&imp.ID, &imp.UserID, &imp.FileHash, &imp.Format, &imp.Filename, &imp.WorkoutLogID, &imp.CreatedAt, &logExists)
+	if err != nil {
+		return imp, err
+	}
+
+	if !logExists {
+		if _, err := database.DB.Exec("DELETE FROM workout_imports WHERE id = ?", imp.ID); err != nil {
+			return imp, err
+		}
+		return imp, sql.ErrNoRows
+	}
+	return imp, nil
+}
+
+// activityWorkoutLaps converts the laps of an activity into canonical workout laps
+func activityWorkoutLaps(activity *services.Activity) []models.WorkoutLap {
+	laps := make([]models.WorkoutLap, 0, len(activity.Laps))
+	for i, lap := range activity.Laps {
+		if lap.DistanceMeters <= 0 && lap.DurationSeconds <= 0 {
+			continue
+		}
+		wl := models.WorkoutLap{LapIndex: i + 1}
+		if lap.DistanceMeters > 0 {
+			distance := lap.DistanceMeters / 1000
+			wl.Distance = &distance
+		}
+		if lap.DurationSeconds > 0 {
+			seconds := lap.DurationSeconds
+			wl.DurationSeconds = &seconds
+		}
+		if lap.AvgHeartRate != nil && *lap.AvgHeartRate >= 20 && *lap.AvgHeartRate <= 250 {
+			wl.HeartRate = lap.AvgHeartRate
+		}
+		laps = append(laps, wl)
+	}
+	return laps
+}
+
+// ImportWorkoutLog creates a cardio workout log from an uploaded GPX, TCX or
+// FIT file. The multipart form takes the file, exercise_id and optionally
+// source, session_id, date and notes. The date defaults to the activity's
+// start date in UTC. Uploading a file that was imported before returns the
+// existing log instead of creating another one.
+func ImportWorkoutLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize+1<<20)
+	if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+		http.Error(w, `{"error":"Invalid upload, expected a multipart form with a file under 20 MB"}`, http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, `{"error":"file is required"}`, http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, `{"error":"Could not read the uploaded file"}`, http.StatusBadRequest)
+		return
+	}
+
+	exerciseID, err := strconv.ParseInt(r.FormValue("exercise_id"), 10, 64)
+	if err != nil {
+		http.Error(w, `{"error":"exercise_id is required"}`, http.StatusBadRequest)
+		return
+	}
+	source := r.FormValue("source")
+	if source != "" && source != "private" && source != "public" {
+		http.Error(w, `{"error":"source must be private or public"}`, http.StatusBadRequest)
+		return
+	}
+	date := r.FormValue("date")
+	if date != "" {
+		if _, err := utils.ParseDate(date); err != nil {
+			http.Error(w, `{"error":"date must be YYYY-MM-DD"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	exercise, err := resolveExercise(userID, exerciseID, source)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	if !models.RulesForExerciseType(exercise.Type).Distance {
+		http.Error(w, `{"error":"Activity files can only be imported for cardio and mixed exercises"}`, http.StatusBadRequest)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	fileHash := hex.EncodeToString(sum[:])
+
+	// A file imported before returns its existing log
+	existing, err := fetchWorkoutImport(userID, fileHash)
+	if err == nil {
+		log, err := fetchWorkoutLog(existing.WorkoutLogID, userID)
+		if err != nil {
+			fmt.Printf("Import workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		localizeWorkoutLog(&log, units)
+
+		response := WorkoutImportResponse{Log: log, Import: existing, Duplicate: true}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+		return
+	} else if err != sql.ErrNoRows {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Tracks without recorded laps are split every whole unit of distance
+	splitMeters := units.DistanceToCanonical(1) * 1000
+	activity, err := services.ParseActivityFile(data, header.Filename, splitMeters)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if date == "" {
+		date = utils.FormatDate(activity.StartTime.UTC())
+	}
+
+	metrics := cardioMetrics{}
+	if activity.DistanceMeters > 0 {
+		distance := activity.DistanceMeters / 1000
+		metrics.Distance = &distance
+	}
+	if activity.DurationSeconds > 0 {
+		duration := int(math.Round(activity.DurationSeconds / 60))
+		metrics.Duration = &duration
+		metrics.Seconds = &activity.DurationSeconds
+	}
+	laps := activityWorkoutLaps(activity)
+	metrics, err = resolveCardioMetrics(metrics, laps, units)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	var sessionID, sessionOrder sql.NullInt64
+	if v := r.FormValue("session_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, `{"error":"Invalid session ID"}`, http.StatusBadRequest)
+			return
+		}
+		order, err := nextSessionOrder(id, userID)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Workout session not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Printf("Import workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		sessionID = sql.NullInt64{Int64: id, Valid: true}
+		sessionOrder = sql.NullInt64{Int64: int64(order), Valid: true}
+	}
+
+	var notes, filename *string
+	if v := strings.TrimSpace(r.FormValue("notes")); v != "" {
+		notes = &v
+	}
+	if header.Filename != "" {
+		filename = &header.Filename
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO workout_logs (user_id, exercise_id, exercise_source, session_id, session_order, date, distance, duration, pace, elevation_gain, notes)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, exercise.ID, exercise.Source, sessionID, sessionOrder, date, metrics.Distance, metrics.Duration, metrics.Pace,
+		activity.ElevationGain, notes,
+	)
+	if err != nil {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	logID, _ := result.LastInsertId()
+
+	if err := replaceWorkoutLaps(tx, logID, laps); err != nil {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO workout_imports (user_id, file_hash, format, filename, workout_log_id) VALUES (?, ?, ?, ?, ?)",
+		userID, fileHash, activity.Format, filename, logID,
+	)
+	if err != nil {
+		// A concurrent upload of the same file won the race
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			http.Error(w, `{"error":"This file has already been imported"}`, http.StatusConflict)
+			return
+		}
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	records, err := services.RebuildPersonalRecords(tx, userID, exercise.ID, exercise.Source)
+	if err != nil {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := services.EvaluateGoals(tx, userID); err != nil {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Import workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	log, err := fetchWorkoutLog(logID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching imported log: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeWorkoutLog(&log, units)
+
+	imp, err := fetchWorkoutImport(userID, fileHash)
+	if err != nil {
+		fmt.Printf("Error fetching workout import: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := WorkoutImportResponse{Log: log, PersonalRecords: recordsSetByLog(records, logID, units), Import: imp}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+// paceTolerance is how far, relative to the derived pace, a submitted pace or
+// lap total may be off before it is rejected. It absorbs rounding by clients.
+const paceTolerance = 0.05
+
+// WorkoutLapRequest describes one lap or split of a cardio log, in order
+type WorkoutLapRequest struct {
+	Label           *string  `json:"label"`
+	Distance        *float64 `json:"distance"`
+	DurationSeconds *float64 `json:"duration_seconds"`
+	HeartRate       *int     `json:"heart_rate"`
+}
+
+// buildWorkoutLaps returns the laps to store for a log. Typed laps win;
+// otherwise the legacy lap_times value is converted.
+func buildWorkoutLaps(items []WorkoutLapRequest, lapTimes interface{}) ([]models.WorkoutLap, error) {
+	if items == nil {
+		return models.WorkoutLapsFromLapTimes(lapTimes)
+	}
+
+	laps := make([]models.WorkoutLap, 0, len(items))
+	for i, item := range items {
+		if item.Distance != nil && *item.Distance < 0 {
+			return nil, fmt.Errorf("lap %d: distance cannot be negative", i+1)
+		}
+		if item.DurationSeconds != nil && *item.DurationSeconds < 0 {
+			return nil, fmt.Errorf("lap %d: duration_seconds cannot be negative", i+1)
+		}
+		if item.HeartRate != nil && (*item.HeartRate < 20 || *item.HeartRate > 250) {
+			return nil, fmt.Errorf("lap %d: heart_rate must be between 20 and 250", i+1)
+		}
+
+		laps = append(laps, models.WorkoutLap{
+			LapIndex:        i + 1,
+			Label:           item.Label,
+			Distance:        item.Distance,
+			DurationSeconds: item.DurationSeconds,
+			HeartRate:       item.HeartRate,
+		})
+	}
+	return laps, nil
+}
+
+// cardioMetrics are the canonical distance, duration and pace of a cardio log
+type cardioMetrics struct {
+	Distance *float64
+	Duration *int
+	Pace     *float64
+	// Seconds is the exact duration when known; Duration only keeps whole minutes
+	Seconds *float64
+}
+
+// resolveCardioMetrics completes and checks the canonical metrics of a cardio
+// log. Missing distance and duration are summed from the laps, and pace is
+// derived from distance and duration whenever both are known, using the exact
+// lap seconds when the laps cover the whole log. A submitted pace or lap total
+// that contradicts them is rejected; units only phrase the error.
+func resolveCardioMetrics(m cardioMetrics, laps []models.WorkoutLap, units utils.Units) (cardioMetrics, error) {
+	var lapDistance, lapSeconds float64
+	for _, lap := range laps {
+		if lap.Distance != nil {
+			lapDistance += *lap.Distance
+		}
+		if lap.DurationSeconds != nil {
+			lapSeconds += *lap.DurationSeconds
+		}
+	}
+
+	if m.Distance == nil && lapDistance > 0 {
+		distance := lapDistance
+		m.Distance = &distance
+	} else if m.Distance != nil && lapDistance > *m.Distance*(1+paceTolerance) {
+		return m, fmt.Errorf("lap distances add up to more than the log distance")
+	}
+
+	if m.Duration == nil && lapSeconds > 0 {
+		duration := int(math.Round(lapSeconds / 60))
+		m.Duration = &duration
+	} else if m.Duration != nil && lapSeconds/60 > float64(*m.Duration)*(1+paceTolerance)+1 {
+		return m, fmt.Errorf("lap durations add up to more than the log duration")
+	}
+
+	if m.Distance == nil || *m.Distance <= 0 || m.Duration == nil || *m.Duration <= 0 {
+		return m, nil
+	}
+
+	// The duration column holds whole minutes, so the exact seconds, or the
+	// laps when they account for all of it, give the true pace
+	minutes := float64(*m.Duration)
+	if m.Seconds != nil && int(math.Round(*m.Seconds/60)) == *m.Duration {
+		minutes = *m.Seconds / 60
+	} else if lapSeconds > 0 && int(math.Round(lapSeconds/60)) == *m.Duration {
+		minutes = lapSeconds / 60
+	}
+
+	derived := minutes / *m.Distance
+	if m.Pace != nil && math.Abs(*m.Pace-derived) > derived*paceTolerance {
+		return m, fmt.Errorf(
+			"pace does not match distance and duration (expected about %.2f %s)",
+			units.PaceFromCanonical(derived), units.PaceLabel(),
+		)
+	}
+	m.Pace = &derived
+	return m, nil
+}
+
+// replaceWorkoutLaps replaces the laps of a workout log inside a transaction
+func replaceWorkoutLaps(tx *sql.Tx, logID int64, laps []models.WorkoutLap) error {
+	if _, err := tx.Exec("DELETE FROM workout_laps WHERE workout_log_id = ?", logID); err != nil {
+		return err
+	}
+
+	for i, lap := range laps {
+		_, err := tx.Exec(
+			`INSERT INTO workout_laps (workout_log_id, lap_index, label, distance, duration_seconds, heart_rate)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			logID, i+1, lap.Label, lap.Distance, lap.DurationSeconds, lap.HeartRate,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchWorkoutLaps loads the laps of the given logs keyed by log ID
+func fetchWorkoutLaps(logIDs []int64) (map[int64][]models.WorkoutLap, error) {
+	lapsByLog := make(map[int64][]models.WorkoutLap)
+
+	for start := 0; start < len(logIDs); start += workoutSetsBatchSize {
+		end := start + workoutSetsBatchSize
+		if end > len(logIDs) {
+			end = len(logIDs)
+		}
+		batch := logIDs[start:end]
+
+		placeholders := make([]string, len(batch))
+		params := make([]interface{}, len(batch))
+		for i, id := range batch {
+			placeholders[i] = "?"
+			params[i] = id
+		}
+
+		rows, err := database.DB.Query(
+			fmt.Sprintf(`SELECT id, workout_log_id, lap_index, label, distance, duration_seconds, heart_rate
+			 FROM workout_laps
+			 WHERE workout_log_id IN (%s)
+			 ORDER BY workout_log_id, lap_index`, strings.Join(placeholders, ", ")),
+			params...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var lap models.WorkoutLap
+			if err := rows.Scan(
+				&lap.ID, &lap.WorkoutLogID, &lap.LapIndex, &lap.Label, &lap.Distance,
+				&lap.DurationSeconds, &lap.HeartRate,
+			); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			lap.DerivePace()
+			lapsByLog[lap.WorkoutLogID] = append(lapsByLog[lap.WorkoutLogID], lap)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return lapsByLog, nil
+}
+
+// attachWorkoutLaps loads the laps of each log and fills in the legacy
+// lap_times view from them
+func attachWorkoutLaps(logs []models.WorkoutLog) error {
+	logIDs := make([]int64, len(logs))
+	for i, log := range logs {
+		logIDs[i] = log.ID
+	}
+
+	lapsByLog, err := fetchWorkoutLaps(logIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range logs {
+		laps := lapsByLog[logs[i].ID]
+		if len(laps) == 0 {
+			continue
+		}
+		logs[i].Laps = laps
+		logs[i].LapTimes = models.LapTimesFromWorkoutLaps(laps)
+	}
+	return nil
+}
+
+// attachLogDetails loads the sets, laps and custom metrics of each log
+func attachLogDetails(logs []models.WorkoutLog) error {
+	if err := attachWorkoutSets(logs); err != nil {
+		return err
+	}
+	if err := attachWorkoutLaps(logs); err != nil {
+		return err
+	}
+	return attachCustomMetrics(logs)
+}
@@ -11,51 +11,190 @@ import (
 	"gym-app-backend/database"
 	"gym-app-backend/middleware"
 	"gym-app-backend/models"
+	"gym-app-backend/services"
 )
 
 type WorkoutLogResponse struct {
-	Log models.WorkoutLog `json:"log"`
+	Log             models.WorkoutLog       `json:"log"`
+	PersonalRecords []models.PersonalRecord `json:"personal_records,omitempty"` // records set by this log
+	AchievedGoals   []models.Goal           `json:"achieved_goals,omitempty"`   // goals this log achieved
 }
 
 type WorkoutLogsResponse struct {
 	Logs []models.WorkoutLog `json:"logs"`
 }
 
+type WorkoutLogGroupsResponse struct {
+	Groups []WorkoutLogGroup `json:"groups"`
+}
+
 type LastWorkoutResponse struct {
-	LastLog *models.WorkoutLog `json:"lastLog"`
+	LastLog    *models.WorkoutLog              `json:"lastLog"`
+	Suggestion *services.ProgressionSuggestion `json:"suggestion"` // next prescription, when one can be derived
+}
+
+// workoutLogSelect selects workout log columns in a fixed order together with
+// the exercise name and type. Use it with scanWorkoutLog.
+const workoutLogSelect = `
+	SELECT wl.id, wl.user_id, wl.exercise_id, wl.exercise_source, wl.session_id, wl.session_order, wl.group_id, wl.date,
+	       wl.sets, wl.reps, wl.weight, wl.weight_per_set, wl.rest_time, wl.distance,
+	       wl.duration, wl.pace, wl.lap_times, wl.elevation_gain, wl.notes, wl.planned, wl.created_at,
+	       COALESCE(e.name, pe.name) as exercise_name,
+	       COALESCE(e.exercise_type, pe.exercise_type) as exercise_type
+	FROM workout_logs wl
+	LEFT JOIN exercises e ON wl.exercise_source = 'private' AND wl.exercise_id = e.id AND wl.user_id = e.user_id
+	LEFT JOIN public_exercises pe ON wl.exercise_source = 'public' AND wl.exercise_id = pe.id
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWorkoutLog scans a row selected with workoutLogSelect and parses its JSON fields
+func scanWorkoutLog(row rowScanner) (models.WorkoutLog, error) {
+	var log models.WorkoutLog
+	var weightPerSetStr, lapTimesStr sql.NullString
+	err := row.Scan(
+		&log.ID, &log.UserID, &log.ExerciseID, &log.ExerciseSource, &log.SessionID, &log.SessionOrder, &log.GroupID, &log.Date,
+		&log.Sets, &log.Reps, &log.Weight, &weightPerSetStr, &log.RestTime, &log.Distance,
+		&log.Duration, &log.Pace, &lapTimesStr, &log.ElevationGain, &log.Notes, &log.Planned, &log.CreatedAt,
+		&log.ExerciseName, &log.ExerciseType,
+	)
+	if err != nil {
+		return log, err
+	}
+
+	// Parse JSON fields
+	if weightPerSetStr.Valid && weightPerSetStr.String != "" {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(weightPerSetStr.String), &parsed); err == nil {
+			log.WeightPerSet = parsed
+		}
+	}
+	if lapTimesStr.Valid && lapTimesStr.String != "" {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(lapTimesStr.String), &parsed); err == nil {
+			log.LapTimes = parsed
+		}
+	}
+
+	return log, nil
+}
+
+// fetchWorkoutLog loads a single workout log owned by the user with its sets
+func fetchWorkoutLog(logID, userID int64) (models.WorkoutLog, error) {
+	log, err := scanWorkoutLog(database.DB.QueryRow(
+		workoutLogSelect+" WHERE wl.id = ? AND wl.user_id = ?",
+		logID, userID,
+	))
+	if err != nil {
+		return log, err
+	}
+
+	logs := []models.WorkoutLog{log}
+	if err := attachLogDetails(logs); err != nil {
+		return log, err
+	}
+	return logs[0], nil
 }
 
 type CreateWorkoutLogRequest struct {
-	ExerciseID   int64        `json:"exercise_id"`
-	Date         string       `json:"date"`
-	Sets         *int         `json:"sets"`
-	Reps         *int         `json:"reps"`
-	Weight       *float64     `json:"weight"`
-	WeightPerSet interface{} `json:"weight_per_set"`
-	RestTime     *int         `json:"rest_time"`
-	Distance     *float64     `json:"distance"`
-	Duration     *int         `json:"duration"`
-	Pace         *float64     `json:"pace"`
-	LapTimes     interface{}  `json:"lap_times"`
-	Notes        *string      `json:"notes"`
+	ExerciseID     int64    `json:"exercise_id"`
+	ExerciseSource string   `json:"exercise_source"` // "private" or "public"; empty prefers the user's own exercise
+	SessionID      *int64   `json:"session_id"`
+	SessionOrder   *int     `json:"session_order"`
+	Date           string   `json:"date"`
+	Sets           *int     `json:"sets"`
+	Reps           *int     `json:"reps"`
+	Weight         *float64 `json:"weight"`
+	// WeightPerSet is the legacy per-set field; WorkoutSets takes precedence
+	WeightPerSet interface{}         `json:"weight_per_set"`
+	WorkoutSets  []WorkoutSetRequest `json:"workout_sets"`
+	RestTime     *int                `json:"rest_time"`
+	Distance     *float64            `json:"distance"`
+	Duration     *int                `json:"duration"`
+	Pace         *float64            `json:"pace"`
+	// LapTimes is the legacy lap field; Laps takes precedence
+	LapTimes      interface{}         `json:"lap_times"`
+	Laps          []WorkoutLapRequest `json:"laps"`
+	ElevationGain *float64            `json:"elevation_gain"`
+	Notes         *string             `json:"notes"`
+	// CustomMetrics sets values of the custom fields defined on the exercise
+	CustomMetrics []CustomMetricRequest `json:"custom_metrics"`
 }
 
 type UpdateWorkoutLogRequest struct {
-	ExerciseID   *int64       `json:"exercise_id"`
-	Date         *string      `json:"date"`
-	Sets         *int          `json:"sets"`
-	Reps         *int         `json:"reps"`
-	Weight       *float64     `json:"weight"`
-	WeightPerSet interface{}  `json:"weight_per_set"`
-	RestTime     *int         `json:"rest_time"`
-	Distance     *float64     `json:"distance"`
-	Duration     *int         `json:"duration"`
-	Pace         *float64     `json:"pace"`
-	LapTimes     interface{}  `json:"lap_times"`
-	Notes        *string      `json:"notes"`
+	ExerciseID     *int64  `json:"exercise_id"`
+	ExerciseSource *string `json:"exercise_source"` // "private" or "public"; a new exercise_id without it prefers the user's own exercise
+	// SessionID moves the log into another session; 0 detaches it from its session
+	SessionID    *int64   `json:"session_id"`
+	SessionOrder *int     `json:"session_order"`
+	Date         *string  `json:"date"`
+	Sets         *int     `json:"sets"`
+	Reps         *int     `json:"reps"`
+	Weight       *float64 `json:"weight"`
+	// WeightPerSet is the legacy per-set field; WorkoutSets takes precedence.
+	// Either one replaces all sets of the log.
+	WeightPerSet interface{}          `json:"weight_per_set"`
+	WorkoutSets  *[]WorkoutSetRequest `json:"workout_sets"`
+	RestTime     *int                 `json:"rest_time"`
+	Distance     *float64             `json:"distance"`
+	Duration     *int                 `json:"duration"`
+	Pace         *float64             `json:"pace"`
+	// LapTimes is the legacy lap field; Laps takes precedence.
+	// Either one replaces all laps of the log.
+	LapTimes      interface{}          `json:"lap_times"`
+	Laps          *[]WorkoutLapRequest `json:"laps"`
+	ElevationGain *float64             `json:"elevation_gain"`
+	Notes         *string              `json:"notes"`
+	// CustomMetrics replaces all custom metric values of the log
+	CustomMetrics *[]CustomMetricRequest `json:"custom_metrics"`
 }
 
-// GetAllWorkoutLogs returns all workout logs for the authenticated user
+// loggedValues records which kinds of values a create or update request
+// carries, so they can be checked against the rules of the exercise type
+type loggedValues struct {
+	weight   bool // log, legacy or per-set weight
+	sets     bool // per-set values of any kind
+	reps     bool // log or per-set reps
+	hold     bool // per-set hold time
+	distance bool // distance, pace, laps or elevation gain
+	duration bool
+}
+
+// setValues adds the values carried by per-set requests
+func (v *loggedValues) setValues(items []WorkoutSetRequest) {
+	for _, item := range items {
+		v.weight = v.weight || item.Weight != nil
+		v.reps = v.reps || item.Reps != nil
+		v.hold = v.hold || item.DurationSeconds != nil
+	}
+}
+
+// validateLoggedValues checks that an exercise type can be logged with the
+// values of a request
+func validateLoggedValues(exerciseType string, v loggedValues) error {
+	rules := models.RulesForExerciseType(exerciseType)
+	switch {
+	case v.distance && !rules.Distance:
+		return fmt.Errorf("distance, pace, lap times, and elevation gain cannot be used for %s exercises", exerciseType)
+	case v.duration && !rules.Duration:
+		return fmt.Errorf("duration cannot be used for %s exercises", exerciseType)
+	case v.sets && !rules.Sets():
+		return fmt.Errorf("workout sets cannot be used for %s exercises", exerciseType)
+	case v.weight && rules.Load == models.LoadNone:
+		return fmt.Errorf("weight cannot be used for %s exercises", exerciseType)
+	case v.reps && !rules.Reps && rules.Sets():
+		// Cardio logs have always been allowed a set and rep count for intervals
+		return fmt.Errorf("reps cannot be used for %s exercises", exerciseType)
+	case v.hold && !rules.Hold:
+		return fmt.Errorf("set durations can only be used for timed hold, flexibility, and mixed exercises")
+	}
+	return nil
+}
+
+// GetAllWorkoutLogs returns all workout logs for the authenticated user.
+// Pass group_by=date or group_by=session to receive the logs grouped.
 func GetAllWorkoutLogs(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
@@ -64,22 +203,46 @@ func GetAllWorkoutLogs(w http.ResponseWriter, r *http.Request) {
 
 	userID := middleware.GetUserID(r)
 
-	query := `
-		SELECT wl.*, 
-		       COALESCE(e.name, pe.name) as exercise_name,
-		       COALESCE(e.exercise_type, pe.exercise_type) as exercise_type
-		FROM workout_logs wl
-		LEFT JOIN exercises e ON wl.exercise_id = e.id AND wl.user_id = e.user_id
-		LEFT JOIN public_exercises pe ON wl.exercise_id = pe.id
-		WHERE wl.user_id = ?
-	`
+	groupBy := r.URL.Query().Get("group_by")
+	if groupBy != "" && groupBy != "date" && groupBy != "session" {
+		http.Error(w, `{"error":"group_by must be date or session"}`, http.StatusBadRequest)
+		return
+	}
+
+	query := workoutLogSelect + " WHERE wl.user_id = ?"
 	params := []interface{}{userID}
 
-	// Add filters
+	// Add filters. source tells private and public exercises with the same
+	// ID apart; without it the user's own exercise is preferred.
 	if exerciseIDStr := r.URL.Query().Get("exercise_id"); exerciseIDStr != "" {
 		if exerciseID, err := strconv.ParseInt(exerciseIDStr, 10, 64); err == nil {
+			source := r.URL.Query().Get("source")
+			if source != "" && source != "private" && source != "public" {
+				http.Error(w, `{"error":"source must be private or public"}`, http.StatusBadRequest)
+				return
+			}
+			if source == "" {
+				exercise, err := resolveExercise(userID, exerciseID, "")
+				if err != nil && err != sql.ErrNoRows {
+					fmt.Printf("Get workout logs error: %v\n", err)
+					http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+					return
+				}
+				source = exercise.Source
+			}
 			query += " AND wl.exercise_id = ?"
 			params = append(params, exerciseID)
+			if source != "" {
+				query += " AND wl.exercise_source = ?"
+				params = append(params, source)
+			}
+		}
+	}
+
+	if sessionIDStr := r.URL.Query().Get("session_id"); sessionIDStr != "" {
+		if sessionID, err := strconv.ParseInt(sessionIDStr, 10, 64); err == nil {
+			query += " AND wl.session_id = ?"
+			params = append(params, sessionID)
 		}
 	}
 
@@ -112,38 +275,42 @@ func GetAllWorkoutLogs(w http.ResponseWriter, r *http.Request) {
 
 	var logs []models.WorkoutLog
 	for rows.Next() {
-		var log models.WorkoutLog
-		var weightPerSetStr, lapTimesStr sql.NullString
-		var createdAtStr string
-		err := rows.Scan(
-			&log.ID, &log.UserID, &log.ExerciseID, &log.Date, &log.Sets, &log.Reps,
-			&log.Weight, &weightPerSetStr, &log.RestTime, &log.Distance, &log.Duration,
-			&log.Pace, &lapTimesStr, &log.Notes, &createdAtStr, &log.ExerciseName, &log.ExerciseType,
-		)
+		log, err := scanWorkoutLog(rows)
 		if err != nil {
 			fmt.Printf("Error scanning log: %v\n", err)
 			continue
 		}
+		logs = append(logs, log)
+	}
 
-		// Parse JSON fields
-		if weightPerSetStr.Valid && weightPerSetStr.String != "" {
-			var parsed interface{}
-			if err := json.Unmarshal([]byte(weightPerSetStr.String), &parsed); err == nil {
-				log.WeightPerSet = parsed
-			}
-		}
-		if lapTimesStr.Valid && lapTimesStr.String != "" {
-			var parsed interface{}
-			if err := json.Unmarshal([]byte(lapTimesStr.String), &parsed); err == nil {
-				log.LapTimes = parsed
-			}
-		}
+	if err := attachLogDetails(logs); err != nil {
+		fmt.Printf("Get workout logs error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
 
-		logs = append(logs, log)
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get workout logs error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
+	localizeWorkoutLogs(logs, units)
 
-	response := WorkoutLogsResponse{Logs: logs}
 	w.Header().Set("Content-Type", "application/json")
+
+	if groupBy != "" {
+		groups, err := groupWorkoutLogs(userID, logs, groupBy)
+		if err != nil {
+			fmt.Printf("Group workout logs error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(WorkoutLogGroupsResponse{Groups: groups})
+		return
+	}
+
+	response := WorkoutLogsResponse{Logs: logs}
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -161,24 +328,7 @@ func GetWorkoutLogById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var log models.WorkoutLog
-	var weightPerSetStr, lapTimesStr sql.NullString
-	var createdAtStr string
-	err = database.DB.QueryRow(
-		`SELECT wl.*, 
-		       COALESCE(e.name, pe.name) as exercise_name,
-		       COALESCE(e.exercise_type, pe.exercise_type) as exercise_type
-		 FROM workout_logs wl
-		 LEFT JOIN exercises e ON wl.exercise_id = e.id AND wl.user_id = e.user_id
-		 LEFT JOIN public_exercises pe ON wl.exercise_id = pe.id
-		 WHERE wl.id = ? AND wl.user_id = ?`,
-		logID, userID,
-	).Scan(
-		&log.ID, &log.UserID, &log.ExerciseID, &log.Date, &log.Sets, &log.Reps,
-		&log.Weight, &weightPerSetStr, &log.RestTime, &log.Distance, &log.Duration,
-		&log.Pace, &lapTimesStr, &log.Notes, &createdAtStr, &log.ExerciseName, &log.ExerciseType,
-	)
-
+	log, err := fetchWorkoutLog(logID, userID)
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Workout log not found"}`, http.StatusNotFound)
 		return
@@ -188,19 +338,13 @@ func GetWorkoutLogById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse JSON fields
-	if weightPerSetStr.Valid && weightPerSetStr.String != "" {
-		var parsed interface{}
-		if err := json.Unmarshal([]byte(weightPerSetStr.String), &parsed); err == nil {
-			log.WeightPerSet = parsed
-		}
-	}
-	if lapTimesStr.Valid && lapTimesStr.String != "" {
-		var parsed interface{}
-		if err := json.Unmarshal([]byte(lapTimesStr.String), &parsed); err == nil {
-			log.LapTimes = parsed
-		}
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
+	localizeWorkoutLog(&log, units)
 
 	response := WorkoutLogResponse{Log: log}
 	w.Header().Set("Content-Type", "application/json")
@@ -227,15 +371,13 @@ func CreateWorkoutLog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify exercise exists (either user's exercise or public exercise) and get exercise type
-	var exerciseType string
-	err := database.DB.QueryRow(
-		`SELECT exercise_type FROM exercises WHERE id = ? AND user_id = ?
-		 UNION ALL
-		 SELECT exercise_type FROM public_exercises WHERE id = ?`,
-		req.ExerciseID, userID, req.ExerciseID,
-	).Scan(&exerciseType)
+	if req.ExerciseSource != "" && req.ExerciseSource != "private" && req.ExerciseSource != "public" {
+		http.Error(w, `{"error":"exercise_source must be private or public"}`, http.StatusBadRequest)
+		return
+	}
 
+	// Verify exercise exists (either user's exercise or public exercise) and get exercise type
+	exercise, err := resolveExercise(userID, req.ExerciseID, req.ExerciseSource)
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
 		return
@@ -244,39 +386,115 @@ func CreateWorkoutLog(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
+	exerciseType := exercise.Type
+
+	// Validate: each exercise type is logged with its own kinds of values
+	logged := loggedValues{
+		weight:   req.Weight != nil || req.WeightPerSet != nil,
+		sets:     req.WorkoutSets != nil || req.WeightPerSet != nil,
+		reps:     req.Reps != nil,
+		distance: req.Distance != nil || req.Pace != nil || req.LapTimes != nil || req.Laps != nil || req.ElevationGain != nil,
+		duration: req.Duration != nil,
+	}
+	logged.setValues(req.WorkoutSets)
+	if err := validateLoggedValues(exerciseType, logged); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if req.ElevationGain != nil && *req.ElevationGain < 0 {
+		http.Error(w, `{"error":"elevation_gain cannot be negative"}`, http.StatusBadRequest)
+		return
+	}
+	rules := models.RulesForExerciseType(exerciseType)
 
-	// Validate: distance/time should only be used for cardio
-	if exerciseType != "cardio" && (req.Distance != nil || req.Duration != nil || req.Pace != nil || req.LapTimes != nil) {
-		http.Error(w, `{"error":"Distance, duration, pace, and lap times can only be used for cardio exercises"}`, http.StatusBadRequest)
+	sets, err := buildWorkoutSets(req.WorkoutSets, req.WeightPerSet, req.Reps)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	laps, err := buildWorkoutLaps(req.Laps, req.LapTimes)
+	if err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
 		return
 	}
 
-	// Validate: weight/weight_per_set should only be used for strength
-	if exerciseType != "strength" && (req.Weight != nil || req.WeightPerSet != nil) {
-		http.Error(w, `{"error":"Weight and weight per set can only be used for strength exercises"}`, http.StatusBadRequest)
+	// Custom metrics must match the fields defined on the exercise
+	var customMetrics []models.CustomMetric
+	if len(req.CustomMetrics) > 0 {
+		fields, err := fetchCustomFields(userID, exercise)
+		if err != nil {
+			fmt.Printf("Create workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		customMetrics, err = buildCustomMetrics(fields, req.CustomMetrics)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Values arrive in the user's units and are stored canonically
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Create workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
+	req.Weight = weightToCanonical(units, req.Weight)
+	req.Distance = distanceToCanonical(units, req.Distance)
+	req.Pace = paceToCanonical(units, req.Pace)
+	req.ElevationGain = elevationToCanonical(units, req.ElevationGain)
+	canonicalizeWorkoutSets(sets, units)
+	canonicalizeWorkoutLaps(laps, units)
+	if req.Sets == nil && len(sets) > 0 {
+		setCount := len(sets)
+		req.Sets = &setCount
+	}
 
-	// Serialize JSON fields
-	var weightPerSetStr, lapTimesStr sql.NullString
-	if req.WeightPerSet != nil {
-		data, err := json.Marshal(req.WeightPerSet)
-		if err == nil {
-			weightPerSetStr = sql.NullString{String: string(data), Valid: true}
+	// Pace is derived from distance and duration rather than trusted
+	if rules.Distance {
+		metrics, err := resolveCardioMetrics(cardioMetrics{Distance: req.Distance, Duration: req.Duration, Pace: req.Pace}, laps, units)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
 		}
+		req.Distance, req.Duration, req.Pace = metrics.Distance, metrics.Duration, metrics.Pace
 	}
-	if req.LapTimes != nil {
-		data, err := json.Marshal(req.LapTimes)
-		if err == nil {
-			lapTimesStr = sql.NullString{String: string(data), Valid: true}
+
+	// Verify session belongs to user and place the log at the end unless an order is given
+	var sessionID sql.NullInt64
+	var sessionOrder sql.NullInt64
+	if req.SessionID != nil && *req.SessionID != 0 {
+		order, err := nextSessionOrder(*req.SessionID, userID)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Workout session not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Printf("Create workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if req.SessionOrder != nil {
+			order = *req.SessionOrder
 		}
+		sessionID = sql.NullInt64{Int64: *req.SessionID, Valid: true}
+		sessionOrder = sql.NullInt64{Int64: int64(order), Valid: true}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Create workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
+	defer tx.Rollback()
 
-	result, err := database.DB.Exec(
-		`INSERT INTO workout_logs (user_id, exercise_id, date, sets, reps, weight, weight_per_set, rest_time, distance, duration, pace, lap_times, notes)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		userID, req.ExerciseID, req.Date, req.Sets, req.Reps, req.Weight,
-		weightPerSetStr, req.RestTime, req.Distance, req.Duration, req.Pace, lapTimesStr, req.Notes,
+	result, err := tx.Exec(
+		`INSERT INTO workout_logs (user_id, exercise_id, exercise_source, session_id, session_order, date, sets, reps, weight, rest_time, distance, duration, pace, elevation_gain, notes)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		userID, exercise.ID, exercise.Source, sessionID, sessionOrder, req.Date, req.Sets, req.Reps, req.Weight,
+		req.RestTime, req.Distance, req.Duration, req.Pace, req.ElevationGain, req.Notes,
 	)
 	if err != nil {
 		fmt.Printf("Create workout log error: %v\n", err)
@@ -286,44 +504,57 @@ func CreateWorkoutLog(w http.ResponseWriter, r *http.Request) {
 
 	logID, _ := result.LastInsertId()
 
-	var log models.WorkoutLog
-	var weightPerSetStr2, lapTimesStr2 sql.NullString
-	var createdAtStr string
-	err = database.DB.QueryRow(
-		`SELECT wl.*, 
-		       COALESCE(e.name, pe.name) as exercise_name,
-		       COALESCE(e.exercise_type, pe.exercise_type) as exercise_type
-		 FROM workout_logs wl
-		 LEFT JOIN exercises e ON wl.exercise_id = e.id AND wl.user_id = e.user_id
-		 LEFT JOIN public_exercises pe ON wl.exercise_id = pe.id
-		 WHERE wl.id = ?`,
-		logID,
-	).Scan(
-		&log.ID, &log.UserID, &log.ExerciseID, &log.Date, &log.Sets, &log.Reps,
-		&log.Weight, &weightPerSetStr2, &log.RestTime, &log.Distance, &log.Duration,
-		&log.Pace, &lapTimesStr2, &log.Notes, &createdAtStr, &log.ExerciseName, &log.ExerciseType,
-	)
+	if err := replaceWorkoutSets(tx, logID, sets); err != nil {
+		fmt.Printf("Create workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := replaceWorkoutLaps(tx, logID, laps); err != nil {
+		fmt.Printf("Create workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := replaceCustomMetrics(tx, logID, customMetrics); err != nil {
+		fmt.Printf("Create workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	records, err := services.RebuildPersonalRecords(tx, userID, exercise.ID, exercise.Source)
 	if err != nil {
-		fmt.Printf("Error fetching created log: %v\n", err)
+		fmt.Printf("Create workout log error: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// Parse JSON fields
-	if weightPerSetStr2.Valid && weightPerSetStr2.String != "" {
-		var parsed interface{}
-		if err := json.Unmarshal([]byte(weightPerSetStr2.String), &parsed); err == nil {
-			log.WeightPerSet = parsed
-		}
+	achievedGoals, err := services.EvaluateGoals(tx, userID)
+	if err != nil {
+		fmt.Printf("Create workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
-	if lapTimesStr2.Valid && lapTimesStr2.String != "" {
-		var parsed interface{}
-		if err := json.Unmarshal([]byte(lapTimesStr2.String), &parsed); err == nil {
-			log.LapTimes = parsed
-		}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Create workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
 
-	response := WorkoutLogResponse{Log: log}
+	log, err := fetchWorkoutLog(logID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching created log: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeWorkoutLog(&log, units)
+
+	response := WorkoutLogResponse{
+		Log:             log,
+		PersonalRecords: recordsSetByLog(records, logID, units),
+		AchievedGoals:   goalsAchievedBy(achievedGoals, logID, userID, units),
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(response)
@@ -345,10 +576,13 @@ func UpdateWorkoutLog(w http.ResponseWriter, r *http.Request) {
 
 	// Verify log belongs to user
 	var existingExerciseID int64
+	var existingSource string
+	var existingReps, existingDuration *int
+	var existingDistance *float64
 	err = database.DB.QueryRow(
-		"SELECT exercise_id FROM workout_logs WHERE id = ? AND user_id = ?",
+		"SELECT exercise_id, exercise_source, reps, distance, duration FROM workout_logs WHERE id = ? AND user_id = ?",
 		logID, userID,
-	).Scan(&existingExerciseID)
+	).Scan(&existingExerciseID, &existingSource, &existingReps, &existingDistance, &existingDuration)
 
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Workout log not found"}`, http.StatusNotFound)
@@ -366,19 +600,19 @@ func UpdateWorkoutLog(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get exercise type for validation
-	currentExerciseID := existingExerciseID
+	exerciseID, source := existingExerciseID, existingSource
 	if req.ExerciseID != nil {
-		currentExerciseID = *req.ExerciseID
+		exerciseID, source = *req.ExerciseID, ""
+	}
+	if req.ExerciseSource != nil {
+		if *req.ExerciseSource != "private" && *req.ExerciseSource != "public" {
+			http.Error(w, `{"error":"exercise_source must be private or public"}`, http.StatusBadRequest)
+			return
+		}
+		source = *req.ExerciseSource
 	}
 
-	var exerciseType string
-	err = database.DB.QueryRow(
-		`SELECT exercise_type FROM exercises WHERE id = ? AND user_id = ?
-		 UNION ALL
-		 SELECT exercise_type FROM public_exercises WHERE id = ?`,
-		currentExerciseID, userID, currentExerciseID,
-	).Scan(&exerciseType)
-
+	exercise, err := resolveExercise(userID, exerciseID, source)
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
 		return
@@ -387,26 +621,164 @@ func UpdateWorkoutLog(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
-
-	// Validate: distance/time should only be used for cardio
-	if exerciseType != "cardio" && (req.Distance != nil || req.Duration != nil || req.Pace != nil || req.LapTimes != nil) {
-		http.Error(w, `{"error":"Distance, duration, pace, and lap times can only be used for cardio exercises"}`, http.StatusBadRequest)
+	exerciseType := exercise.Type
+	exerciseChanged := exercise.ID != existingExerciseID || exercise.Source != existingSource
+
+	// Validate: each exercise type is logged with its own kinds of values
+	logged := loggedValues{
+		weight:   req.Weight != nil || req.WeightPerSet != nil,
+		sets:     req.WorkoutSets != nil || req.WeightPerSet != nil,
+		reps:     req.Reps != nil,
+		distance: req.Distance != nil || req.Pace != nil || req.LapTimes != nil || req.Laps != nil || req.ElevationGain != nil,
+		duration: req.Duration != nil,
+	}
+	if req.WorkoutSets != nil {
+		logged.setValues(*req.WorkoutSets)
+	}
+	if err := validateLoggedValues(exerciseType, logged); err != nil {
+		http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+		return
+	}
+	if req.ElevationGain != nil && *req.ElevationGain < 0 {
+		http.Error(w, `{"error":"elevation_gain cannot be negative"}`, http.StatusBadRequest)
 		return
 	}
+	rules := models.RulesForExerciseType(exerciseType)
 
-	// Validate: weight/weight_per_set should only be used for strength
-	if exerciseType != "strength" && (req.Weight != nil || req.WeightPerSet != nil) {
-		http.Error(w, `{"error":"Weight and weight per set can only be used for strength exercises"}`, http.StatusBadRequest)
+	// Values arrive in the user's units and are stored canonically
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Update workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
+	req.Weight = weightToCanonical(units, req.Weight)
+	req.Distance = distanceToCanonical(units, req.Distance)
+	req.Pace = paceToCanonical(units, req.Pace)
+	req.ElevationGain = elevationToCanonical(units, req.ElevationGain)
+
+	// Either per-set field replaces the stored sets
+	var sets []models.WorkoutSet
+	replaceSets := req.WorkoutSets != nil || req.WeightPerSet != nil
+	if replaceSets {
+		var items []WorkoutSetRequest
+		if req.WorkoutSets != nil {
+			items = *req.WorkoutSets
+			if items == nil {
+				items = []WorkoutSetRequest{}
+			}
+		}
+		reps := existingReps
+		if req.Reps != nil {
+			reps = req.Reps
+		}
+		sets, err = buildWorkoutSets(items, req.WeightPerSet, reps)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		canonicalizeWorkoutSets(sets, units)
+		if req.Sets == nil {
+			setCount := len(sets)
+			req.Sets = &setCount
+		}
+	}
+
+	// Either lap field replaces the stored laps
+	var laps []models.WorkoutLap
+	replaceLaps := req.Laps != nil || req.LapTimes != nil
+	if replaceLaps {
+		var items []WorkoutLapRequest
+		if req.Laps != nil {
+			items = *req.Laps
+			if items == nil {
+				items = []WorkoutLapRequest{}
+			}
+		}
+		laps, err = buildWorkoutLaps(items, req.LapTimes)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		canonicalizeWorkoutLaps(laps, units)
+	}
+
+	// Custom metrics replace the stored values. Moving the log to another
+	// exercise drops values of the old exercise's fields.
+	var customMetrics []models.CustomMetric
+	replaceMetrics := req.CustomMetrics != nil || exerciseChanged
+	if req.CustomMetrics != nil && len(*req.CustomMetrics) > 0 {
+		fields, err := fetchCustomFields(userID, exercise)
+		if err != nil {
+			fmt.Printf("Update workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		customMetrics, err = buildCustomMetrics(fields, *req.CustomMetrics)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Pace is derived again whenever anything it depends on changes
+	if rules.Distance && (req.Distance != nil || req.Duration != nil || req.Pace != nil || replaceLaps) {
+		metrics := cardioMetrics{Distance: existingDistance, Duration: existingDuration, Pace: req.Pace}
+		if req.Distance != nil {
+			metrics.Distance = req.Distance
+		}
+		if req.Duration != nil {
+			metrics.Duration = req.Duration
+		}
+		checkLaps := laps
+		if !replaceLaps {
+			lapsByLog, err := fetchWorkoutLaps([]int64{logID})
+			if err != nil {
+				fmt.Printf("Update workout log error: %v\n", err)
+				http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+				return
+			}
+			checkLaps = lapsByLog[logID]
+		}
+
+		metrics, err = resolveCardioMetrics(metrics, checkLaps, units)
+		if err != nil {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, err.Error()), http.StatusBadRequest)
+			return
+		}
+		req.Distance, req.Duration, req.Pace = metrics.Distance, metrics.Duration, metrics.Pace
+	}
 
 	// Build update query dynamically
 	updates := []string{}
 	values := []interface{}{}
 
-	if req.ExerciseID != nil {
-		updates = append(updates, "exercise_id = ?")
-		values = append(values, *req.ExerciseID)
+	if exerciseChanged {
+		updates = append(updates, "exercise_id = ?", "exercise_source = ?")
+		values = append(values, exercise.ID, exercise.Source)
+	}
+	if req.SessionID != nil {
+		if *req.SessionID == 0 {
+			updates = append(updates, "session_id = NULL", "session_order = NULL")
+		} else {
+			order, err := nextSessionOrder(*req.SessionID, userID)
+			if err == sql.ErrNoRows {
+				http.Error(w, `{"error":"Workout session not found"}`, http.StatusNotFound)
+				return
+			} else if err != nil {
+				fmt.Printf("Update workout log error: %v\n", err)
+				http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+				return
+			}
+			if req.SessionOrder != nil {
+				order = *req.SessionOrder
+			}
+			updates = append(updates, "session_id = ?", "session_order = ?")
+			values = append(values, *req.SessionID, order)
+		}
+	} else if req.SessionOrder != nil {
+		updates = append(updates, "session_order = ?")
+		values = append(values, *req.SessionOrder)
 	}
 	if req.Date != nil {
 		updates = append(updates, "date = ?")
@@ -424,12 +796,8 @@ func UpdateWorkoutLog(w http.ResponseWriter, r *http.Request) {
 		updates = append(updates, "weight = ?")
 		values = append(values, *req.Weight)
 	}
-	if req.WeightPerSet != nil {
-		data, err := json.Marshal(req.WeightPerSet)
-		if err == nil {
-			updates = append(updates, "weight_per_set = ?")
-			values = append(values, string(data))
-		}
+	if replaceSets {
+		updates = append(updates, "weight_per_set = NULL")
 	}
 	if req.RestTime != nil {
 		updates = append(updates, "rest_time = ?")
@@ -447,67 +815,111 @@ func UpdateWorkoutLog(w http.ResponseWriter, r *http.Request) {
 		updates = append(updates, "pace = ?")
 		values = append(values, *req.Pace)
 	}
-	if req.LapTimes != nil {
-		data, err := json.Marshal(req.LapTimes)
-		if err == nil {
-			updates = append(updates, "lap_times = ?")
-			values = append(values, string(data))
-		}
+	if replaceLaps {
+		updates = append(updates, "lap_times = NULL")
+	}
+	if req.ElevationGain != nil {
+		updates = append(updates, "elevation_gain = ?")
+		values = append(values, *req.ElevationGain)
 	}
 	if req.Notes != nil {
 		updates = append(updates, "notes = ?")
 		values = append(values, *req.Notes)
 	}
 
-	if len(updates) > 0 {
-		values = append(values, logID, userID)
-		query := fmt.Sprintf("UPDATE workout_logs SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
-		_, err = database.DB.Exec(query, values...)
-		if err != nil {
+	// Saving a planned log confirms it was performed
+	updates = append(updates, "planned = 0")
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Update workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	values = append(values, logID, userID)
+	query := fmt.Sprintf("UPDATE workout_logs SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
+	if _, err := tx.Exec(query, values...); err != nil {
+		fmt.Printf("Update workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if replaceSets {
+		if err := replaceWorkoutSets(tx, logID, sets); err != nil {
 			fmt.Printf("Update workout log error: %v\n", err)
 			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 			return
 		}
 	}
 
-	var log models.WorkoutLog
-	var weightPerSetStr, lapTimesStr sql.NullString
-	var createdAtStr string
-	err = database.DB.QueryRow(
-		`SELECT wl.*, 
-		       COALESCE(e.name, pe.name) as exercise_name,
-		       COALESCE(e.exercise_type, pe.exercise_type) as exercise_type
-		 FROM workout_logs wl
-		 LEFT JOIN exercises e ON wl.exercise_id = e.id AND wl.user_id = e.user_id
-		 LEFT JOIN public_exercises pe ON wl.exercise_id = pe.id
-		 WHERE wl.id = ?`,
-		logID,
-	).Scan(
-		&log.ID, &log.UserID, &log.ExerciseID, &log.Date, &log.Sets, &log.Reps,
-		&log.Weight, &weightPerSetStr, &log.RestTime, &log.Distance, &log.Duration,
-		&log.Pace, &lapTimesStr, &log.Notes, &createdAtStr, &log.ExerciseName, &log.ExerciseType,
-	)
+	if replaceLaps {
+		if err := replaceWorkoutLaps(tx, logID, laps); err != nil {
+			fmt.Printf("Update workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if replaceMetrics {
+		if err := replaceCustomMetrics(tx, logID, customMetrics); err != nil {
+			fmt.Printf("Update workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// A log moved out of its session leaves its exercise group
+	if req.SessionID != nil {
+		if err := pruneExerciseGroups(tx, userID); err != nil {
+			fmt.Printf("Update workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// Moving a log to another exercise changes the records of both
+	if exerciseChanged {
+		if _, err := services.RebuildPersonalRecords(tx, userID, existingExerciseID, existingSource); err != nil {
+			fmt.Printf("Update workout log error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+	records, err := services.RebuildPersonalRecords(tx, userID, exercise.ID, exercise.Source)
 	if err != nil {
-		fmt.Printf("Error fetching updated log: %v\n", err)
+		fmt.Printf("Update workout log error: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// Parse JSON fields
-	if weightPerSetStr.Valid && weightPerSetStr.String != "" {
-		var parsed interface{}
-		if err := json.Unmarshal([]byte(weightPerSetStr.String), &parsed); err == nil {
-			log.WeightPerSet = parsed
-		}
+	achievedGoals, err := services.EvaluateGoals(tx, userID)
+	if err != nil {
+		fmt.Printf("Update workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
-	if lapTimesStr.Valid && lapTimesStr.String != "" {
-		var parsed interface{}
-		if err := json.Unmarshal([]byte(lapTimesStr.String), &parsed); err == nil {
-			log.LapTimes = parsed
-		}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Update workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
 	}
 
-	response := WorkoutLogResponse{Log: log}
+	log, err := fetchWorkoutLog(logID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching updated log: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeWorkoutLog(&log, units)
+
+	response := WorkoutLogResponse{
+		Log:             log,
+		PersonalRecords: recordsSetByLog(records, logID, units),
+		AchievedGoals:   goalsAchievedBy(achievedGoals, logID, userID, units),
+	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -527,11 +939,12 @@ func DeleteWorkoutLog(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify log belongs to user
-	var existingID int64
+	var exerciseID int64
+	var source string
 	err = database.DB.QueryRow(
-		"SELECT id FROM workout_logs WHERE id = ? AND user_id = ?",
+		"SELECT exercise_id, exercise_source FROM workout_logs WHERE id = ? AND user_id = ?",
 		logID, userID,
-	).Scan(&existingID)
+	).Scan(&exerciseID, &source)
 
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Workout log not found"}`, http.StatusNotFound)
@@ -542,18 +955,75 @@ func DeleteWorkoutLog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = database.DB.Exec("DELETE FROM workout_logs WHERE id = ? AND user_id = ?", logID, userID)
+	tx, err := database.DB.Begin()
 	if err != nil {
 		fmt.Printf("Delete workout log error: %v\n", err)
 		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM workout_sets WHERE workout_log_id = ?", logID); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM workout_laps WHERE workout_log_id = ?", logID); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM workout_log_custom_values WHERE workout_log_id = ?", logID); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Dropping the import record lets the same file be imported again
+	if _, err := tx.Exec("DELETE FROM workout_imports WHERE workout_log_id = ?", logID); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM workout_logs WHERE id = ? AND user_id = ?", logID, userID); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := pruneExerciseGroups(tx, userID); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := services.RebuildPersonalRecords(tx, userID, exerciseID, source); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := services.EvaluateGoals(tx, userID); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Delete workout log error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "Workout log deleted successfully"})
 }
 
 // GetLastWorkoutValues returns the most recent workout log for an exercise
+// together with a suggested next prescription from its progression rule
 func GetLastWorkoutValues(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
@@ -583,15 +1053,14 @@ func GetLastWorkoutValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify exercise exists (either user's exercise or public exercise)
-	var exerciseExists int64
-	err = database.DB.QueryRow(
-		`SELECT id FROM exercises WHERE id = ? AND user_id = ?
-		 UNION ALL
-		 SELECT id FROM public_exercises WHERE id = ?`,
-		exerciseID, userID, exerciseID,
-	).Scan(&exerciseExists)
+	source := r.URL.Query().Get("source")
+	if source != "" && source != "private" && source != "public" {
+		http.Error(w, `{"error":"source must be private or public"}`, http.StatusBadRequest)
+		return
+	}
 
+	// Verify exercise exists (either user's exercise or public exercise)
+	exercise, err := resolveExercise(userID, exerciseID, source)
 	if err == sql.ErrNoRows {
 		http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
 		return
@@ -602,20 +1071,7 @@ func GetLastWorkoutValues(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get the most recent workout log for this exercise
-	var log models.WorkoutLog
-	var weightPerSetStr, lapTimesStr sql.NullString
-	err = database.DB.QueryRow(
-		`SELECT sets, reps, weight, weight_per_set, rest_time, distance, duration, pace, lap_times, date
-		 FROM workout_logs
-		 WHERE exercise_id = ? AND user_id = ?
-		 ORDER BY date DESC, created_at DESC
-		 LIMIT 1`,
-		exerciseID, userID,
-	).Scan(
-		&log.Sets, &log.Reps, &log.Weight, &weightPerSetStr, &log.RestTime,
-		&log.Distance, &log.Duration, &log.Pace, &lapTimesStr, &log.Date,
-	)
-
+	log, err := fetchLastWorkoutLog(userID, exercise.ID, exercise.Source)
 	if err == sql.ErrNoRows {
 		response := LastWorkoutResponse{LastLog: nil}
 		w.Header().Set("Content-Type", "application/json")
@@ -627,6 +1083,46 @@ func GetLastWorkoutValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get last workout values error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeWorkoutLog(&log, units)
+
+	suggestion, err := suggestNextPrescription(userID, exercise, units)
+	if err != nil {
+		fmt.Printf("Get last workout values error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := LastWorkoutResponse{LastLog: &log, Suggestion: suggestion}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// fetchLastWorkoutLog returns the user's most recent performed log for an
+// exercise. Returns sql.ErrNoRows if the exercise has never been logged.
+func fetchLastWorkoutLog(userID, exerciseID int64, source string) (models.WorkoutLog, error) {
+	var log models.WorkoutLog
+	var weightPerSetStr, lapTimesStr sql.NullString
+	err := database.DB.QueryRow(
+		`SELECT id, sets, reps, weight, weight_per_set, rest_time, distance, duration, pace, lap_times, date
+		 FROM workout_logs
+		 WHERE exercise_id = ? AND exercise_source = ? AND user_id = ? AND planned = 0
+		 ORDER BY date DESC, created_at DESC
+		 LIMIT 1`,
+		exerciseID, source, userID,
+	).Scan(
+		&log.ID, &log.Sets, &log.Reps, &log.Weight, &weightPerSetStr, &log.RestTime,
+		&log.Distance, &log.Duration, &log.Pace, &lapTimesStr, &log.Date,
+	)
+	if err != nil {
+		return log, err
+	}
+
 	// Parse JSON fields
 	if weightPerSetStr.Valid && weightPerSetStr.String != "" {
 		var parsed interface{}
@@ -641,8 +1137,9 @@ func GetLastWorkoutValues(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	response := LastWorkoutResponse{LastLog: &log}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	logs := []models.WorkoutLog{log}
+	if err := attachLogDetails(logs); err != nil {
+		return log, err
+	}
+	return logs[0], nil
 }
-
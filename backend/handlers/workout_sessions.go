@@ -0,0 +1,633 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/services"
+)
+
+type WorkoutSessionResponse struct {
+	Session models.WorkoutSession `json:"session"`
+}
+
+type WorkoutSessionsResponse struct {
+	Sessions []models.WorkoutSession `json:"sessions"`
+}
+
+type CreateWorkoutSessionRequest struct {
+	Name      *string `json:"name"`
+	Date      string  `json:"date"`
+	StartedAt *string `json:"started_at"`
+	EndedAt   *string `json:"ended_at"`
+	Notes     *string `json:"notes"`
+	// LogIDs attaches existing workout logs to the session in the given order
+	LogIDs []int64 `json:"log_ids"`
+}
+
+type UpdateWorkoutSessionRequest struct {
+	Name      *string `json:"name"`
+	Date      *string `json:"date"`
+	StartedAt *string `json:"started_at"`
+	EndedAt   *string `json:"ended_at"`
+	Notes     *string `json:"notes"`
+	// LogIDs replaces the session's logs and their order; logs left out are detached
+	LogIDs *[]int64 `json:"log_ids"`
+}
+
+// WorkoutLogGroup is a set of workout logs that share a date or a session
+type WorkoutLogGroup struct {
+	Key     string                 `json:"key"`
+	Date    string                 `json:"date"`
+	Session *models.WorkoutSession `json:"session,omitempty"`
+	Logs    []models.WorkoutLog    `json:"logs"`
+}
+
+var errSessionLogNotFound = errors.New("workout log not found")
+
+const workoutSessionSelect = `
+	SELECT id, user_id, name, date, started_at, ended_at, notes, created_at
+	FROM workout_sessions
+`
+
+func scanWorkoutSession(row rowScanner) (models.WorkoutSession, error) {
+	var session models.WorkoutSession
+	err := row.Scan(
+		&session.ID, &session.UserID, &session.Name, &session.Date,
+		&session.StartedAt, &session.EndedAt, &session.Notes, &session.CreatedAt,
+	)
+	return session, err
+}
+
+// fetchWorkoutSession loads a session owned by the user with its exercise
+// groups, without its logs
+func fetchWorkoutSession(sessionID, userID int64) (models.WorkoutSession, error) {
+	session, err := scanWorkoutSession(database.DB.QueryRow(
+		workoutSessionSelect+" WHERE id = ? AND user_id = ?",
+		sessionID, userID,
+	))
+	if err != nil {
+		return session, err
+	}
+
+	session.Groups, err = fetchExerciseGroups(sessionID, userID)
+	return session, err
+}
+
+// fetchSessionLogs loads the logs of a session in session order
+func fetchSessionLogs(sessionID, userID int64) ([]models.WorkoutLog, error) {
+	rows, err := database.DB.Query(
+		workoutLogSelect+" WHERE wl.session_id = ? AND wl.user_id = ? ORDER BY wl.session_order ASC, wl.created_at ASC",
+		sessionID, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var logs []models.WorkoutLog
+	for rows.Next() {
+		log, err := scanWorkoutLog(rows)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := attachLogDetails(logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// fetchLocalizedSessionLogs loads the logs of a session in the user's units
+func fetchLocalizedSessionLogs(sessionID, userID int64) ([]models.WorkoutLog, error) {
+	logs, err := fetchSessionLogs(sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		return nil, err
+	}
+	localizeWorkoutLogs(logs, units)
+	return logs, nil
+}
+
+// nextSessionOrder verifies the session belongs to the user and returns the
+// position after its last log. Returns sql.ErrNoRows if the session is not found.
+func nextSessionOrder(sessionID, userID int64) (int, error) {
+	var order int
+	err := database.DB.QueryRow(
+		`SELECT (SELECT COALESCE(MAX(session_order), 0) FROM workout_logs WHERE session_id = ws.id) + 1
+		 FROM workout_sessions ws
+		 WHERE ws.id = ? AND ws.user_id = ?`,
+		sessionID, userID,
+	).Scan(&order)
+	return order, err
+}
+
+// assignSessionLogs makes logIDs the ordered logs of the session. Logs
+// currently in the session that are not listed are detached.
+func assignSessionLogs(tx *sql.Tx, sessionID, userID int64, logIDs []int64) error {
+	for _, logID := range logIDs {
+		var existingID int64
+		err := tx.QueryRow(
+			"SELECT id FROM workout_logs WHERE id = ? AND user_id = ?",
+			logID, userID,
+		).Scan(&existingID)
+		if err == sql.ErrNoRows {
+			return errSessionLogNotFound
+		} else if err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(
+		"UPDATE workout_logs SET session_id = NULL, session_order = NULL WHERE session_id = ? AND user_id = ?",
+		sessionID, userID,
+	)
+	if err != nil {
+		return err
+	}
+
+	for i, logID := range logIDs {
+		_, err := tx.Exec(
+			"UPDATE workout_logs SET session_id = ?, session_order = ? WHERE id = ? AND user_id = ?",
+			sessionID, i+1, logID, userID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Logs that left a session leave its groups as well
+	return pruneExerciseGroups(tx, userID)
+}
+
+// groupWorkoutLogs groups logs by date or by session, keeping the order in
+// which groups first appear. With group_by=session, logs that are not part of
+// a session fall back to one group per date.
+func groupWorkoutLogs(userID int64, logs []models.WorkoutLog, groupBy string) ([]WorkoutLogGroup, error) {
+	groups := []WorkoutLogGroup{}
+	index := make(map[string]int)
+
+	for _, log := range logs {
+		key := "date:" + log.Date
+		if groupBy == "session" && log.SessionID != nil {
+			key = fmt.Sprintf("session:%d", *log.SessionID)
+		}
+
+		i, ok := index[key]
+		if !ok {
+			group := WorkoutLogGroup{Key: key, Date: log.Date}
+			if groupBy == "session" && log.SessionID != nil {
+				session, err := fetchWorkoutSession(*log.SessionID, userID)
+				if err != nil {
+					return nil, err
+				}
+				group.Date = session.Date
+				group.Session = &session
+			}
+			groups = append(groups, group)
+			i = len(groups) - 1
+			index[key] = i
+		}
+		groups[i].Logs = append(groups[i].Logs, log)
+	}
+
+	for _, group := range groups {
+		if group.Session == nil {
+			continue
+		}
+		sort.SliceStable(group.Logs, func(a, b int) bool {
+			return sessionOrderOf(group.Logs[a]) < sessionOrderOf(group.Logs[b])
+		})
+	}
+
+	return groups, nil
+}
+
+func sessionOrderOf(log models.WorkoutLog) int {
+	if log.SessionOrder == nil {
+		return 0
+	}
+	return *log.SessionOrder
+}
+
+func parseWorkoutSessionID(r *http.Request) (int64, error) {
+	return strconv.ParseInt(strings.TrimSuffix(r.URL.Path[len("/api/workouts/"):], "/"), 10, 64)
+}
+
+// GetAllWorkoutSessions returns the user's workout sessions with their logs
+func GetAllWorkoutSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	query := workoutSessionSelect + " WHERE user_id = ?"
+	params := []interface{}{userID}
+
+	if startDate := r.URL.Query().Get("start_date"); startDate != "" {
+		query += " AND date >= ?"
+		params = append(params, startDate)
+	}
+
+	if endDate := r.URL.Query().Get("end_date"); endDate != "" {
+		query += " AND date <= ?"
+		params = append(params, endDate)
+	}
+
+	query += " ORDER BY date DESC, created_at DESC"
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.ParseInt(limitStr, 10, 64); err == nil {
+			query += " LIMIT ?"
+			params = append(params, limit)
+		}
+	}
+
+	rows, err := database.DB.Query(query, params...)
+	if err != nil {
+		fmt.Printf("Get workout sessions error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var sessions []models.WorkoutSession
+	for rows.Next() {
+		session, err := scanWorkoutSession(rows)
+		if err != nil {
+			fmt.Printf("Error scanning workout session: %v\n", err)
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	rows.Close()
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get workout sessions error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	for i := range sessions {
+		logs, err := fetchSessionLogs(sessions[i].ID, userID)
+		if err != nil {
+			fmt.Printf("Get workout sessions error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		localizeWorkoutLogs(logs, units)
+		sessions[i].Logs = logs
+
+		sessions[i].Groups, err = fetchExerciseGroups(sessions[i].ID, userID)
+		if err != nil {
+			fmt.Printf("Get workout sessions error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	response := WorkoutSessionsResponse{Sessions: sessions}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetWorkoutSessionById returns a single workout session with its ordered logs
+func GetWorkoutSessionById(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	sessionID, err := parseWorkoutSessionID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid session ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	session, err := fetchWorkoutSession(sessionID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Workout session not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Get workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	session.Logs, err = fetchLocalizedSessionLogs(sessionID, userID)
+	if err != nil {
+		fmt.Printf("Get workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := WorkoutSessionResponse{Session: session}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateWorkoutSession creates a new workout session, optionally attaching existing logs
+func CreateWorkoutSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	var req CreateWorkoutSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Date == "" {
+		http.Error(w, `{"error":"Date is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Create workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO workout_sessions (user_id, name, date, started_at, ended_at, notes)
+		 VALUES (?, ?, ?, ?, ?, ?)`,
+		userID, req.Name, req.Date, req.StartedAt, req.EndedAt, req.Notes,
+	)
+	if err != nil {
+		fmt.Printf("Create workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sessionID, _ := result.LastInsertId()
+
+	if len(req.LogIDs) > 0 {
+		err = assignSessionLogs(tx, sessionID, userID, req.LogIDs)
+		if err == errSessionLogNotFound {
+			http.Error(w, `{"error":"Workout log not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Printf("Create workout session error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Create workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	session, err := fetchWorkoutSession(sessionID, userID)
+	if err == nil {
+		session.Logs, err = fetchLocalizedSessionLogs(sessionID, userID)
+	}
+	if err != nil {
+		fmt.Printf("Error fetching created workout session: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := WorkoutSessionResponse{Session: session}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateWorkoutSession updates a workout session and optionally replaces its ordered logs
+func UpdateWorkoutSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	sessionID, err := parseWorkoutSessionID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid session ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Verify session belongs to user
+	if _, err := fetchWorkoutSession(sessionID, userID); err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Workout session not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Update workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var req UpdateWorkoutSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Build update query dynamically
+	updates := []string{}
+	values := []interface{}{}
+
+	if req.Name != nil {
+		updates = append(updates, "name = ?")
+		values = append(values, *req.Name)
+	}
+	if req.Date != nil {
+		if *req.Date == "" {
+			http.Error(w, `{"error":"Date cannot be empty"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "date = ?")
+		values = append(values, *req.Date)
+	}
+	if req.StartedAt != nil {
+		updates = append(updates, "started_at = ?")
+		values = append(values, *req.StartedAt)
+	}
+	if req.EndedAt != nil {
+		updates = append(updates, "ended_at = ?")
+		values = append(values, *req.EndedAt)
+	}
+	if req.Notes != nil {
+		updates = append(updates, "notes = ?")
+		values = append(values, *req.Notes)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Update workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if len(updates) > 0 {
+		values = append(values, sessionID, userID)
+		query := fmt.Sprintf("UPDATE workout_sessions SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
+		if _, err := tx.Exec(query, values...); err != nil {
+			fmt.Printf("Update workout session error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.LogIDs != nil {
+		err = assignSessionLogs(tx, sessionID, userID, *req.LogIDs)
+		if err == errSessionLogNotFound {
+			http.Error(w, `{"error":"Workout log not found"}`, http.StatusNotFound)
+			return
+		} else if err != nil {
+			fmt.Printf("Update workout session error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Update workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	session, err := fetchWorkoutSession(sessionID, userID)
+	if err == nil {
+		session.Logs, err = fetchLocalizedSessionLogs(sessionID, userID)
+	}
+	if err != nil {
+		fmt.Printf("Error fetching updated workout session: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := WorkoutSessionResponse{Session: session}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteWorkoutSession deletes a workout session together with its logs.
+// Pass keep_logs=true to detach the logs instead of deleting them.
+func DeleteWorkoutSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	sessionID, err := parseWorkoutSessionID(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid session ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Verify session belongs to user
+	if _, err := fetchWorkoutSession(sessionID, userID); err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Workout session not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Delete workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Delete workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	// Handle the logs explicitly rather than relying on foreign key cascades,
+	// which are only enabled on some pooled connections
+	logsQueries := []string{
+		"DELETE FROM workout_sets WHERE workout_log_id IN (SELECT id FROM workout_logs WHERE session_id = ? AND user_id = ?)",
+		"DELETE FROM workout_laps WHERE workout_log_id IN (SELECT id FROM workout_logs WHERE session_id = ? AND user_id = ?)",
+		"DELETE FROM workout_log_custom_values WHERE workout_log_id IN (SELECT id FROM workout_logs WHERE session_id = ? AND user_id = ?)",
+		"DELETE FROM workout_imports WHERE workout_log_id IN (SELECT id FROM workout_logs WHERE session_id = ? AND user_id = ?)",
+		"DELETE FROM workout_logs WHERE session_id = ? AND user_id = ?",
+		"DELETE FROM exercise_groups WHERE session_id = ? AND user_id = ?",
+	}
+	keepLogs := r.URL.Query().Get("keep_logs") == "true"
+	if keepLogs {
+		logsQueries = []string{
+			"UPDATE workout_logs SET session_id = NULL, session_order = NULL, group_id = NULL WHERE session_id = ? AND user_id = ?",
+			"DELETE FROM exercise_groups WHERE session_id = ? AND user_id = ?",
+		}
+	}
+
+	// Deleted logs may have held records, so note their exercises first
+	var exercises []exerciseRef
+	if !keepLogs {
+		exercises, err = sessionExercises(tx, sessionID, userID)
+		if err != nil {
+			fmt.Printf("Delete workout session error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, query := range logsQueries {
+		if _, err := tx.Exec(query, sessionID, userID); err != nil {
+			fmt.Printf("Delete workout session error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, exercise := range exercises {
+		if _, err := services.RebuildPersonalRecords(tx, userID, exercise.ID, exercise.Source); err != nil {
+			fmt.Printf("Delete workout session error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if _, err := services.EvaluateGoals(tx, userID); err != nil {
+		fmt.Printf("Delete workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM workout_sessions WHERE id = ? AND user_id = ?", sessionID, userID); err != nil {
+		fmt.Printf("Delete workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Delete workout session error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Workout session deleted successfully"})
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"gym-app-backend/database"
+	"gym-app-backend/models"
+)
+
+// WorkoutSetRequest describes one set of a workout log, in order
+type WorkoutSetRequest struct {
+	Reps            *int     `json:"reps"`
+	Weight          *float64 `json:"weight"`
+	DurationSeconds *int     `json:"duration_seconds"`
+	SetType         string   `json:"set_type"` // warmup, working, drop, failure; defaults to working
+	RPE             *float64 `json:"rpe"`
+	Tempo           *string  `json:"tempo"`
+}
+
+// buildWorkoutSets returns the sets to store for a log. Typed workout_sets win;
+// otherwise the legacy weight_per_set value is converted.
+func buildWorkoutSets(items []WorkoutSetRequest, weightPerSet interface{}, reps *int) ([]models.WorkoutSet, error) {
+	if items == nil {
+		return models.WorkoutSetsFromWeightPerSet(weightPerSet, reps)
+	}
+
+	sets := make([]models.WorkoutSet, 0, len(items))
+	for i, item := range items {
+		setType := item.SetType
+		if setType == "" {
+			setType = models.SetTypeWorking
+		}
+		if !models.IsValidSetType(setType) {
+			return nil, fmt.Errorf("set %d: set_type must be warmup, working, drop, or failure", i+1)
+		}
+		if item.Reps != nil && *item.Reps < 0 {
+			return nil, fmt.Errorf("set %d: reps cannot be negative", i+1)
+		}
+		if item.Weight != nil && *item.Weight < 0 {
+			return nil, fmt.Errorf("set %d: weight cannot be negative", i+1)
+		}
+		if item.DurationSeconds != nil && *item.DurationSeconds < 0 {
+			return nil, fmt.Errorf("set %d: duration_seconds cannot be negative", i+1)
+		}
+		if item.RPE != nil && (*item.RPE < 1 || *item.RPE > 10) {
+			return nil, fmt.Errorf("set %d: rpe must be between 1 and 10", i+1)
+		}
+
+		sets = append(sets, models.WorkoutSet{
+			SetIndex:        i + 1,
+			Reps:            item.Reps,
+			Weight:          item.Weight,
+			DurationSeconds: item.DurationSeconds,
+			SetType:         setType,
+			RPE:             item.RPE,
+			Tempo:           item.Tempo,
+		})
+	}
+	return sets, nil
+}
+
+// replaceWorkoutSets replaces the sets of a workout log inside a transaction
+func replaceWorkoutSets(tx *sql.Tx, logID int64, sets []models.WorkoutSet) error {
+	if _, err := tx.Exec("DELETE FROM workout_sets WHERE workout_log_id = ?", logID); err != nil {
+		return err
+	}
+
+	for i, set := range sets {
+		setType := set.SetType
+		if setType == "" {
+			setType = models.SetTypeWorking
+		}
+		_, err := tx.Exec(
+			`INSERT INTO workout_sets (workout_log_id, set_index, reps, weight, duration_seconds, set_type, rpe, tempo)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			logID, i+1, set.Reps, set.Weight, set.DurationSeconds, setType, set.RPE, set.Tempo,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workoutSetsBatchSize keeps IN lists well below SQLite's variable limit
+const workoutSetsBatchSize = 500
+
+// fetchWorkoutSets loads the sets of the given logs keyed by log ID
+func fetchWorkoutSets(logIDs []int64) (map[int64][]models.WorkoutSet, error) {
+	setsByLog := make(map[int64][]models.WorkoutSet)
+
+	for start := 0; start < len(logIDs); start += workoutSetsBatchSize {
+		end := start + workoutSetsBatchSize
+		if end > len(logIDs) {
+			end = len(logIDs)
+		}
+		batch := logIDs[start:end]
+
+		placeholders := make([]string, len(batch))
+		params := make([]interface{}, len(batch))
+		for i, id := range batch {
+			placeholders[i] = "?"
+			params[i] = id
+		}
+
+		rows, err := database.DB.Query(
+			fmt.Sprintf(`SELECT id, workout_log_id, set_index, reps, weight, duration_seconds, set_type, rpe, tempo
+			 FROM workout_sets
+			 WHERE workout_log_id IN (%s)
+			 ORDER BY workout_log_id, set_index`, strings.Join(placeholders, ", ")),
+			params...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var set models.WorkoutSet
+			if err := rows.Scan(
+				&set.ID, &set.WorkoutLogID, &set.SetIndex, &set.Reps, &set.Weight, &set.DurationSeconds,
+				&set.SetType, &set.RPE, &set.Tempo,
+			); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			setsByLog[set.WorkoutLogID] = append(setsByLog[set.WorkoutLogID], set)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return setsByLog, nil
+}
+
+// attachWorkoutSets loads the sets of each log and fills in the legacy
+// weight_per_set view from them
+func attachWorkoutSets(logs []models.WorkoutLog) error {
+	logIDs := make([]int64, len(logs))
+	for i, log := range logs {
+		logIDs[i] = log.ID
+	}
+
+	setsByLog, err := fetchWorkoutSets(logIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range logs {
+		sets := setsByLog[logs[i].ID]
+		if len(sets) == 0 {
+			continue
+		}
+		logs[i].WorkoutSets = sets
+		logs[i].WeightPerSet = models.WeightPerSetFromWorkoutSets(sets)
+	}
+	return nil
+}
@@ -0,0 +1,717 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gym-app-backend/database"
+	"gym-app-backend/middleware"
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+type WorkoutTemplateResponse struct {
+	Template models.WorkoutTemplate `json:"template"`
+}
+
+type WorkoutTemplatesResponse struct {
+	Templates []models.WorkoutTemplate `json:"templates"`
+}
+
+type TemplateExerciseRequest struct {
+	ExerciseID int64 `json:"exercise_id"`
+	// ExerciseSource is "private" or "public"; empty resolves the user's exercises first
+	ExerciseSource string   `json:"exercise_source"`
+	TargetSets     *int     `json:"target_sets"`
+	TargetReps     *int     `json:"target_reps"`
+	TargetWeight   *float64 `json:"target_weight"`
+	TargetDistance *float64 `json:"target_distance"`
+	TargetDuration *int     `json:"target_duration"`
+	RestTime       *int     `json:"rest_time"`
+	Notes          *string  `json:"notes"`
+}
+
+type CreateWorkoutTemplateRequest struct {
+	Name        string                    `json:"name"`
+	Description *string                   `json:"description"`
+	Exercises   []TemplateExerciseRequest `json:"exercises"`
+}
+
+type UpdateWorkoutTemplateRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	// Exercises replaces the template's exercise list when present
+	Exercises *[]TemplateExerciseRequest `json:"exercises"`
+}
+
+type StartWorkoutTemplateRequest struct {
+	Date string  `json:"date"` // defaults to today
+	Name *string `json:"name"` // defaults to the template name
+}
+
+func fetchWorkoutTemplate(templateID, userID int64) (models.WorkoutTemplate, error) {
+	var template models.WorkoutTemplate
+	err := database.DB.QueryRow(
+		"SELECT id, user_id, name, description, created_at FROM workout_templates WHERE id = ? AND user_id = ?",
+		templateID, userID,
+	).Scan(&template.ID, &template.UserID, &template.Name, &template.Description, &template.CreatedAt)
+	if err != nil {
+		return template, err
+	}
+
+	template.Exercises, err = fetchTemplateExercises(templateID, userID)
+	return template, err
+}
+
+// fetchTemplateExercises loads a template's exercises in order with their names and types
+func fetchTemplateExercises(templateID, userID int64) ([]models.WorkoutTemplateExercise, error) {
+	rows, err := database.DB.Query(
+		`SELECT te.id, te.template_id, te.exercise_id, te.exercise_source, te.position,
+		        te.target_sets, te.target_reps, te.target_weight, te.target_distance, te.target_duration,
+		        te.rest_time, te.notes,
+		        CASE te.exercise_source WHEN 'public' THEN pe.name ELSE e.name END as exercise_name,
+		        CASE te.exercise_source WHEN 'public' THEN pe.exercise_type ELSE e.exercise_type END as exercise_type
+		 FROM workout_template_exercises te
+		 LEFT JOIN exercises e ON te.exercise_id = e.id AND e.user_id = ?
+		 LEFT JOIN public_exercises pe ON te.exercise_id = pe.id
+		 WHERE te.template_id = ?
+		 ORDER BY te.position ASC`,
+		userID, templateID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exercises := []models.WorkoutTemplateExercise{}
+	for rows.Next() {
+		var te models.WorkoutTemplateExercise
+		err := rows.Scan(
+			&te.ID, &te.TemplateID, &te.ExerciseID, &te.ExerciseSource, &te.Position,
+			&te.TargetSets, &te.TargetReps, &te.TargetWeight, &te.TargetDistance, &te.TargetDuration,
+			&te.RestTime, &te.Notes, &te.ExerciseName, &te.ExerciseType,
+		)
+		if err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, te)
+	}
+	return exercises, rows.Err()
+}
+
+// validateTemplateExercises resolves each exercise and checks that targets fit
+// the exercise type. It writes the error response and returns false on failure.
+func validateTemplateExercises(w http.ResponseWriter, userID int64, items []TemplateExerciseRequest) ([]exerciseRef, bool) {
+	refs := make([]exerciseRef, 0, len(items))
+	for _, item := range items {
+		if item.ExerciseSource != "" && item.ExerciseSource != "private" && item.ExerciseSource != "public" {
+			http.Error(w, `{"error":"exercise_source must be private or public"}`, http.StatusBadRequest)
+			return nil, false
+		}
+
+		ref, err := resolveExercise(userID, item.ExerciseID, item.ExerciseSource)
+		if err == sql.ErrNoRows {
+			http.Error(w, `{"error":"Exercise not found"}`, http.StatusNotFound)
+			return nil, false
+		} else if err != nil {
+			fmt.Printf("Validate template exercise error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return nil, false
+		}
+
+		rules := models.RulesForExerciseType(ref.Type)
+		var typeErr string
+		switch {
+		case item.TargetDistance != nil && !rules.Distance:
+			typeErr = "Target distance cannot be used for %s exercises"
+		case item.TargetDuration != nil && !rules.Duration:
+			typeErr = "Target duration cannot be used for %s exercises"
+		case item.TargetWeight != nil && rules.Load == models.LoadNone:
+			typeErr = "Target weight cannot be used for %s exercises"
+		}
+		if typeErr != "" {
+			http.Error(w, fmt.Sprintf(`{"error":%q}`, fmt.Sprintf(typeErr, ref.Type)), http.StatusBadRequest)
+			return nil, false
+		}
+
+		refs = append(refs, ref)
+	}
+	return refs, true
+}
+
+// replaceTemplateExercises stores items as the ordered exercise list of a
+// template, converting targets from the user's units
+func replaceTemplateExercises(tx *sql.Tx, templateID int64, items []TemplateExerciseRequest, refs []exerciseRef, units utils.Units) error {
+	if _, err := tx.Exec("DELETE FROM workout_template_exercises WHERE template_id = ?", templateID); err != nil {
+		return err
+	}
+
+	for i, item := range items {
+		_, err := tx.Exec(
+			`INSERT INTO workout_template_exercises (template_id, exercise_id, exercise_source, position, target_sets, target_reps, target_weight, target_distance, target_duration, rest_time, notes)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			templateID, refs[i].ID, refs[i].Source, i+1, item.TargetSets, item.TargetReps,
+			weightToCanonical(units, item.TargetWeight), distanceToCanonical(units, item.TargetDistance),
+			item.TargetDuration, item.RestTime, item.Notes,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// localizeTemplate converts the targets of a stored template to the user's units
+func localizeTemplate(template *models.WorkoutTemplate, units utils.Units) {
+	for i := range template.Exercises {
+		te := &template.Exercises[i]
+		te.TargetWeight = weightFromCanonical(units, te.TargetWeight)
+		te.TargetDistance = distanceFromCanonical(units, te.TargetDistance)
+	}
+}
+
+// parseWorkoutTemplatePath extracts the template ID and optional action from
+// paths like /api/templates/:id and /api/templates/:id/start
+func parseWorkoutTemplatePath(r *http.Request) (int64, string, error) {
+	parts := strings.SplitN(strings.Trim(r.URL.Path[len("/api/templates/"):], "/"), "/", 2)
+	templateID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(parts) == 2 {
+		return templateID, parts[1], nil
+	}
+	return templateID, "", nil
+}
+
+// GetAllWorkoutTemplates returns all workout templates for the authenticated user
+func GetAllWorkoutTemplates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	rows, err := database.DB.Query(
+		"SELECT id, user_id, name, description, created_at FROM workout_templates WHERE user_id = ? ORDER BY name ASC",
+		userID,
+	)
+	if err != nil {
+		fmt.Printf("Get workout templates error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var templates []models.WorkoutTemplate
+	for rows.Next() {
+		var template models.WorkoutTemplate
+		if err := rows.Scan(&template.ID, &template.UserID, &template.Name, &template.Description, &template.CreatedAt); err != nil {
+			fmt.Printf("Error scanning workout template: %v\n", err)
+			continue
+		}
+		templates = append(templates, template)
+	}
+	rows.Close()
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get workout templates error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	for i := range templates {
+		templates[i].Exercises, err = fetchTemplateExercises(templates[i].ID, userID)
+		if err != nil {
+			fmt.Printf("Get workout templates error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		localizeTemplate(&templates[i], units)
+	}
+
+	response := WorkoutTemplatesResponse{Templates: templates}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetWorkoutTemplateById returns a single workout template by ID
+func GetWorkoutTemplateById(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	templateID, _, err := parseWorkoutTemplatePath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid template ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	template, err := fetchWorkoutTemplate(templateID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Workout template not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Get workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Get workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeTemplate(&template, units)
+
+	response := WorkoutTemplateResponse{Template: template}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateWorkoutTemplate creates a new workout template
+func CreateWorkoutTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+
+	var req CreateWorkoutTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		http.Error(w, `{"error":"Template name is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	refs, ok := validateTemplateExercises(w, userID, req.Exercises)
+	if !ok {
+		return
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Create workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Create workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO workout_templates (user_id, name, description) VALUES (?, ?, ?)",
+		userID, req.Name, req.Description,
+	)
+	if err != nil {
+		fmt.Printf("Create workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	templateID, _ := result.LastInsertId()
+
+	if err := replaceTemplateExercises(tx, templateID, req.Exercises, refs, units); err != nil {
+		fmt.Printf("Create workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Create workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	template, err := fetchWorkoutTemplate(templateID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching created workout template: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeTemplate(&template, units)
+
+	response := WorkoutTemplateResponse{Template: template}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateWorkoutTemplate updates a workout template and optionally replaces its exercises
+func UpdateWorkoutTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	templateID, _, err := parseWorkoutTemplatePath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid template ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Verify template belongs to user
+	var existingID int64
+	err = database.DB.QueryRow(
+		"SELECT id FROM workout_templates WHERE id = ? AND user_id = ?",
+		templateID, userID,
+	).Scan(&existingID)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Workout template not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Update workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	var req UpdateWorkoutTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Build update query dynamically
+	updates := []string{}
+	values := []interface{}{}
+
+	if req.Name != nil {
+		if *req.Name == "" {
+			http.Error(w, `{"error":"Template name is required"}`, http.StatusBadRequest)
+			return
+		}
+		updates = append(updates, "name = ?")
+		values = append(values, *req.Name)
+	}
+	if req.Description != nil {
+		updates = append(updates, "description = ?")
+		values = append(values, *req.Description)
+	}
+
+	var refs []exerciseRef
+	if req.Exercises != nil {
+		var ok bool
+		refs, ok = validateTemplateExercises(w, userID, *req.Exercises)
+		if !ok {
+			return
+		}
+	}
+
+	units, err := fetchUserUnits(userID)
+	if err != nil {
+		fmt.Printf("Update workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Update workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if len(updates) > 0 {
+		values = append(values, templateID, userID)
+		query := fmt.Sprintf("UPDATE workout_templates SET %s WHERE id = ? AND user_id = ?", strings.Join(updates, ", "))
+		if _, err := tx.Exec(query, values...); err != nil {
+			fmt.Printf("Update workout template error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if req.Exercises != nil {
+		if err := replaceTemplateExercises(tx, templateID, *req.Exercises, refs, units); err != nil {
+			fmt.Printf("Update workout template error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Update workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	template, err := fetchWorkoutTemplate(templateID, userID)
+	if err != nil {
+		fmt.Printf("Error fetching updated workout template: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	localizeTemplate(&template, units)
+
+	response := WorkoutTemplateResponse{Template: template}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteWorkoutTemplate deletes a workout template. Logs started from it are
+// kept; templates that a program day still uses cannot be deleted.
+func DeleteWorkoutTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	templateID, _, err := parseWorkoutTemplatePath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid template ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Verify template belongs to user
+	var existingID int64
+	err = database.DB.QueryRow(
+		"SELECT id FROM workout_templates WHERE id = ? AND user_id = ?",
+		templateID, userID,
+	).Scan(&existingID)
+
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Workout template not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Delete workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Delete workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var found int
+	err = tx.QueryRow(
+		`SELECT 1 FROM program_days pd JOIN training_programs tp ON pd.program_id = tp.id
+		 WHERE pd.template_id = ? AND tp.user_id = ? LIMIT 1`,
+		templateID, userID,
+	).Scan(&found)
+	if err == nil {
+		http.Error(w, `{"error":"Workout template is used by a training program; remove it from the program first"}`, http.StatusConflict)
+		return
+	} else if err != sql.ErrNoRows {
+		fmt.Printf("Delete workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM workout_template_exercises WHERE template_id = ?", templateID); err != nil {
+		fmt.Printf("Delete workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.Exec("DELETE FROM workout_templates WHERE id = ? AND user_id = ?", templateID, userID); err != nil {
+		fmt.Printf("Delete workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Delete workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Workout template deleted successfully"})
+}
+
+// StartWorkoutTemplate creates a workout session from a template. Each
+// exercise becomes a log pre-filled with the template targets, falling back to
+// the values of the last time the exercise was logged. The logs stay planned,
+// outside records and progression history, until the user saves them.
+func StartWorkoutTemplate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := middleware.GetUserID(r)
+	templateID, _, err := parseWorkoutTemplatePath(r)
+	if err != nil {
+		http.Error(w, `{"error":"Invalid template ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req StartWorkoutTemplateRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"Invalid request body"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Date == "" {
+		req.Date = time.Now().Format("2006-01-02")
+	}
+
+	template, err := fetchWorkoutTemplate(templateID, userID)
+	if err == sql.ErrNoRows {
+		http.Error(w, `{"error":"Workout template not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Printf("Start workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sessionName := template.Name
+	if req.Name != nil && *req.Name != "" {
+		sessionName = *req.Name
+	}
+
+	// Resolve exercises and look up previous values before opening the transaction
+	prefills := make([]models.WorkoutLog, len(template.Exercises))
+	for i, te := range template.Exercises {
+		if _, err := resolveExercise(userID, te.ExerciseID, te.ExerciseSource); err == sql.ErrNoRows {
+			http.Error(w, fmt.Sprintf(`{"error":"Template exercise %d no longer exists"}`, te.ExerciseID), http.StatusConflict)
+			return
+		} else if err != nil {
+			fmt.Printf("Start workout template error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		last, err := fetchLastWorkoutLog(userID, te.ExerciseID, te.ExerciseSource)
+		if err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Start workout template error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		prefills[i] = prefillTemplateLog(te, last, err == nil)
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		fmt.Printf("Start workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO workout_sessions (user_id, name, date) VALUES (?, ?, ?)",
+		userID, sessionName, req.Date,
+	)
+	if err != nil {
+		fmt.Printf("Start workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	sessionID, _ := result.LastInsertId()
+
+	for i, log := range prefills {
+		// Prefilled values are canonical and carry no pace, so this cannot fail
+		metrics, _ := resolveCardioMetrics(cardioMetrics{Distance: log.Distance, Duration: log.Duration}, nil, utils.CanonicalUnits)
+		result, err := tx.Exec(
+			`INSERT INTO workout_logs (user_id, exercise_id, exercise_source, session_id, session_order, date, sets, reps, weight, rest_time, distance, duration, pace, notes, planned)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)`,
+			userID, log.ExerciseID, log.ExerciseSource, sessionID, i+1, req.Date, log.Sets, log.Reps, log.Weight,
+			log.RestTime, log.Distance, log.Duration, metrics.Pace, log.Notes,
+		)
+		if err != nil {
+			fmt.Printf("Start workout template error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+
+		logID, _ := result.LastInsertId()
+		if err := replaceWorkoutSets(tx, logID, log.WorkoutSets); err != nil {
+			fmt.Printf("Start workout template error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fmt.Printf("Start workout template error: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	session, err := fetchWorkoutSession(sessionID, userID)
+	if err == nil {
+		session.Logs, err = fetchLocalizedSessionLogs(sessionID, userID)
+	}
+	if err != nil {
+		fmt.Printf("Error fetching started workout session: %v\n", err)
+		http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := WorkoutSessionResponse{Session: session}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// prefillTemplateLog builds the log for a template exercise. Targets win over
+// the last logged values; the last sets are only reused when the template
+// does not prescribe a weight.
+func prefillTemplateLog(te models.WorkoutTemplateExercise, last models.WorkoutLog, hasLast bool) models.WorkoutLog {
+	log := models.WorkoutLog{
+		ExerciseID:     te.ExerciseID,
+		ExerciseSource: te.ExerciseSource,
+		Sets:           te.TargetSets,
+		Reps:           te.TargetReps,
+		Weight:         te.TargetWeight,
+		Distance:       te.TargetDistance,
+		Duration:       te.TargetDuration,
+		RestTime:       te.RestTime,
+		Notes:          te.Notes,
+	}
+	if !hasLast {
+		return log
+	}
+
+	if log.Sets == nil {
+		log.Sets = last.Sets
+	}
+	if log.Reps == nil {
+		log.Reps = last.Reps
+	}
+	if log.Weight == nil {
+		log.Weight = last.Weight
+		log.WorkoutSets = last.WorkoutSets
+	}
+	if log.Distance == nil {
+		log.Distance = last.Distance
+	}
+	if log.Duration == nil {
+		log.Duration = last.Duration
+	}
+	if log.RestTime == nil {
+		log.RestTime = last.RestTime
+	}
+	return log
+}
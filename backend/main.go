@@ -19,6 +19,11 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Build personal records for logs recorded before records were tracked
+	if err := services.BackfillPersonalRecords(); err != nil {
+		log.Fatalf("Failed to backfill personal records: %v", err)
+	}
+
 	// Initialize email service
 	if err := services.InitializeEmailService(); err != nil {
 		log.Printf("Warning: Failed to initialize email service: %v (email features will be disabled)", err)
@@ -52,13 +57,66 @@ func main() {
 	mux.HandleFunc("/api/auth/reset-password", handlers.ResetPassword)
 	mux.HandleFunc("/api/auth/setup-totp", middleware.RequireAuth(http.HandlerFunc(handlers.SetupTOTP)).ServeHTTP)
 	mux.HandleFunc("/api/auth/verify-totp", handlers.VerifyTOTP)
+	mux.HandleFunc("/api/auth/preferences", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetPreferences(w, r)
+		case http.MethodPut:
+			handlers.UpdatePreferences(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Account archive routes
+	mux.HandleFunc("/api/account/export", middleware.RequireAuth(http.HandlerFunc(handlers.ExportAccount)).ServeHTTP)
+	mux.HandleFunc("/api/account/import", middleware.RequireAuth(http.HandlerFunc(handlers.ImportAccount)).ServeHTTP)
+
+	// Personal records routes
+	mux.HandleFunc("/api/records", middleware.RequireAuth(http.HandlerFunc(handlers.GetPersonalRecords)).ServeHTTP)
+
+	// Analytics routes
+	mux.HandleFunc("/api/analytics/muscle-volume", middleware.RequireAuth(http.HandlerFunc(handlers.GetMuscleVolume)).ServeHTTP)
+	mux.HandleFunc("/api/analytics/calendar", middleware.RequireAuth(http.HandlerFunc(handlers.GetTrainingCalendar)).ServeHTTP)
 
 	// Reports routes
 	mux.HandleFunc("/api/reports/weekly", middleware.RequireAuth(http.HandlerFunc(handlers.SendWeeklyReport)).ServeHTTP)
 
-	// Public exercise routes (no auth required)
+	// Public exercise routes (no auth required, except copying)
 	mux.HandleFunc("/api/public-exercises", handlers.GetAllPublicExercises)
-	mux.HandleFunc("/api/public-exercises/", handlers.GetPublicExerciseById)
+	mux.HandleFunc("/api/public-exercises/", func(w http.ResponseWriter, r *http.Request) {
+		// Copying into the personal library needs a user: /api/public-exercises/:id/copy
+		if strings.HasSuffix(r.URL.Path, "/copy") {
+			middleware.RequireAuth(http.HandlerFunc(handlers.CopyPublicExercise)).ServeHTTP(w, r)
+			return
+		}
+		handlers.GetPublicExerciseById(w, r)
+	})
+
+	// Admin routes for curating the public exercise library (admin role required)
+	mux.HandleFunc("/api/admin/public-exercises", middleware.RequireAdmin(http.HandlerFunc(handlers.CreatePublicExercise)).ServeHTTP)
+	mux.HandleFunc("/api/admin/public-exercises/", middleware.RequireAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if strings.HasSuffix(path, "/deprecate") {
+			handlers.DeprecatePublicExercise(w, r)
+			return
+		}
+		if strings.HasSuffix(path, "/restore") {
+			handlers.RestorePublicExercise(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			handlers.UpdatePublicExercise(w, r)
+		case http.MethodDelete:
+			handlers.DeletePublicExercise(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Exercise taxonomy routes (no auth required)
+	mux.HandleFunc("/api/taxonomy", handlers.GetTaxonomy)
 
 	// Exercise routes - exact match for list/create (with auth)
 	mux.HandleFunc("/api/exercises", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -77,6 +135,39 @@ func main() {
 		path := r.URL.Path
 		if strings.HasSuffix(path, "/progress") {
 			handlers.GetExerciseProgress(w, r)
+		} else if strings.Contains(path, "/custom-fields") {
+			// Handle /api/exercises/:id/custom-fields and /api/exercises/:id/custom-fields/:fieldId
+			if strings.HasSuffix(strings.TrimSuffix(path, "/"), "/custom-fields") {
+				switch r.Method {
+				case http.MethodGet:
+					handlers.GetCustomFields(w, r)
+				case http.MethodPost:
+					handlers.CreateCustomField(w, r)
+				default:
+					http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+				}
+				return
+			}
+			switch r.Method {
+			case http.MethodPut:
+				handlers.UpdateCustomField(w, r)
+			case http.MethodDelete:
+				handlers.DeleteCustomField(w, r)
+			default:
+				http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+			}
+		} else if strings.HasSuffix(path, "/progression") {
+			// Handle /api/exercises/:id/progression
+			switch r.Method {
+			case http.MethodGet:
+				handlers.GetProgressionRule(w, r)
+			case http.MethodPut:
+				handlers.UpdateProgressionRule(w, r)
+			case http.MethodDelete:
+				handlers.DeleteProgressionRule(w, r)
+			default:
+				http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+			}
 		} else {
 			// Handle /api/exercises/:id
 			switch r.Method {
@@ -104,10 +195,22 @@ func main() {
 		}
 	})).ServeHTTP)
 
-	// Workout log routes with path - handle /api/workout-logs/exercise/:id/last and /api/workout-logs/:id (with auth)
+	// Workout log routes with path - handle /api/workout-logs/import[/csv], /api/workout-logs/exercise/:id/last and /api/workout-logs/:id (with auth)
 	mux.HandleFunc("/api/workout-logs/", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
 
+		// Activity file upload: /api/workout-logs/import
+		if path == "/api/workout-logs/import" {
+			handlers.ImportWorkoutLog(w, r)
+			return
+		}
+
+		// CSV history upload: /api/workout-logs/import/csv
+		if path == "/api/workout-logs/import/csv" {
+			handlers.ImportWorkoutCSV(w, r)
+			return
+		}
+
 		// Check if it's the special route /api/workout-logs/exercise/:id/last
 		if strings.Contains(path, "/exercise/") && strings.HasSuffix(path, "/last") {
 			handlers.GetLastWorkoutValues(w, r)
@@ -127,6 +230,194 @@ func main() {
 		}
 	})).ServeHTTP)
 
+	// Workout session routes - exact match for list/create (with auth)
+	mux.HandleFunc("/api/workouts", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetAllWorkoutSessions(w, r)
+		case http.MethodPost:
+			handlers.CreateWorkoutSession(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Workout session routes with ID (with auth)
+	mux.HandleFunc("/api/workouts/", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Path, "/groups") {
+			// Handle /api/workouts/:id/groups and /api/workouts/:id/groups/:groupId
+			if strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/groups") {
+				switch r.Method {
+				case http.MethodGet:
+					handlers.GetExerciseGroups(w, r)
+				case http.MethodPost:
+					handlers.CreateExerciseGroup(w, r)
+				default:
+					http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+				}
+				return
+			}
+			switch r.Method {
+			case http.MethodPut:
+				handlers.UpdateExerciseGroup(w, r)
+			case http.MethodDelete:
+				handlers.DeleteExerciseGroup(w, r)
+			default:
+				http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+			}
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetWorkoutSessionById(w, r)
+		case http.MethodPut:
+			handlers.UpdateWorkoutSession(w, r)
+		case http.MethodDelete:
+			handlers.DeleteWorkoutSession(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Body metric routes - exact match for list/create (with auth)
+	mux.HandleFunc("/api/body-metrics", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetAllBodyMetrics(w, r)
+		case http.MethodPost:
+			handlers.CreateBodyMetric(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Body metric routes with path - handle /api/body-metrics/progress and /api/body-metrics/:id (with auth)
+	mux.HandleFunc("/api/body-metrics/", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.TrimSuffix(r.URL.Path, "/") == "/api/body-metrics/progress" {
+			handlers.GetBodyMetricProgress(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetBodyMetricById(w, r)
+		case http.MethodPut:
+			handlers.UpdateBodyMetric(w, r)
+		case http.MethodDelete:
+			handlers.DeleteBodyMetric(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Goal routes - exact match for list/create (with auth)
+	mux.HandleFunc("/api/goals", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetGoals(w, r)
+		case http.MethodPost:
+			handlers.CreateGoal(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Goal routes with ID (with auth)
+	mux.HandleFunc("/api/goals/", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetGoalById(w, r)
+		case http.MethodPut:
+			handlers.UpdateGoal(w, r)
+		case http.MethodDelete:
+			handlers.DeleteGoal(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Workout template routes - exact match for list/create (with auth)
+	mux.HandleFunc("/api/templates", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetAllWorkoutTemplates(w, r)
+		case http.MethodPost:
+			handlers.CreateWorkoutTemplate(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Workout template routes with path - handle /api/templates/:id/start and /api/templates/:id (with auth)
+	mux.HandleFunc("/api/templates/", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/start") {
+			handlers.StartWorkoutTemplate(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetWorkoutTemplateById(w, r)
+		case http.MethodPut:
+			handlers.UpdateWorkoutTemplate(w, r)
+		case http.MethodDelete:
+			handlers.DeleteWorkoutTemplate(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Program routes - exact match for list/create (with auth)
+	mux.HandleFunc("/api/programs", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetAllPrograms(w, r)
+		case http.MethodPost:
+			handlers.CreateProgram(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Program routes with path - handle /api/programs/today, /api/programs/:id/enroll and /api/programs/:id (with auth)
+	mux.HandleFunc("/api/programs/", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+
+		if strings.TrimSuffix(path, "/") == "/api/programs/today" {
+			handlers.GetScheduledToday(w, r)
+			return
+		}
+
+		if strings.HasSuffix(path, "/enroll") {
+			handlers.EnrollInProgram(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetProgramById(w, r)
+		case http.MethodPut:
+			handlers.UpdateProgram(w, r)
+		case http.MethodDelete:
+			handlers.DeleteProgram(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
+	// Program enrollment routes (with auth)
+	mux.HandleFunc("/api/program-enrollments", middleware.RequireAuth(http.HandlerFunc(handlers.GetAllProgramEnrollments)).ServeHTTP)
+	mux.HandleFunc("/api/program-enrollments/", middleware.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			handlers.GetProgramEnrollmentById(w, r)
+		case http.MethodDelete:
+			handlers.DeleteProgramEnrollment(w, r)
+		default:
+			http.Error(w, `{"error":"Method not allowed"}`, http.StatusMethodNotAllowed)
+		}
+	})).ServeHTTP)
+
 	// Apply middleware
 	handler := middleware.Logging(middleware.CORS(mux))
 
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"gym-app-backend/database"
+	"gym-app-backend/models"
+)
+
+// RequireAdmin middleware verifies that the user is authenticated and has the
+// admin role. The role is read on every request so revoking it takes effect
+// immediately.
+func RequireAdmin(next http.Handler) http.Handler {
+	return RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var role string
+		err := database.DB.QueryRow("SELECT role FROM users WHERE id = ?", GetUserID(r)).Scan(&role)
+		if err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Admin check error: %v\n", err)
+			http.Error(w, `{"error":"Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if role != models.RoleAdmin {
+			http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// AccountArchiveFormat identifies account archives. AccountArchiveVersion is
+// bumped whenever the layout changes in a way older importers cannot read.
+const (
+	AccountArchiveFormat  = "gym-app-account"
+	AccountArchiveVersion = 1
+)
+
+// AccountArchive is a full export of a user's data. Tables hold raw rows keyed
+// by column name, in canonical units, with the IDs of the exporting instance.
+type AccountArchive struct {
+	Format          string                              `json:"format"`
+	Version         int                                 `json:"version"`
+	ExportedAt      time.Time                           `json:"exported_at"`
+	Profile         ArchiveProfile                      `json:"profile"`
+	Settings        ArchiveSettings                     `json:"settings"`
+	PublicExercises []ArchivePublicExercise             `json:"public_exercises"` // public exercises the rows refer to
+	Tables          map[string][]map[string]interface{} `json:"tables"`
+}
+
+type ArchiveProfile struct {
+	Username  string    `json:"username"`
+	Email     *string   `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ArchiveSettings struct {
+	WeightUnit          string `json:"weight_unit"`
+	DistanceUnit        string `json:"distance_unit"`
+	WeeklyReportEnabled bool   `json:"weekly_report_enabled"`
+}
+
+// ArchivePublicExercise identifies a public exercise by name, since public
+// exercise IDs differ between instances
+type ArchivePublicExercise struct {
+	ID           int64  `json:"id"`
+	Slug         string `json:"slug,omitempty"`
+	Name         string `json:"name"`
+	ExerciseType string `json:"exercise_type"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// BodyMetric is one dated entry of a user's body measurements. Every value is
+// optional, so a morning weigh-in and a monthly tape measure can be logged
+// separately. Bodyweight is stored in kilograms and measurements in
+// centimeters.
+type BodyMetric struct {
+	ID             int64            `json:"id"`
+	UserID         int64            `json:"user_id"`
+	Date           string           `json:"date"`
+	Bodyweight     *float64         `json:"bodyweight"`
+	BodyFatPercent *float64         `json:"body_fat_percent"`
+	Measurements   BodyMeasurements `json:"measurements"`
+	Notes          *string          `json:"notes"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+}
+
+// BodyMeasurements are circumferences taken around a body part
+type BodyMeasurements struct {
+	Neck    *float64 `json:"neck"`
+	Chest   *float64 `json:"chest"`
+	Waist   *float64 `json:"waist"`
+	Hips    *float64 `json:"hips"`
+	Arm     *float64 `json:"arm"`
+	Forearm *float64 `json:"forearm"`
+	Thigh   *float64 `json:"thigh"`
+	Calf    *float64 `json:"calf"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Custom field types
+const (
+	CustomFieldNumber = "number"
+	CustomFieldText   = "text"
+	CustomFieldEnum   = "enum" // text limited to the field's options
+)
+
+// CustomField is a value a user records on an exercise beyond the standard
+// log columns, such as treadmill incline or band color
+type CustomField struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"user_id"`
+	ExerciseID     int64     `json:"exercise_id"`
+	ExerciseSource string    `json:"exercise_source"` // "private" or "public"
+	Name           string    `json:"name"`
+	FieldType      string    `json:"field_type"`
+	Unit           *string   `json:"unit"`              // free-form label, values are not converted
+	Options        []string  `json:"options,omitempty"` // allowed values of enum fields
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CustomMetric is the value a workout log recorded for a custom field. Value
+// is a float64 for number fields and a string otherwise.
+type CustomMetric struct {
+	FieldID   int64       `json:"field_id"`
+	Name      string      `json:"name"`
+	FieldType string      `json:"field_type"`
+	Unit      *string     `json:"unit"`
+	Value     interface{} `json:"value"`
+}
+
+// IsValidCustomFieldType reports whether t is a known custom field type
+func IsValidCustomFieldType(t string) bool {
+	return t == CustomFieldNumber || t == CustomFieldText || t == CustomFieldEnum
+}
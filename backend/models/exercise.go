@@ -14,5 +14,11 @@ type Exercise struct {
 	VideoLink    *string   `json:"video_link"`
 	ImageLink    *string   `json:"image_link"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// SourcePublicExerciseID is the public exercise this one was copied from
+	SourcePublicExerciseID *int64 `json:"source_public_exercise_id"`
+
+	// Muscles, equipment and movement patterns from the taxonomy
+	ExerciseTaxonomy
 }
 
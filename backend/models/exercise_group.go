@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Exercise group types
+const (
+	GroupSuperset = "superset"  // exercises alternated set by set
+	GroupCircuit  = "circuit"   // exercises performed in sequence for rounds, with shared rest
+	GroupGiantSet = "giant_set" // three or more exercises back to back
+)
+
+// ExerciseGroup marks workout logs of one session that were performed
+// together as a superset, circuit or giant set
+type ExerciseGroup struct {
+	ID                int64     `json:"id"`
+	UserID            int64     `json:"user_id"`
+	SessionID         int64     `json:"session_id"`
+	GroupType         string    `json:"group_type"`
+	Rounds            *int      `json:"rounds"`
+	RestBetweenRounds *int      `json:"rest_between_rounds"` // seconds
+	Notes             *string   `json:"notes"`
+	LogIDs            []int64   `json:"log_ids"` // member logs in session order
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// IsValidGroupType reports whether t is a known exercise group type
+func IsValidGroupType(t string) bool {
+	return t == GroupSuperset || t == GroupCircuit || t == GroupGiantSet
+}
+
+// MinGroupSize returns the fewest logs a group of the type can hold
+func MinGroupSize(groupType string) int {
+	if groupType == GroupGiantSet {
+		return 3
+	}
+	return 2
+}
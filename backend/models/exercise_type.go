@@ -0,0 +1,103 @@
+package models
+
+// Exercise types
+const (
+	ExerciseTypeStrength    = "strength"    // external load for reps
+	ExerciseTypeCardio      = "cardio"      // distance and duration
+	ExerciseTypeBodyweight  = "bodyweight"  // reps, optionally with added load, e.g. weighted pull-ups
+	ExerciseTypeTimedHold   = "timed_hold"  // sets held for time, e.g. planks
+	ExerciseTypeAssisted    = "assisted"    // reps with a counterweight taking load off, e.g. assisted dips
+	ExerciseTypeFlexibility = "flexibility" // stretches and mobility work held for time or reps
+	ExerciseTypeMixed       = "mixed"       // any combination, e.g. loaded carries
+)
+
+// ExerciseTypes lists every exercise type
+var ExerciseTypes = []string{
+	ExerciseTypeStrength, ExerciseTypeCardio, ExerciseTypeBodyweight, ExerciseTypeTimedHold,
+	ExerciseTypeAssisted, ExerciseTypeFlexibility, ExerciseTypeMixed,
+}
+
+// How the weight of a log or set is read
+const (
+	LoadNone       = ""           // weight cannot be logged
+	LoadAbsolute   = "absolute"   // the weight lifted
+	LoadAdded      = "added"      // weight added on top of bodyweight
+	LoadAssistance = "assistance" // weight taken off bodyweight
+)
+
+// ExerciseTypeRules are the values a type of exercise is logged with
+type ExerciseTypeRules struct {
+	Load     string // how weights are read, LoadNone when they are not allowed
+	Reps     bool   // reps per set
+	Hold     bool   // seconds held per set
+	Distance bool   // distance, pace, laps and elevation gain
+	Duration bool   // total duration in minutes
+}
+
+// Sets reports whether the type is logged in sets
+func (r ExerciseTypeRules) Sets() bool {
+	return r.Load != LoadNone || r.Reps || r.Hold
+}
+
+// UsesBodyweight reports whether the load of a set depends on the lifter's bodyweight
+func (r ExerciseTypeRules) UsesBodyweight() bool {
+	return r.Load == LoadAdded || r.Load == LoadAssistance
+}
+
+// IsValidExerciseType reports whether t is a known exercise type
+func IsValidExerciseType(t string) bool {
+	for _, known := range ExerciseTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// RulesForExerciseType returns the rules of an exercise type. Unknown types
+// are treated as strength, the column default.
+func RulesForExerciseType(t string) ExerciseTypeRules {
+	switch t {
+	case ExerciseTypeCardio:
+		return ExerciseTypeRules{Distance: true, Duration: true}
+	case ExerciseTypeBodyweight:
+		return ExerciseTypeRules{Load: LoadAdded, Reps: true}
+	case ExerciseTypeTimedHold:
+		return ExerciseTypeRules{Load: LoadAdded, Hold: true}
+	case ExerciseTypeAssisted:
+		return ExerciseTypeRules{Load: LoadAssistance, Reps: true}
+	case ExerciseTypeFlexibility:
+		return ExerciseTypeRules{Reps: true, Hold: true, Duration: true}
+	case ExerciseTypeMixed:
+		return ExerciseTypeRules{Load: LoadAbsolute, Reps: true, Hold: true, Distance: true, Duration: true}
+	}
+	return ExerciseTypeRules{Load: LoadAbsolute, Reps: true}
+}
+
+// EffectiveLoad returns the load actually moved in a set: the weight itself,
+// or bodyweight adjusted by it. ok is false when the load depends on an
+// unknown bodyweight or the type carries no load.
+func (r ExerciseTypeRules) EffectiveLoad(weight *float64, bodyweight *float64) (load float64, ok bool) {
+	var w float64
+	if weight != nil {
+		w = *weight
+	}
+	switch r.Load {
+	case LoadAbsolute:
+		return w, weight != nil
+	case LoadAdded:
+		if bodyweight == nil {
+			return 0, false
+		}
+		return *bodyweight + w, true
+	case LoadAssistance:
+		if bodyweight == nil {
+			return 0, false
+		}
+		if w > *bodyweight {
+			return 0, true
+		}
+		return *bodyweight - w, true
+	}
+	return 0, false
+}
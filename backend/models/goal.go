@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Goal types
+const (
+	GoalWeight       = "weight"        // lift a weight for at least target_reps reps
+	GoalDistanceTime = "distance_time" // cover a distance within a time
+	GoalWorkoutCount = "workout_count" // train on a number of days
+)
+
+// Goal statuses
+const (
+	GoalAchieved = "achieved"
+	GoalOnTrack  = "on_track"
+	GoalBehind   = "behind"
+	GoalMissed   = "missed" // the deadline passed without achieving the goal
+)
+
+// Goal is a target a user works towards by a deadline, either on one
+// exercise or, for workout counts, across all training. Only logs dated from
+// StartDate to Deadline count towards it.
+type Goal struct {
+	ID             int64   `json:"id"`
+	UserID         int64   `json:"user_id"`
+	ExerciseID     *int64  `json:"exercise_id"`
+	ExerciseSource *string `json:"exercise_source"` // "private" or "public", nil for goals across all exercises
+	ExerciseName   *string `json:"exercise_name,omitempty"`
+	GoalType       string  `json:"goal_type"`
+	// TargetValue is the weight of weight goals, the distance of distance_time
+	// goals and the number of training days of workout_count goals
+	TargetValue    float64      `json:"target_value"`
+	TargetReps     *int         `json:"target_reps"`     // weight goals
+	TargetDuration *int         `json:"target_duration"` // minutes, distance_time goals
+	StartDate      string       `json:"start_date"`
+	Deadline       string       `json:"deadline"`
+	Notes          *string      `json:"notes"`
+	AchievedOn     *string      `json:"achieved_on"`
+	AchievedLogID  *int64       `json:"achieved_log_id"` // first log that met the target
+	Progress       GoalProgress `json:"progress"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// GoalProgress is how far a goal has come. CurrentValue is the heaviest
+// qualifying weight, the fastest time over the distance in minutes, or the
+// training days so far; BaselineValue is the best before the goal started.
+type GoalProgress struct {
+	CurrentValue    *float64 `json:"current_value"`
+	BaselineValue   *float64 `json:"baseline_value"`
+	Percent         float64  `json:"percent"`          // share of the way from the baseline to the target
+	ExpectedPercent float64  `json:"expected_percent"` // share of the time to the deadline that has passed
+	Status          string   `json:"status"`
+}
+
+// IsValidGoalType reports whether t is a known goal type
+func IsValidGoalType(t string) bool {
+	return t == GoalWeight || t == GoalDistanceTime || t == GoalWorkoutCount
+}
@@ -0,0 +1,28 @@
+package models
+
+// Personal record types
+const (
+	RecordMaxWeight    = "max_weight"    // heaviest weight lifted in a working set
+	RecordEstimated1RM = "estimated_1rm" // best estimated one-rep max
+	RecordMaxReps      = "max_reps"      // most reps at a given weight
+	RecordMaxDistance  = "max_distance"  // longest distance in a single log
+	RecordBestPace     = "best_pace"     // fastest pace, lower is better
+	RecordLongestHold  = "longest_hold"  // longest set held, in seconds
+)
+
+// PersonalRecord is a record set by a workout log. Records of the same type
+// (and, for max_reps, the same weight) form a history; the latest is current.
+type PersonalRecord struct {
+	ID             int64    `json:"id"`
+	UserID         int64    `json:"user_id"`
+	ExerciseID     int64    `json:"exercise_id"`
+	ExerciseSource string   `json:"exercise_source"` // "private" or "public"
+	ExerciseName   *string  `json:"exercise_name,omitempty"`
+	RecordType     string   `json:"record_type"`
+	Value          float64  `json:"value"`
+	Weight         *float64 `json:"weight"` // logged weight of the set behind a record; the full load for estimated_1rm
+	Reps           *int     `json:"reps"`   // reps of the set behind a strength record
+	PreviousValue  *float64 `json:"previous_value"`
+	WorkoutLogID   int64    `json:"workout_log_id"`
+	AchievedOn     string   `json:"achieved_on"`
+}
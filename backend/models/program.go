@@ -0,0 +1,94 @@
+package models
+
+import "time"
+
+// Program is a multi-week training plan made of scheduled days
+type Program struct {
+	ID            int64        `json:"id"`
+	UserID        int64        `json:"user_id"`
+	Name          string       `json:"name"`
+	Description   *string      `json:"description"`
+	DurationWeeks int          `json:"duration_weeks"`
+	Days          []ProgramDay `json:"days"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// ProgramDay is one training day of a program. A day without a week repeats
+// every week of the program.
+type ProgramDay struct {
+	ID            int64                 `json:"id"`
+	ProgramID     int64                 `json:"program_id"`
+	Week          *int                  `json:"week"`
+	Day           int                   `json:"day"` // 1-7, offset within the program week
+	Name          *string               `json:"name"`
+	TemplateID    *int64                `json:"template_id"`
+	Prescriptions []ProgramPrescription `json:"prescriptions"`
+}
+
+// ProgramPrescription sets the targets of one exercise on a program day.
+// Method "percentage" prescribes a percentage of the enrollment's training max;
+// method "increment" starts at the base values and adds the increments every week.
+type ProgramPrescription struct {
+	ID                int64    `json:"id"`
+	ProgramDayID      int64    `json:"program_day_id"`
+	ExerciseID        int64    `json:"exercise_id"`
+	ExerciseSource    string   `json:"exercise_source"`
+	Method            string   `json:"method"`
+	Sets              *int     `json:"sets"`
+	Reps              *int     `json:"reps"`
+	Percentage        *float64 `json:"percentage"`
+	BaseWeight        *float64 `json:"base_weight"`
+	WeightIncrement   *float64 `json:"weight_increment"`
+	BaseDistance      *float64 `json:"base_distance"`
+	DistanceIncrement *float64 `json:"distance_increment"`
+	BaseDuration      *int     `json:"base_duration"`
+	DurationIncrement *int     `json:"duration_increment"`
+}
+
+// ProgramEnrollment is a user following a program from a start date
+type ProgramEnrollment struct {
+	ID            int64                `json:"id"`
+	UserID        int64                `json:"user_id"`
+	ProgramID     int64                `json:"program_id"`
+	ProgramName   *string              `json:"program_name,omitempty"`
+	StartDate     string               `json:"start_date"`
+	Status        string               `json:"status"` // active, completed
+	TrainingMaxes []ProgramTrainingMax `json:"training_maxes"`
+	CreatedAt     time.Time            `json:"created_at"`
+}
+
+// ProgramTrainingMax is the reference weight percentage prescriptions are based on
+type ProgramTrainingMax struct {
+	ExerciseID     int64   `json:"exercise_id"`
+	ExerciseSource string  `json:"exercise_source"`
+	Weight         float64 `json:"weight"`
+}
+
+// ScheduledProgramDay is a program day placed on the calendar of an enrollment
+type ScheduledProgramDay struct {
+	EnrollmentID       int64               `json:"enrollment_id"`
+	ProgramID          int64               `json:"program_id"`
+	ProgramDayID       int64               `json:"program_day_id"`
+	Date               string              `json:"date"`
+	Week               int                 `json:"week"`
+	Day                int                 `json:"day"`
+	Name               *string             `json:"name"`
+	TemplateID         *int64              `json:"template_id"`
+	Exercises          []ScheduledExercise `json:"exercises"`
+	CompletedExercises int                 `json:"completed_exercises"`
+	Completed          bool                `json:"completed"`
+}
+
+// ScheduledExercise is an exercise of a scheduled day with its computed targets
+type ScheduledExercise struct {
+	ExerciseID     int64    `json:"exercise_id"`
+	ExerciseSource string   `json:"exercise_source"`
+	ExerciseName   *string  `json:"exercise_name,omitempty"`
+	ExerciseType   *string  `json:"exercise_type,omitempty"`
+	Sets           *int     `json:"sets"`
+	Reps           *int     `json:"reps"`
+	Weight         *float64 `json:"weight"`
+	Distance       *float64 `json:"distance"`
+	Duration       *int     `json:"duration"`
+	Completed      bool     `json:"completed"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Progression methods
+const (
+	// ProgressionDouble adds reps within a range and only adds load once
+	// every working set reaches the top of the range
+	ProgressionDouble = "double_progression"
+	// ProgressionFixed adds a fixed increment whenever the target reps are hit
+	ProgressionFixed = "fixed_increment"
+)
+
+// ProgressionRule configures how the next prescription of an exercise is suggested
+type ProgressionRule struct {
+	ID                int64     `json:"id"`
+	UserID            int64     `json:"user_id"`
+	ExerciseID        int64     `json:"exercise_id"`
+	ExerciseSource    string    `json:"exercise_source"` // "private" or "public"
+	Method            string    `json:"method"`
+	MinReps           *int      `json:"min_reps"`    // bottom of the rep range (double progression)
+	MaxReps           *int      `json:"max_reps"`    // top of the rep range (double progression)
+	TargetReps        *int      `json:"target_reps"` // reps per set to hit (fixed increment)
+	Increment         *float64  `json:"increment"`
+	HoldOnMissedReps  bool      `json:"hold_on_missed_reps"` // always true for double progression
+	DeloadAfterMisses *int      `json:"deload_after_misses"` // consecutive missed sessions before a deload
+	DeloadPercent     *float64  `json:"deload_percent"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// IsValidProgressionMethod reports whether method is a known progression method
+func IsValidProgressionMethod(method string) bool {
+	return method == ProgressionDouble || method == ProgressionFixed
+}
@@ -4,6 +4,7 @@ import "time"
 
 type PublicExercise struct {
 	ID           int64     `json:"id"`
+	Slug         *string   `json:"slug"` // set for exercises shipped in the library data file
 	Name         string    `json:"name"`
 	ExerciseType string    `json:"exercise_type"`
 	MuscleGroup  *string   `json:"muscle_group"`
@@ -13,5 +14,14 @@ type PublicExercise struct {
 	VideoLink    *string   `json:"video_link"`
 	ImageLink    *string   `json:"image_link"`
 	CreatedAt    time.Time `json:"created_at"`
+
+	// Deprecated exercises are hidden from the library but still resolve for
+	// the logs, templates and goals that use them. ReplacedByID points users
+	// at the exercise to use instead.
+	DeprecatedAt *time.Time `json:"deprecated_at"`
+	ReplacedByID *int64     `json:"replaced_by_id"`
+
+	// Muscles, equipment and movement patterns from the taxonomy
+	ExerciseTaxonomy
 }
 
@@ -0,0 +1,374 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Roles of a muscle in an exercise
+const (
+	MuscleRolePrimary   = "primary"   // the prime movers
+	MuscleRoleSecondary = "secondary" // assisting muscles and stabilizers
+)
+
+// Muscle regions
+const (
+	RegionUpper    = "upper"
+	RegionCore     = "core"
+	RegionLower    = "lower"
+	RegionFullBody = "full_body"
+)
+
+// Muscle is an entry of the muscle taxonomy
+type Muscle struct {
+	Slug   string `json:"slug"`
+	Name   string `json:"name"`
+	Region string `json:"region"`
+}
+
+// TaxonomyItem is an entry of the equipment or movement pattern taxonomy
+type TaxonomyItem struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// ExerciseMuscle is a muscle an exercise trains
+type ExerciseMuscle struct {
+	Slug   string `json:"slug"`
+	Name   string `json:"name"`
+	Region string `json:"region"`
+	Role   string `json:"role"`
+}
+
+// ExerciseTaxonomy is the muscles, equipment and movement patterns an
+// exercise is linked to. Muscles are listed primary first.
+type ExerciseTaxonomy struct {
+	Muscles          []ExerciseMuscle `json:"muscles"`
+	EquipmentItems   []TaxonomyItem   `json:"equipment_items"`
+	MovementPatterns []TaxonomyItem   `json:"movement_patterns"`
+}
+
+// MuscleCatalog lists every muscle of the taxonomy
+var MuscleCatalog = []Muscle{
+	{Slug: "chest", Name: "Chest", Region: RegionUpper},
+	{Slug: "shoulders", Name: "Shoulders", Region: RegionUpper},
+	{Slug: "rear_delts", Name: "Rear Delts", Region: RegionUpper},
+	{Slug: "triceps", Name: "Triceps", Region: RegionUpper},
+	{Slug: "biceps", Name: "Biceps", Region: RegionUpper},
+	{Slug: "forearms", Name: "Forearms", Region: RegionUpper},
+	{Slug: "lats", Name: "Lats", Region: RegionUpper},
+	{Slug: "upper_back", Name: "Upper Back", Region: RegionUpper},
+	{Slug: "traps", Name: "Traps", Region: RegionUpper},
+	{Slug: "lower_back", Name: "Lower Back", Region: RegionCore},
+	{Slug: "abs", Name: "Abs", Region: RegionCore},
+	{Slug: "obliques", Name: "Obliques", Region: RegionCore},
+	{Slug: "quadriceps", Name: "Quadriceps", Region: RegionLower},
+	{Slug: "hamstrings", Name: "Hamstrings", Region: RegionLower},
+	{Slug: "glutes", Name: "Glutes", Region: RegionLower},
+	{Slug: "calves", Name: "Calves", Region: RegionLower},
+	{Slug: "adductors", Name: "Adductors", Region: RegionLower},
+	{Slug: "abductors", Name: "Abductors", Region: RegionLower},
+	{Slug: "hip_flexors", Name: "Hip Flexors", Region: RegionLower},
+	{Slug: "full_body", Name: "Full Body", Region: RegionFullBody},
+}
+
+// EquipmentCatalog lists every piece of equipment of the taxonomy
+var EquipmentCatalog = []TaxonomyItem{
+	{Slug: "barbell", Name: "Barbell"},
+	{Slug: "ez_bar", Name: "EZ Bar"},
+	{Slug: "dumbbell", Name: "Dumbbell"},
+	{Slug: "kettlebell", Name: "Kettlebell"},
+	{Slug: "bench", Name: "Bench"},
+	{Slug: "cable", Name: "Cable"},
+	{Slug: "machine", Name: "Machine"},
+	{Slug: "smith_machine", Name: "Smith Machine"},
+	{Slug: "pull_up_bar", Name: "Pull-up Bar"},
+	{Slug: "parallel_bars", Name: "Parallel Bars"},
+	{Slug: "resistance_band", Name: "Resistance Band"},
+	{Slug: "medicine_ball", Name: "Medicine Ball"},
+	{Slug: "jump_rope", Name: "Jump Rope"},
+	{Slug: "bicycle", Name: "Bicycle"},
+	{Slug: "rowing_machine", Name: "Rowing Machine"},
+	{Slug: "treadmill", Name: "Treadmill"},
+	{Slug: "pool", Name: "Pool"},
+}
+
+// MovementPatternCatalog lists every movement pattern of the taxonomy
+var MovementPatternCatalog = []TaxonomyItem{
+	{Slug: "push", Name: "Push"},
+	{Slug: "pull", Name: "Pull"},
+	{Slug: "hinge", Name: "Hinge"},
+	{Slug: "squat", Name: "Squat"},
+	{Slug: "carry", Name: "Carry"},
+}
+
+// muscleAliases maps free-text muscle names that are not catalog names to
+// muscles. Terms mapped to nothing, such as "cardiovascular", name no muscle.
+var muscleAliases = map[string][]string{
+	"pecs":             {"chest"},
+	"pectorals":        {"chest"},
+	"delts":            {"shoulders"},
+	"deltoids":         {"shoulders"},
+	"front delts":      {"shoulders"},
+	"side delts":       {"shoulders"},
+	"rear deltoids":    {"rear_delts"},
+	"calf":             {"calves"},
+	"grip":             {"forearms"},
+	"latissimus":       {"lats"},
+	"latissimus dorsi": {"lats"},
+	"back":             {"lats", "upper_back"},
+	"mid back":         {"upper_back"},
+	"rhomboids":        {"upper_back"},
+	"trapezius":        {"traps"},
+	"erector spinae":   {"lower_back"},
+	"erectors":         {"lower_back"},
+	"core":             {"abs"},
+	"abdominals":       {"abs"},
+	"quads":            {"quadriceps"},
+	"hams":             {"hamstrings"},
+	"legs":             {"quadriceps", "hamstrings", "glutes", "calves"},
+	"inner thighs":     {"adductors"},
+	"outer thighs":     {"abductors"},
+	"cardiovascular":   nil,
+	"cardio":           nil,
+	"none":             nil,
+}
+
+// equipmentAliases maps free-text equipment names that are not catalog names
+// to equipment
+var equipmentAliases = map[string][]string{
+	"ez curl bar":     {"ez_bar"},
+	"cables":          {"cable"},
+	"cable machine":   {"cable"},
+	"chin up bar":     {"pull_up_bar"},
+	"pullup bar":      {"pull_up_bar"},
+	"dip station":     {"parallel_bars"},
+	"dip bars":        {"parallel_bars"},
+	"bands":           {"resistance_band"},
+	"band":            {"resistance_band"},
+	"bike":            {"bicycle"},
+	"stationary bike": {"bicycle"},
+	"rower":           {"rowing_machine"},
+	"erg":             {"rowing_machine"},
+	"bodyweight":      nil,
+	"none":            nil,
+}
+
+// movementPatternKeywords infers movement patterns from exercise names. The
+// first keyword that starts a word of the name wins, so more specific
+// phrases such as "leg press" come before "press".
+var movementPatternKeywords = []struct {
+	keyword string
+	pattern string
+}{
+	{"farmer", "carry"},
+	{"suitcase", "carry"},
+	{"carry", "carry"},
+	{"yoke", "carry"},
+	{"leg press", "squat"},
+	{"hack squat", "squat"},
+	{"squat", "squat"},
+	{"lunge", "squat"},
+	{"step up", "squat"},
+	{"leg extension", "squat"},
+	{"leg curl", "hinge"},
+	{"deadlift", "hinge"},
+	{"rdl", "hinge"},
+	{"good morning", "hinge"},
+	{"hip thrust", "hinge"},
+	{"glute bridge", "hinge"},
+	{"swing", "hinge"},
+	{"back extension", "hinge"},
+	{"hyperextension", "hinge"},
+	{"face pull", "pull"},
+	{"pull", "pull"},
+	{"chin", "pull"},
+	{"row", "pull"},
+	{"curl", "pull"},
+	{"shrug", "pull"},
+	{"push", "push"},
+	{"press", "push"},
+	{"dip", "push"},
+	{"fly", "push"},
+	{"extension", "push"},
+}
+
+// taxonomyKey normalizes a free-text taxonomy term: lowercase words of
+// letters and digits joined by single spaces
+func taxonomyKey(term string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(term), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// splitTaxonomyText splits a comma-separated free-text value into terms
+func splitTaxonomyText(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '/' || r == ';'
+	})
+}
+
+// lookupTerm finds the slugs a normalized term stands for: a catalog slug or
+// name or an alias, in the singular or plural. ok is false for unknown terms.
+func lookupTerm(key string, slugs map[string]bool, aliases map[string][]string) ([]string, bool) {
+	for _, candidate := range []string{key, strings.TrimSuffix(key, "s"), key + "s"} {
+		if slug := strings.ReplaceAll(candidate, " ", "_"); slugs[slug] {
+			return []string{slug}, true
+		}
+		if mapped, ok := aliases[candidate]; ok {
+			return mapped, true
+		}
+	}
+	return nil, false
+}
+
+var (
+	muscleSlugs          = make(map[string]bool)
+	equipmentSlugs       = make(map[string]bool)
+	movementPatternSlugs = make(map[string]bool)
+)
+
+func init() {
+	for _, m := range MuscleCatalog {
+		muscleSlugs[m.Slug] = true
+	}
+	for _, e := range EquipmentCatalog {
+		equipmentSlugs[e.Slug] = true
+	}
+	for _, p := range MovementPatternCatalog {
+		movementPatternSlugs[p.Slug] = true
+	}
+}
+
+// IsValidMuscle reports whether slug is a muscle of the taxonomy
+func IsValidMuscle(slug string) bool {
+	return muscleSlugs[slug]
+}
+
+// IsValidEquipment reports whether slug is equipment of the taxonomy
+func IsValidEquipment(slug string) bool {
+	return equipmentSlugs[slug]
+}
+
+// IsValidMovementPattern reports whether slug is a movement pattern of the taxonomy
+func IsValidMovementPattern(slug string) bool {
+	return movementPatternSlugs[slug]
+}
+
+// IsValidMuscleRole reports whether role is a known muscle role
+func IsValidMuscleRole(role string) bool {
+	return role == MuscleRolePrimary || role == MuscleRoleSecondary
+}
+
+// MapMuscleGroup maps a free-text muscle_group such as "Chest, Triceps,
+// Shoulders" to muscles of the taxonomy. The muscles of the first term are
+// primary and the rest secondary. Unknown terms are skipped.
+func MapMuscleGroup(text string) []ExerciseMuscle {
+	var muscles []ExerciseMuscle
+	seen := make(map[string]bool)
+	first := true
+	for _, term := range splitTaxonomyText(text) {
+		key := taxonomyKey(term)
+		if key == "" {
+			continue
+		}
+		slugs, ok := lookupTerm(key, muscleSlugs, muscleAliases)
+		if !ok {
+			continue
+		}
+		role := MuscleRoleSecondary
+		if first {
+			role = MuscleRolePrimary
+		}
+		first = false
+		for _, slug := range slugs {
+			if !seen[slug] {
+				seen[slug] = true
+				muscles = append(muscles, ExerciseMuscle{Slug: slug, Role: role})
+			}
+		}
+	}
+	return muscles
+}
+
+// MapEquipment maps a free-text equipment value such as "Barbell, Bench" to
+// equipment of the taxonomy. Unknown terms are skipped.
+func MapEquipment(text string) []string {
+	var slugs []string
+	seen := make(map[string]bool)
+	for _, term := range splitTaxonomyText(text) {
+		mapped, _ := lookupTerm(taxonomyKey(term), equipmentSlugs, equipmentAliases)
+		for _, slug := range mapped {
+			if !seen[slug] {
+				seen[slug] = true
+				slugs = append(slugs, slug)
+			}
+		}
+	}
+	return slugs
+}
+
+// InferMovementPatterns guesses the movement pattern of an exercise from its
+// name. Exercises that carry no load, such as cardio and stretches, have none.
+func InferMovementPatterns(name, exerciseType string) []string {
+	if RulesForExerciseType(exerciseType).Load == LoadNone {
+		return nil
+	}
+	words := " " + taxonomyKey(name)
+	for _, k := range movementPatternKeywords {
+		if strings.Contains(words, " "+k.keyword) {
+			return []string{k.pattern}
+		}
+	}
+	return nil
+}
+
+// MuscleGroupText renders muscles as a free-text muscle_group, primary
+// muscles first
+func MuscleGroupText(muscles []ExerciseMuscle) string {
+	var names []string
+	for _, role := range []string{MuscleRolePrimary, MuscleRoleSecondary} {
+		for _, m := range muscles {
+			if m.Role == role {
+				name := m.Slug
+				if muscle, ok := MuscleBySlug(m.Slug); ok {
+					name = muscle.Name
+				}
+				names = append(names, name)
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// EquipmentText renders equipment as a free-text equipment value
+func EquipmentText(slugs []string) string {
+	names := make([]string, len(slugs))
+	for i, slug := range slugs {
+		names[i] = slug
+		if e, ok := EquipmentBySlug(slug); ok {
+			names[i] = e.Name
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// MuscleBySlug looks up a muscle of the taxonomy
+func MuscleBySlug(slug string) (Muscle, bool) {
+	for _, m := range MuscleCatalog {
+		if m.Slug == slug {
+			return m, true
+		}
+	}
+	return Muscle{}, false
+}
+
+// EquipmentBySlug looks up equipment of the taxonomy
+func EquipmentBySlug(slug string) (TaxonomyItem, bool) {
+	for _, e := range EquipmentCatalog {
+		if e.Slug == slug {
+			return e, true
+		}
+	}
+	return TaxonomyItem{}, false
+}
@@ -2,6 +2,12 @@ package models
 
 import "time"
 
+// User roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin" // may curate the public exercise library
+)
+
 type User struct {
 	ID                   int64      `json:"id"`
 	Username             string     `json:"username"`
@@ -12,9 +18,12 @@ type User struct {
 	PasswordResetToken   *string    `json:"-"`
 	PasswordResetExpires *time.Time `json:"-"`
 	WeeklyReportEnabled  *bool      `json:"weekly_report_enabled"`
+	WeightUnit           string     `json:"weight_unit,omitempty"`   // kg or lb
+	DistanceUnit         string     `json:"distance_unit,omitempty"` // km or mi
 	TOTPSecret           *string    `json:"-"`
 	TOTPEnabled          *bool      `json:"totp_enabled"`
 	TOTPBackupCodes      *string    `json:"-"`
+	Role                 string     `json:"role,omitempty"` // user or admin
 	CreatedAt            time.Time  `json:"created_at"`
 }
 
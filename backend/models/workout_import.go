@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// WorkoutImport records an activity file imported as a workout log. The file
+// hash makes imports idempotent: the same file maps to the same log.
+type WorkoutImport struct {
+	ID           int64     `json:"id"`
+	UserID       int64     `json:"user_id"`
+	FileHash     string    `json:"file_hash"` // SHA-256 of the file content
+	Format       string    `json:"format"`    // gpx, tcx or fit
+	Filename     *string   `json:"filename"`
+	WorkoutLogID int64     `json:"workout_log_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
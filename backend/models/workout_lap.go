@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WorkoutLap is a single lap or split of a cardio workout log
+type WorkoutLap struct {
+	ID              int64    `json:"id"`
+	WorkoutLogID    int64    `json:"workout_log_id"`
+	LapIndex        int      `json:"lap_index"`
+	Label           *string  `json:"label"`
+	Distance        *float64 `json:"distance"`
+	DurationSeconds *float64 `json:"duration_seconds"`
+	HeartRate       *int     `json:"heart_rate"` // average beats per minute
+	Pace            *float64 `json:"pace"`       // derived from distance and duration, never stored
+}
+
+// LegacyLapTime is the per-lap shape of the legacy lap_times field
+type LegacyLapTime struct {
+	Label string `json:"label"`
+	Time  string `json:"time"` // "m:ss" or "h:mm:ss"
+}
+
+// DerivePace fills in the lap's pace in minutes per distance unit
+func (l *WorkoutLap) DerivePace() {
+	l.Pace = nil
+	if l.Distance != nil && *l.Distance > 0 && l.DurationSeconds != nil && *l.DurationSeconds > 0 {
+		pace := *l.DurationSeconds / 60 / *l.Distance
+		l.Pace = &pace
+	}
+}
+
+// ParseLapTime reads a lap time as seconds. Strings use "m:ss" or "h:mm:ss";
+// plain numbers are minutes, like the duration of a log.
+func ParseLapTime(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v * 60, nil
+	case string:
+		v = strings.TrimSpace(v)
+		if !strings.Contains(v, ":") {
+			minutes, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid lap time %q", v)
+			}
+			return minutes * 60, nil
+		}
+
+		var seconds float64
+		for _, part := range strings.Split(v, ":") {
+			n, err := strconv.ParseFloat(part, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid lap time %q", v)
+			}
+			seconds = seconds*60 + n
+		}
+		return seconds, nil
+	}
+	return 0, fmt.Errorf("invalid lap time")
+}
+
+// FormatLapTime formats seconds as "m:ss", or "h:mm:ss" from an hour up
+func FormatLapTime(seconds float64) string {
+	total := int(math.Round(seconds))
+	h, m, s := total/3600, total/60%60, total%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// WorkoutLapsFromLapTimes converts a decoded legacy lap_times value into laps.
+// Entries are {label, time} objects; older entries may carry a lap number
+// instead of a label. Entries without a time are skipped.
+func WorkoutLapsFromLapTimes(value interface{}) ([]WorkoutLap, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	entries, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("lap_times must be a list")
+	}
+
+	laps := make([]WorkoutLap, 0, len(entries))
+	for i, entry := range entries {
+		v, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid lap_times entry at position %d", i+1)
+		}
+		if t, ok := v["time"].(string); v["time"] == nil || (ok && strings.TrimSpace(t) == "") {
+			continue
+		}
+
+		seconds, err := ParseLapTime(v["time"])
+		if err != nil {
+			return nil, fmt.Errorf("lap %d: %v", i+1, err)
+		}
+		lap := WorkoutLap{LapIndex: len(laps) + 1, DurationSeconds: &seconds}
+
+		if label, ok := v["label"].(string); ok && label != "" {
+			lap.Label = &label
+		} else if n, ok := legacyNumber(v["lap"]); ok {
+			label := fmt.Sprintf("Lap %g", n)
+			lap.Label = &label
+		}
+		if distance, ok := legacyNumber(v["distance"]); ok {
+			lap.Distance = &distance
+		}
+
+		laps = append(laps, lap)
+	}
+	return laps, nil
+}
+
+// LapTimesFromWorkoutLaps builds the legacy lap_times view of laps
+func LapTimesFromWorkoutLaps(laps []WorkoutLap) []LegacyLapTime {
+	legacy := make([]LegacyLapTime, 0, len(laps))
+	for _, lap := range laps {
+		entry := LegacyLapTime{Label: fmt.Sprintf("Lap %d", lap.LapIndex)}
+		if lap.Label != nil {
+			entry.Label = *lap.Label
+		}
+		if lap.DurationSeconds != nil {
+			entry.Time = FormatLapTime(*lap.DurationSeconds)
+		}
+		legacy = append(legacy, entry)
+	}
+	return legacy
+}
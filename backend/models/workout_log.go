@@ -9,19 +9,28 @@ type WorkoutLog struct {
 	ID           int64     `json:"id"`
 	UserID       int64     `json:"user_id"`
 	ExerciseID   int64     `json:"exercise_id"`
+	ExerciseSource string  `json:"exercise_source"` // "private" or "public"
 	ExerciseName *string   `json:"exercise_name,omitempty"`
 	ExerciseType *string   `json:"exercise_type,omitempty"`
+	SessionID    *int64    `json:"session_id"`
+	SessionOrder *int      `json:"session_order"`
+	GroupID      *int64    `json:"group_id"` // exercise group within the session
 	Date         string    `json:"date"`
 	Sets         *int      `json:"sets"`
 	Reps         *int      `json:"reps"`
 	Weight       *float64  `json:"weight"`
-	WeightPerSet interface{} `json:"weight_per_set"` // Can be array or null
+	WeightPerSet interface{} `json:"weight_per_set"` // Legacy view of WorkoutSets: array or null
+	WorkoutSets  []WorkoutSet `json:"workout_sets"`
 	RestTime     *int      `json:"rest_time"`
 	Distance     *float64  `json:"distance"`
 	Duration     *int      `json:"duration"`
 	Pace         *float64  `json:"pace"`
-	LapTimes     interface{} `json:"lap_times"` // Can be array or null
+	LapTimes     interface{} `json:"lap_times"` // Legacy view of Laps: array or null
+	Laps         []WorkoutLap `json:"laps"`
+	ElevationGain *float64  `json:"elevation_gain"`
+	CustomMetrics []CustomMetric `json:"custom_metrics"` // values of the exercise's custom fields
 	Notes        *string   `json:"notes"`
+	Planned      bool      `json:"planned"` // pre-filled from a template and not performed yet
 	CreatedAt    time.Time `json:"created_at"`
 }
 
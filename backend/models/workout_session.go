@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// WorkoutSession groups the workout logs performed together, e.g. "Tuesday push day"
+type WorkoutSession struct {
+	ID        int64           `json:"id"`
+	UserID    int64           `json:"user_id"`
+	Name      *string         `json:"name"`
+	Date      string          `json:"date"`
+	StartedAt *string         `json:"started_at"`
+	EndedAt   *string         `json:"ended_at"`
+	Notes     *string         `json:"notes"`
+	Logs      []WorkoutLog    `json:"logs,omitempty"`
+	Groups    []ExerciseGroup `json:"groups"` // supersets and circuits among the logs
+	CreatedAt time.Time       `json:"created_at"`
+}
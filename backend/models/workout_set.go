@@ -0,0 +1,116 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Set types of a workout set
+const (
+	SetTypeWarmup  = "warmup"
+	SetTypeWorking = "working"
+	SetTypeDrop    = "drop"
+	SetTypeFailure = "failure"
+)
+
+// WorkoutSet is a single set of a workout log. Weight is read according to
+// the exercise type: the load itself, load added to bodyweight, or assistance.
+type WorkoutSet struct {
+	ID              int64    `json:"id"`
+	WorkoutLogID    int64    `json:"workout_log_id"`
+	SetIndex        int      `json:"set_index"`
+	Reps            *int     `json:"reps"`
+	Weight          *float64 `json:"weight"`
+	DurationSeconds *int     `json:"duration_seconds"` // time held, for timed holds and mobility work
+	SetType         string   `json:"set_type"`
+	RPE             *float64 `json:"rpe"`
+	Tempo           *string  `json:"tempo"`
+}
+
+// LegacyWeightSet is the per-set shape of the legacy weight_per_set field
+type LegacyWeightSet struct {
+	Reps            *int     `json:"reps"`
+	Weight          *float64 `json:"weight"`
+	PerceivedEffort *float64 `json:"perceived_effort"`
+}
+
+// IsValidSetType reports whether t is a known set type
+func IsValidSetType(t string) bool {
+	switch t {
+	case SetTypeWarmup, SetTypeWorking, SetTypeDrop, SetTypeFailure:
+		return true
+	}
+	return false
+}
+
+// WorkoutSetsFromWeightPerSet converts a decoded legacy weight_per_set value
+// into working sets. The value is either a list of weights or a list of
+// {reps, weight, perceived_effort} objects; entries that only carry a weight
+// use defaultReps.
+func WorkoutSetsFromWeightPerSet(value interface{}, defaultReps *int) ([]WorkoutSet, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	entries, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("weight_per_set must be a list")
+	}
+
+	sets := make([]WorkoutSet, 0, len(entries))
+	for i, entry := range entries {
+		set := WorkoutSet{SetIndex: i + 1, SetType: SetTypeWorking}
+
+		switch v := entry.(type) {
+		case map[string]interface{}:
+			if reps, ok := legacyNumber(v["reps"]); ok {
+				r := int(reps)
+				set.Reps = &r
+			}
+			if weight, ok := legacyNumber(v["weight"]); ok {
+				set.Weight = &weight
+			}
+			if rpe, ok := legacyNumber(v["perceived_effort"]); ok {
+				set.RPE = &rpe
+			}
+		default:
+			weight, ok := legacyNumber(v)
+			if !ok {
+				return nil, fmt.Errorf("invalid weight_per_set entry at position %d", i+1)
+			}
+			set.Weight = &weight
+			set.Reps = defaultReps
+		}
+
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// WeightPerSetFromWorkoutSets builds the legacy weight_per_set view of sets
+func WeightPerSetFromWorkoutSets(sets []WorkoutSet) []LegacyWeightSet {
+	legacy := make([]LegacyWeightSet, 0, len(sets))
+	for _, set := range sets {
+		legacy = append(legacy, LegacyWeightSet{
+			Reps:            set.Reps,
+			Weight:          set.Weight,
+			PerceivedEffort: set.RPE,
+		})
+	}
+	return legacy
+}
+
+// legacyNumber reads a number that older clients may have sent as a string
+func legacyNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		if n == "" {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
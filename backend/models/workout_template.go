@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// WorkoutTemplate is a reusable, named routine of ordered exercises
+type WorkoutTemplate struct {
+	ID          int64                     `json:"id"`
+	UserID      int64                     `json:"user_id"`
+	Name        string                    `json:"name"`
+	Description *string                   `json:"description"`
+	Exercises   []WorkoutTemplateExercise `json:"exercises"`
+	CreatedAt   time.Time                 `json:"created_at"`
+}
+
+// WorkoutTemplateExercise is one exercise of a template with its target prescription
+type WorkoutTemplateExercise struct {
+	ID             int64    `json:"id"`
+	TemplateID     int64    `json:"template_id"`
+	ExerciseID     int64    `json:"exercise_id"`
+	ExerciseSource string   `json:"exercise_source"` // "private" or "public"
+	ExerciseName   *string  `json:"exercise_name,omitempty"`
+	ExerciseType   *string  `json:"exercise_type,omitempty"`
+	Position       int      `json:"position"`
+	TargetSets     *int     `json:"target_sets"`
+	TargetReps     *int     `json:"target_reps"`
+	TargetWeight   *float64 `json:"target_weight"`
+	TargetDistance *float64 `json:"target_distance"`
+	TargetDuration *int     `json:"target_duration"`
+	RestTime       *int     `json:"rest_time"`
+	Notes          *string  `json:"notes"`
+}
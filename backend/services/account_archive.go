@@ -0,0 +1,745 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gym-app-backend/database"
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+// archiveTable describes how a table is exported and restored. Rows belong to
+// the user through userColumn and/or through parentColumn pointing at a row of
+// parentTable; rows whose parent is not restored are dropped. refs are other
+// columns holding IDs of archived tables, cleared when the row is missing
+// unless requiredRefs is set, in which case the row is dropped as well.
+type archiveTable struct {
+	name         string
+	columns      []string // exported columns besides id
+	userColumn   string
+	parentTable  string
+	parentColumn string
+	scope        string // WHERE clause selecting the user's rows; ? is the user ID
+	refs         map[string]string
+	requiredRefs bool
+	dateColumns  []string // DATE columns, exported as YYYY-MM-DD
+	// exerciseSource is set for tables whose exercise_id may point at a public
+	// exercise, as told by their exercise_source column
+	exerciseSource bool
+	ignoreConflict bool // rows already present are kept on import
+}
+
+// archiveTables lists the archived tables in the order they are restored, so
+// every table comes after the tables it refers to. Exercises are handled
+// separately because they are matched by name.
+var archiveTables = []archiveTable{
+	{
+		name:       "workout_sessions",
+		columns:    []string{"name", "date", "started_at", "ended_at", "notes", "created_at"},
+		userColumn: "user_id", scope: "user_id = ?",
+		dateColumns: []string{"date"},
+	},
+	{
+		name:       "exercise_groups",
+		columns:    []string{"session_id", "group_type", "rounds", "rest_between_rounds", "notes", "created_at"},
+		userColumn: "user_id", scope: "user_id = ?",
+		refs:         map[string]string{"session_id": "workout_sessions"},
+		requiredRefs: true,
+	},
+	{
+		name: "workout_logs",
+		columns: []string{
+			"exercise_id", "exercise_source", "session_id", "session_order", "group_id", "date", "sets", "reps",
+			"weight", "rest_time", "distance", "duration", "pace", "elevation_gain", "notes", "created_at",
+		},
+		userColumn: "user_id", scope: "user_id = ?",
+		refs:           map[string]string{"session_id": "workout_sessions", "group_id": "exercise_groups"},
+		dateColumns:    []string{"date"},
+		exerciseSource: true,
+	},
+	{
+		name:        "workout_sets",
+		columns:     []string{"set_index", "reps", "weight", "duration_seconds", "set_type", "rpe", "tempo"},
+		parentTable: "workout_logs", parentColumn: "workout_log_id",
+		scope: "workout_log_id IN (SELECT id FROM workout_logs WHERE user_id = ?)",
+	},
+	{
+		name:        "workout_laps",
+		columns:     []string{"lap_index", "label", "distance", "duration_seconds", "heart_rate"},
+		parentTable: "workout_logs", parentColumn: "workout_log_id",
+		scope: "workout_log_id IN (SELECT id FROM workout_logs WHERE user_id = ?)",
+	},
+	{
+		name:       "workout_imports",
+		columns:    []string{"file_hash", "format", "filename", "created_at"},
+		userColumn: "user_id", scope: "user_id = ?",
+		parentTable: "workout_logs", parentColumn: "workout_log_id",
+		ignoreConflict: true,
+	},
+	{
+		name: "exercise_custom_fields",
+		columns: []string{
+			"exercise_id", "exercise_source", "name", "field_type", "unit", "options", "created_at", "updated_at",
+		},
+		userColumn: "user_id", scope: "user_id = ?",
+		exerciseSource: true,
+		ignoreConflict: true,
+	},
+	{
+		name:        "workout_log_custom_values",
+		columns:     []string{"field_id", "number_value", "text_value"},
+		parentTable: "workout_logs", parentColumn: "workout_log_id",
+		scope:        "workout_log_id IN (SELECT id FROM workout_logs WHERE user_id = ?)",
+		refs:         map[string]string{"field_id": "exercise_custom_fields"},
+		requiredRefs: true,
+	},
+	{
+		name:       "workout_templates",
+		columns:    []string{"name", "description", "created_at"},
+		userColumn: "user_id", scope: "user_id = ?",
+	},
+	{
+		name: "workout_template_exercises",
+		columns: []string{
+			"exercise_id", "exercise_source", "position", "target_sets", "target_reps", "target_weight",
+			"target_distance", "target_duration", "rest_time", "notes",
+		},
+		parentTable: "workout_templates", parentColumn: "template_id",
+		scope:          "template_id IN (SELECT id FROM workout_templates WHERE user_id = ?)",
+		exerciseSource: true,
+	},
+	{
+		name:       "training_programs",
+		columns:    []string{"name", "description", "duration_weeks", "created_at"},
+		userColumn: "user_id", scope: "user_id = ?",
+	},
+	{
+		name:        "program_days",
+		columns:     []string{"week", "day", "name", "template_id"},
+		parentTable: "training_programs", parentColumn: "program_id",
+		scope: "program_id IN (SELECT id FROM training_programs WHERE user_id = ?)",
+		refs:  map[string]string{"template_id": "workout_templates"},
+	},
+	{
+		name: "program_prescriptions",
+		columns: []string{
+			"exercise_id", "exercise_source", "method", "sets", "reps", "percentage", "base_weight",
+			"weight_increment", "base_distance", "distance_increment", "base_duration", "duration_increment",
+		},
+		parentTable: "program_days", parentColumn: "program_day_id",
+		scope: `program_day_id IN (SELECT pd.id FROM program_days pd
+		        JOIN training_programs tp ON pd.program_id = tp.id WHERE tp.user_id = ?)`,
+		exerciseSource: true,
+	},
+	{
+		name:       "program_enrollments",
+		columns:    []string{"program_id", "start_date", "status", "created_at"},
+		userColumn: "user_id", scope: "user_id = ?",
+		refs:        map[string]string{"program_id": "training_programs"},
+		dateColumns: []string{"start_date"},
+	},
+	{
+		name:        "program_training_maxes",
+		columns:     []string{"exercise_id", "exercise_source", "weight"},
+		parentTable: "program_enrollments", parentColumn: "enrollment_id",
+		scope:          "enrollment_id IN (SELECT id FROM program_enrollments WHERE user_id = ?)",
+		exerciseSource: true,
+	},
+	{
+		name: "body_metrics",
+		columns: []string{
+			"date", "bodyweight", "body_fat_percent", "neck", "chest", "waist", "hips", "arm", "forearm",
+			"thigh", "calf", "notes", "created_at", "updated_at",
+		},
+		userColumn: "user_id", scope: "user_id = ?",
+		dateColumns:    []string{"date"},
+		ignoreConflict: true,
+	},
+	{
+		name: "progression_rules",
+		columns: []string{
+			"exercise_id", "exercise_source", "method", "min_reps", "max_reps", "target_reps", "increment",
+			"hold_on_missed_reps", "deload_after_misses", "deload_percent", "created_at", "updated_at",
+		},
+		userColumn: "user_id", scope: "user_id = ?",
+		exerciseSource: true,
+		ignoreConflict: true,
+	},
+	{
+		name: "goals",
+		columns: []string{
+			"exercise_id", "exercise_source", "goal_type", "target_value", "target_reps", "target_duration",
+			"start_date", "deadline", "notes", "created_at", "updated_at",
+		},
+		userColumn: "user_id", scope: "user_id = ?",
+		dateColumns:    []string{"start_date", "deadline"},
+		exerciseSource: true,
+	},
+}
+
+// exerciseColumns are the exported columns of private exercises
+var exerciseColumns = []string{
+	"name", "exercise_type", "muscle_group", "equipment", "description", "instructions",
+	"video_link", "image_link", "created_at",
+}
+
+// ExportAccount builds the archive of a user's data
+func ExportAccount(userID int64) (*models.AccountArchive, error) {
+	archive := &models.AccountArchive{
+		Format:     models.AccountArchiveFormat,
+		Version:    models.AccountArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Tables:     make(map[string][]map[string]interface{}),
+	}
+
+	var weightUnit, distanceUnit sql.NullString
+	var weeklyReport sql.NullBool
+	err := database.DB.QueryRow(
+		"SELECT username, email, created_at, weight_unit, distance_unit, weekly_report_enabled FROM users WHERE id = ?",
+		userID,
+	).Scan(&archive.Profile.Username, &archive.Profile.Email, &archive.Profile.CreatedAt, &weightUnit, &distanceUnit, &weeklyReport)
+	if err != nil {
+		return nil, err
+	}
+	units := utils.DefaultUnits
+	if utils.IsValidWeightUnit(weightUnit.String) {
+		units.Weight = weightUnit.String
+	}
+	if utils.IsValidDistanceUnit(distanceUnit.String) {
+		units.Distance = distanceUnit.String
+	}
+	archive.Settings = models.ArchiveSettings{
+		WeightUnit:          units.Weight,
+		DistanceUnit:        units.Distance,
+		WeeklyReportEnabled: !weeklyReport.Valid || weeklyReport.Bool,
+	}
+
+	exercises, err := exportRows(
+		"SELECT id, "+strings.Join(exerciseColumns, ", ")+" FROM exercises WHERE user_id = ? ORDER BY id",
+		userID, nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("exercises: %w", err)
+	}
+	if err := exportExerciseTaxonomy(userID, exercises); err != nil {
+		return nil, fmt.Errorf("exercise taxonomy: %w", err)
+	}
+	archive.Tables["exercises"] = exercises
+
+	publicIDs := make(map[int64]bool)
+	for _, table := range archiveTables {
+		columns := "id, " + strings.Join(table.columns, ", ")
+		if table.parentColumn != "" {
+			columns += ", " + table.parentColumn
+		}
+
+		rows, err := exportRows(
+			fmt.Sprintf("SELECT %s FROM %s t WHERE %s ORDER BY id", columns, table.name, table.scope),
+			userID, table.dateColumns,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", table.name, err)
+		}
+		for _, row := range rows {
+			if table.exerciseSource && row["exercise_source"] == "public" {
+				if id, ok := archiveID(row["exercise_id"]); ok {
+					publicIDs[id] = true
+				}
+			}
+		}
+		archive.Tables[table.name] = rows
+	}
+
+	archive.PublicExercises = []models.ArchivePublicExercise{}
+	for id := range publicIDs {
+		var ex models.ArchivePublicExercise
+		var slug, exerciseType sql.NullString
+		err := database.DB.QueryRow(
+			"SELECT id, slug, name, exercise_type FROM public_exercises WHERE id = ?", id,
+		).Scan(&ex.ID, &slug, &ex.Name, &exerciseType)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		ex.Slug = slug.String
+		ex.ExerciseType = "strength"
+		if exerciseType.Valid && exerciseType.String != "" {
+			ex.ExerciseType = exerciseType.String
+		}
+		archive.PublicExercises = append(archive.PublicExercises, ex)
+	}
+	sort.Slice(archive.PublicExercises, func(i, j int) bool {
+		return archive.PublicExercises[i].ID < archive.PublicExercises[j].ID
+	})
+
+	return archive, nil
+}
+
+// timestampLayout matches SQLite's CURRENT_TIMESTAMP, so restored rows sort
+// alongside rows the instance writes itself
+const timestampLayout = "2006-01-02 15:04:05"
+
+// exportRows runs a query and returns its rows keyed by column name
+func exportRows(query string, userID int64, dateColumns []string) ([]map[string]interface{}, error) {
+	rows, err := database.DB.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	isDate := make(map[string]bool)
+	for _, c := range dateColumns {
+		isDate[c] = true
+	}
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			switch v := values[i].(type) {
+			case []byte:
+				row[column] = string(v)
+			case time.Time:
+				if isDate[column] {
+					row[column] = utils.FormatDate(v)
+				} else {
+					row[column] = v.UTC().Format(timestampLayout)
+				}
+			default:
+				row[column] = v
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// archiveID reads an ID from an archive row, where JSON decoding leaves numbers as float64
+func archiveID(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), n == float64(int64(n))
+	case int64:
+		return n, true
+	}
+	return 0, false
+}
+
+// archiveExercise is where an exercise of the archive ended up
+type archiveExercise struct {
+	id     int64
+	source string
+}
+
+// AccountImportSummary counts what an import restored
+type AccountImportSummary struct {
+	Rows             map[string]int `json:"rows"`              // rows restored per table
+	Skipped          map[string]int `json:"skipped,omitempty"` // rows dropped because what they refer to is missing
+	MatchedExercises int            `json:"matched_exercises"` // exercises merged into existing ones by name
+}
+
+// ValidateAccountArchive checks that an archive can be read by this server
+func ValidateAccountArchive(archive *models.AccountArchive) error {
+	if archive.Format != models.AccountArchiveFormat {
+		return fmt.Errorf("not an account archive")
+	}
+	if archive.Version < 1 || archive.Version > models.AccountArchiveVersion {
+		return fmt.Errorf("unsupported archive version %d, this server reads up to version %d",
+			archive.Version, models.AccountArchiveVersion)
+	}
+	if !utils.IsValidWeightUnit(archive.Settings.WeightUnit) || !utils.IsValidDistanceUnit(archive.Settings.DistanceUnit) {
+		return fmt.Errorf("invalid unit settings in archive")
+	}
+	return nil
+}
+
+// ImportAccount restores an archive into a user's account inside a
+// transaction. All IDs are remapped; exercises are matched by name and type
+// to the user's existing exercises and, for public exercises, to this
+// instance's public exercises. Personal records are rebuilt afterwards.
+func ImportAccount(tx *sql.Tx, userID int64, archive *models.AccountArchive) (*AccountImportSummary, error) {
+	if err := ValidateAccountArchive(archive); err != nil {
+		return nil, err
+	}
+
+	summary := &AccountImportSummary{Rows: make(map[string]int), Skipped: make(map[string]int)}
+
+	private, public, err := importExercises(tx, userID, archive, summary)
+	if err != nil {
+		return nil, err
+	}
+	resolve := func(id int64, source string) (archiveExercise, bool) {
+		if source == "public" {
+			ex, ok := public[id]
+			return ex, ok
+		}
+		ex, ok := private[id]
+		return ex, ok
+	}
+
+	idMaps := make(map[string]map[int64]int64)
+	logExercises := make(map[archiveExercise]bool)
+	for _, table := range archiveTables {
+		idMap := make(map[int64]int64)
+		idMaps[table.name] = idMap
+
+		for _, row := range archive.Tables[table.name] {
+			var columns []string
+			var values []interface{}
+
+			if table.userColumn != "" {
+				columns, values = append(columns, table.userColumn), append(values, userID)
+			}
+			if table.parentColumn != "" {
+				oldParent, _ := archiveID(row[table.parentColumn])
+				parent, ok := idMaps[table.parentTable][oldParent]
+				if !ok {
+					summary.Skipped[table.name]++
+					continue
+				}
+				columns, values = append(columns, table.parentColumn), append(values, parent)
+			}
+
+			skip := false
+			for _, column := range table.columns {
+				v, present := row[column]
+				if !present || column == "exercise_source" {
+					continue
+				}
+
+				switch {
+				case column == "exercise_id" && table.exerciseSource && v != nil:
+					oldID, _ := archiveID(v)
+					source, _ := row["exercise_source"].(string)
+					ex, ok := resolve(oldID, source)
+					if !ok {
+						skip = true
+						break
+					}
+					v = ex.id
+					columns, values = append(columns, "exercise_source"), append(values, ex.source)
+				case table.refs[column] != "" && v != nil:
+					oldID, _ := archiveID(v)
+					if newID, ok := idMaps[table.refs[column]][oldID]; ok {
+						v = newID
+					} else if table.requiredRefs {
+						skip = true
+					} else {
+						v = nil
+					}
+				}
+				if skip {
+					break
+				}
+				columns, values = append(columns, column), append(values, v)
+			}
+			if skip {
+				summary.Skipped[table.name]++
+				continue
+			}
+
+			verb := "INSERT"
+			if table.ignoreConflict {
+				verb = "INSERT OR IGNORE"
+			}
+			result, err := tx.Exec(
+				fmt.Sprintf("%s INTO %s (%s) VALUES (?%s)", verb, table.name, strings.Join(columns, ", "),
+					strings.Repeat(", ?", len(columns)-1)),
+				values...,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", table.name, err)
+			}
+			if n, _ := result.RowsAffected(); n == 0 {
+				summary.Skipped[table.name]++
+				continue
+			}
+			summary.Rows[table.name]++
+
+			newID, _ := result.LastInsertId()
+			if oldID, ok := archiveID(row["id"]); ok {
+				idMap[oldID] = newID
+			}
+			if table.name == "workout_logs" {
+				var ex archiveExercise
+				for i, column := range columns {
+					switch column {
+					case "exercise_id":
+						ex.id = values[i].(int64)
+					case "exercise_source":
+						ex.source = values[i].(string)
+					}
+				}
+				logExercises[ex] = true
+			}
+		}
+	}
+
+	_, err = tx.Exec(
+		`UPDATE users SET weight_unit = ?, distance_unit = ?, weekly_report_enabled = ?,
+		        email = COALESCE(NULLIF(email, ''), ?)
+		 WHERE id = ?`,
+		archive.Settings.WeightUnit, archive.Settings.DistanceUnit, archive.Settings.WeeklyReportEnabled,
+		archive.Profile.Email, userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for ex := range logExercises {
+		if _, err := RebuildPersonalRecords(tx, userID, ex.id, ex.source); err != nil {
+			return nil, err
+		}
+	}
+	// Achievements are not archived; they follow from the restored logs
+	if _, err := EvaluateGoals(tx, userID); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// importExercises restores the archive's private exercises and resolves the
+// public exercises it refers to. Exercises matching an existing one by name
+// and type are merged into it; public exercises missing from this instance
+// are recreated as private exercises.
+func importExercises(tx *sql.Tx, userID int64, archive *models.AccountArchive, summary *AccountImportSummary) (map[int64]archiveExercise, map[int64]archiveExercise, error) {
+	type exerciseKey struct{ name, exerciseType string }
+	keyOf := func(name, exerciseType string) exerciseKey {
+		if exerciseType == "" {
+			exerciseType = "strength"
+		}
+		return exerciseKey{strings.ToLower(strings.TrimSpace(name)), exerciseType}
+	}
+
+	existing := make(map[exerciseKey]int64)
+	rows, err := tx.Query("SELECT id, name, COALESCE(exercise_type, 'strength') FROM exercises WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var name, exerciseType string
+		if err := rows.Scan(&id, &name, &exerciseType); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		existing[keyOf(name, exerciseType)] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	private := make(map[int64]archiveExercise)
+	for _, row := range archive.Tables["exercises"] {
+		oldID, ok := archiveID(row["id"])
+		name, _ := row["name"].(string)
+		if !ok || strings.TrimSpace(name) == "" {
+			summary.Skipped["exercises"]++
+			continue
+		}
+		exerciseType, _ := row["exercise_type"].(string)
+		key := keyOf(name, exerciseType)
+
+		if id, ok := existing[key]; ok {
+			private[oldID] = archiveExercise{id: id, source: "private"}
+			summary.MatchedExercises++
+			continue
+		}
+
+		columns := []string{"user_id"}
+		values := []interface{}{userID}
+		for _, column := range exerciseColumns {
+			if v, ok := row[column]; ok {
+				columns, values = append(columns, column), append(values, v)
+			}
+		}
+		result, err := tx.Exec(
+			fmt.Sprintf("INSERT INTO exercises (%s) VALUES (?%s)", strings.Join(columns, ", "),
+				strings.Repeat(", ?", len(columns)-1)),
+			values...,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("exercises: %w", err)
+		}
+		id, _ := result.LastInsertId()
+		if err := importExerciseTaxonomy(tx, id, row); err != nil {
+			return nil, nil, fmt.Errorf("exercise taxonomy: %w", err)
+		}
+		existing[key] = id
+		private[oldID] = archiveExercise{id: id, source: "private"}
+		summary.Rows["exercises"]++
+	}
+
+	public := make(map[int64]archiveExercise)
+	for _, ex := range archive.PublicExercises {
+		// The slug survives renames of the library; older archives only have names
+		var id int64
+		err := tx.QueryRow(
+			`SELECT id FROM public_exercises WHERE slug = ? OR LOWER(name) = LOWER(?)
+			 ORDER BY slug IS NOT ?, id LIMIT 1`,
+			ex.Slug, strings.TrimSpace(ex.Name), ex.Slug,
+		).Scan(&id)
+		if err == nil {
+			public[ex.ID] = archiveExercise{id: id, source: "public"}
+			continue
+		} else if err != sql.ErrNoRows {
+			return nil, nil, err
+		}
+
+		key := keyOf(ex.Name, ex.ExerciseType)
+		if id, ok := existing[key]; ok {
+			public[ex.ID] = archiveExercise{id: id, source: "private"}
+			continue
+		}
+		result, err := tx.Exec(
+			"INSERT INTO exercises (user_id, name, exercise_type) VALUES (?, ?, ?)",
+			userID, ex.Name, key.exerciseType,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("exercises: %w", err)
+		}
+		id, _ = result.LastInsertId()
+		if err := database.MapExerciseTaxonomy(tx, id, "private", ex.Name, key.exerciseType, nil, nil); err != nil {
+			return nil, nil, fmt.Errorf("exercise taxonomy: %w", err)
+		}
+		existing[key] = id
+		public[ex.ID] = archiveExercise{id: id, source: "private"}
+		summary.Rows["exercises"]++
+	}
+
+	return private, public, nil
+}
+
+// exportExerciseTaxonomy adds the taxonomy links of each exported exercise
+// to its row by slug, since taxonomy IDs differ between instances
+func exportExerciseTaxonomy(userID int64, exercises []map[string]interface{}) error {
+	byID := make(map[int64]map[string]interface{}, len(exercises))
+	for _, row := range exercises {
+		id, _ := archiveID(row["id"])
+		byID[id] = row
+		row["muscles"] = []map[string]string{}
+		row["equipment_items"] = []string{}
+		row["movement_patterns"] = []string{}
+	}
+
+	rows, err := database.DB.Query(
+		`SELECT em.exercise_id, m.slug, em.role FROM exercise_muscles em JOIN muscles m ON m.id = em.muscle_id
+		 WHERE em.exercise_source = 'private' AND em.exercise_id IN (SELECT id FROM exercises WHERE user_id = ?)
+		 ORDER BY em.exercise_id, m.id`,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		var slug, role string
+		if err := rows.Scan(&id, &slug, &role); err != nil {
+			rows.Close()
+			return err
+		}
+		if row, ok := byID[id]; ok {
+			row["muscles"] = append(row["muscles"].([]map[string]string), map[string]string{"slug": slug, "role": role})
+		}
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	links := map[string]string{
+		"equipment_items": `SELECT ee.exercise_id, e.slug FROM exercise_equipment ee JOIN equipment e ON e.id = ee.equipment_id
+			WHERE ee.exercise_source = 'private' AND ee.exercise_id IN (SELECT id FROM exercises WHERE user_id = ?)
+			ORDER BY ee.exercise_id, e.id`,
+		"movement_patterns": `SELECT emp.exercise_id, mp.slug FROM exercise_movement_patterns emp
+			JOIN movement_patterns mp ON mp.id = emp.movement_pattern_id
+			WHERE emp.exercise_source = 'private' AND emp.exercise_id IN (SELECT id FROM exercises WHERE user_id = ?)
+			ORDER BY emp.exercise_id, mp.id`,
+	}
+	for key, query := range links {
+		rows, err := database.DB.Query(query, userID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id int64
+			var slug string
+			if err := rows.Scan(&id, &slug); err != nil {
+				rows.Close()
+				return err
+			}
+			if row, ok := byID[id]; ok {
+				row[key] = append(row[key].([]string), slug)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importExerciseTaxonomy links an imported exercise to the taxonomy by the
+// slugs of its archive row, skipping slugs this instance does not know.
+// Exercises from archives written before the taxonomy are mapped from their
+// free text instead.
+func importExerciseTaxonomy(tx *sql.Tx, exerciseID int64, row map[string]interface{}) error {
+	if _, ok := row["muscles"]; !ok {
+		name, _ := row["name"].(string)
+		exerciseType, _ := row["exercise_type"].(string)
+		muscleGroup, _ := row["muscle_group"].(string)
+		equipment, _ := row["equipment"].(string)
+		return database.MapExerciseTaxonomy(tx, exerciseID, "private", name, exerciseType, &muscleGroup, &equipment)
+	}
+
+	var muscles []models.ExerciseMuscle
+	entries, _ := row["muscles"].([]interface{})
+	for _, entry := range entries {
+		m, _ := entry.(map[string]interface{})
+		slug, _ := m["slug"].(string)
+		role, _ := m["role"].(string)
+		if models.IsValidMuscle(slug) && models.IsValidMuscleRole(role) {
+			muscles = append(muscles, models.ExerciseMuscle{Slug: slug, Role: role})
+		}
+	}
+	if err := database.SetExerciseMuscles(tx, exerciseID, "private", muscles); err != nil {
+		return err
+	}
+
+	slugs := func(key string, valid func(string) bool) []string {
+		var result []string
+		entries, _ := row[key].([]interface{})
+		for _, entry := range entries {
+			if slug, _ := entry.(string); valid(slug) {
+				result = append(result, slug)
+			}
+		}
+		return result
+	}
+	if err := database.SetExerciseEquipment(tx, exerciseID, "private", slugs("equipment_items", models.IsValidEquipment)); err != nil {
+		return err
+	}
+	return database.SetExerciseMovementPatterns(tx, exerciseID, "private", slugs("movement_patterns", models.IsValidMovementPattern))
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Activity file formats
+const (
+	ActivityFormatGPX = "gpx"
+	ActivityFormatTCX = "tcx"
+	ActivityFormatFIT = "fit"
+)
+
+// elevationThreshold is the climb in meters that has to build up before it
+// counts towards elevation gain, which keeps GPS altitude noise out of it
+const elevationThreshold = 2.0
+
+// Activity is a cardio activity read from a GPX, TCX or FIT file. Distances and
+// elevations are in meters, durations in seconds.
+type Activity struct {
+	Format          string
+	StartTime       time.Time
+	DistanceMeters  float64
+	DurationSeconds float64
+	ElevationGain   *float64
+	Laps            []ActivityLap
+}
+
+// ActivityLap is a lap recorded by the device or a split computed from the track
+type ActivityLap struct {
+	DistanceMeters  float64
+	DurationSeconds float64
+	AvgHeartRate    *int
+}
+
+// trackPoint is a sample of an activity track. Distance is cumulative; it is
+// computed from positions for formats that do not record it.
+type trackPoint struct {
+	time      time.Time
+	distance  *float64
+	elevation *float64
+	heartRate *int
+}
+
+// DetectActivityFormat determines the format of an activity file from its
+// content, falling back to the file extension
+func DetectActivityFormat(data []byte, filename string) (string, error) {
+	if len(data) >= 12 && string(data[8:12]) == ".FIT" {
+		return ActivityFormatFIT, nil
+	}
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	switch {
+	case bytes.Contains(head, []byte("<gpx")):
+		return ActivityFormatGPX, nil
+	case bytes.Contains(head, []byte("<TrainingCenterDatabase")):
+		return ActivityFormatTCX, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gpx":
+		return ActivityFormatGPX, nil
+	case ".tcx":
+		return ActivityFormatTCX, nil
+	case ".fit":
+		return ActivityFormatFIT, nil
+	}
+	return "", fmt.Errorf("unsupported file format, expected GPX, TCX or FIT")
+}
+
+// ParseActivityFile reads an activity from a GPX, TCX or FIT file. Files that
+// recorded at most one lap are split every splitMeters along the track.
+func ParseActivityFile(data []byte, filename string, splitMeters float64) (*Activity, error) {
+	format, err := DetectActivityFormat(data, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var activity *Activity
+	var points []trackPoint
+	switch format {
+	case ActivityFormatGPX:
+		activity, points, err = parseGPX(data)
+	case ActivityFormatTCX:
+		activity, points, err = parseTCX(data)
+	case ActivityFormatFIT:
+		activity, points, err = parseFIT(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	activity.Format = format
+
+	if activity.StartTime.IsZero() && len(points) > 0 {
+		activity.StartTime = points[0].time
+	}
+	if activity.StartTime.IsZero() {
+		return nil, fmt.Errorf("the file has no timestamps")
+	}
+
+	if len(points) > 1 {
+		last := points[len(points)-1]
+		if activity.DistanceMeters == 0 && last.distance != nil {
+			activity.DistanceMeters = *last.distance
+		}
+		if activity.DurationSeconds == 0 {
+			activity.DurationSeconds = last.time.Sub(points[0].time).Seconds()
+		}
+		if activity.ElevationGain == nil {
+			activity.ElevationGain = elevationGain(points)
+		}
+		if len(activity.Laps) <= 1 && splitMeters > 0 {
+			activity.Laps = splitTrack(points, splitMeters)
+		}
+	}
+
+	if activity.DistanceMeters <= 0 && activity.DurationSeconds <= 0 {
+		return nil, fmt.Errorf("the file contains no distance or duration")
+	}
+	return activity, nil
+}
+
+// haversineMeters returns the great-circle distance between two coordinates
+func haversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000.0
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLon := (lon2 - lon1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
+
+// elevationGain sums the climbs of a track, ignoring changes smaller than
+// elevationThreshold. Returns nil when the track has no elevation data.
+func elevationGain(points []trackPoint) *float64 {
+	var gain float64
+	var anchor *float64
+	for _, p := range points {
+		if p.elevation == nil {
+			continue
+		}
+		e := *p.elevation
+		switch {
+		case anchor == nil:
+			anchor = &e
+		case e-*anchor >= elevationThreshold:
+			gain += e - *anchor
+			anchor = &e
+		case *anchor-e >= elevationThreshold:
+			anchor = &e
+		}
+	}
+	if anchor == nil {
+		return nil
+	}
+	return &gain
+}
+
+// splitTrack cuts a track into splits of splitMeters; the last split holds
+// whatever distance remains
+func splitTrack(points []trackPoint, splitMeters float64) []ActivityLap {
+	var laps []ActivityLap
+	start := points[0]
+	startDistance := 0.0
+	if start.distance != nil {
+		startDistance = *start.distance
+	}
+	var hrSum, hrCount int
+
+	closeSplit := func(end trackPoint, endDistance float64) {
+		lap := ActivityLap{
+			DistanceMeters:  endDistance - startDistance,
+			DurationSeconds: end.time.Sub(start.time).Seconds(),
+		}
+		if hrCount > 0 {
+			avg := int(math.Round(float64(hrSum) / float64(hrCount)))
+			lap.AvgHeartRate = &avg
+		}
+		laps = append(laps, lap)
+		start, startDistance = end, endDistance
+		hrSum, hrCount = 0, 0
+	}
+
+	for _, p := range points[1:] {
+		if p.distance == nil {
+			continue
+		}
+		if p.heartRate != nil {
+			hrSum += *p.heartRate
+			hrCount++
+		}
+		if *p.distance-startDistance >= splitMeters {
+			closeSplit(p, *p.distance)
+		}
+	}
+
+	last := points[len(points)-1]
+	if last.distance != nil && *last.distance-startDistance > 0 {
+		closeSplit(last, *last.distance)
+	}
+	return laps
+}
+
+// parseActivityTime parses the RFC 3339 timestamps used by GPX and TCX
+func parseActivityTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(s))
+}
@@ -0,0 +1,288 @@
+package services
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// fitEpoch is the Unix time of the FIT epoch, 1989-12-31 00:00:00 UTC
+const fitEpoch = 631065600
+
+// FIT global message numbers read by the importer
+const (
+	fitMessageSession = 18
+	fitMessageLap     = 19
+	fitMessageRecord  = 20
+)
+
+// fitFieldTimestamp is the timestamp field shared by all FIT messages
+const fitFieldTimestamp = 253
+
+type fitFieldDef struct {
+	num      byte
+	size     int
+	baseType byte
+}
+
+type fitMessageDef struct {
+	global    uint16
+	order     binary.ByteOrder
+	fields    []fitFieldDef
+	devFields int // total size of developer fields, which are skipped
+}
+
+// fitDecoder walks the records of a FIT file. Only unsigned integer fields
+// are decoded; everything the importer does not need is skipped.
+type fitDecoder struct {
+	data          []byte
+	pos           int
+	defs          map[byte]*fitMessageDef
+	lastTimestamp uint32
+}
+
+// parseFIT reads the session, laps and records of a FIT activity file
+func parseFIT(data []byte) (*Activity, []trackPoint, error) {
+	if len(data) < 12 {
+		return nil, nil, fmt.Errorf("invalid FIT file: header too short")
+	}
+	headerSize := int(data[0])
+	dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	if headerSize < 12 || headerSize+dataSize > len(data) {
+		return nil, nil, fmt.Errorf("invalid FIT file: truncated")
+	}
+
+	d := &fitDecoder{data: data[:headerSize+dataSize], pos: headerSize, defs: make(map[byte]*fitMessageDef)}
+	activity := &Activity{}
+	var points []trackPoint
+	var lapAscent float64
+	var hasLapAscent, hasSession bool
+
+	for d.pos < len(d.data) {
+		global, values, err := d.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if values == nil {
+			continue
+		}
+
+		switch global {
+		case fitMessageRecord:
+			ts, ok := values[fitFieldTimestamp]
+			if !ok {
+				continue
+			}
+			point := trackPoint{time: fitTime(ts)}
+			if v, ok := values[5]; ok {
+				distance := float64(v) / 100
+				point.distance = &distance
+			}
+			if v, ok := values[78]; ok {
+				elevation := float64(v)/5 - 500
+				point.elevation = &elevation
+			} else if v, ok := values[2]; ok {
+				elevation := float64(v)/5 - 500
+				point.elevation = &elevation
+			}
+			if v, ok := values[3]; ok {
+				hr := int(v)
+				point.heartRate = &hr
+			}
+			points = append(points, point)
+
+		case fitMessageLap:
+			lap := ActivityLap{DistanceMeters: fitScaled(values, 9, 100), DurationSeconds: fitDuration(values)}
+			if v, ok := values[15]; ok {
+				hr := int(v)
+				lap.AvgHeartRate = &hr
+			}
+			if v, ok := values[21]; ok {
+				lapAscent += float64(v)
+				hasLapAscent = true
+			}
+			if v, ok := values[2]; ok && len(activity.Laps) == 0 && !hasSession {
+				activity.StartTime = fitTime(v)
+			}
+			activity.Laps = append(activity.Laps, lap)
+
+		case fitMessageSession:
+			// Multisport files have several sessions; only the first is imported
+			if hasSession {
+				continue
+			}
+			hasSession = true
+			activity.DistanceMeters = fitScaled(values, 9, 100)
+			activity.DurationSeconds = fitDuration(values)
+			if v, ok := values[2]; ok {
+				activity.StartTime = fitTime(v)
+			}
+			if v, ok := values[22]; ok {
+				ascent := float64(v)
+				activity.ElevationGain = &ascent
+			}
+		}
+	}
+
+	if !hasSession {
+		for _, lap := range activity.Laps {
+			activity.DistanceMeters += lap.DistanceMeters
+			activity.DurationSeconds += lap.DurationSeconds
+		}
+	}
+	if activity.ElevationGain == nil && hasLapAscent {
+		activity.ElevationGain = &lapAscent
+	}
+	return activity, points, nil
+}
+
+// next reads one record. It returns the global message number and the decoded
+// field values of data messages, and nil values for definition messages.
+func (d *fitDecoder) next() (uint16, map[byte]uint32, error) {
+	header := d.data[d.pos]
+	d.pos++
+
+	var local byte
+	var compressedOffset = -1
+	switch {
+	case header&0x80 != 0:
+		local = (header >> 5) & 0x03
+		compressedOffset = int(header & 0x1F)
+	case header&0x40 != 0:
+		return 0, nil, d.readDefinition(header&0x0F, header&0x20 != 0)
+	default:
+		local = header & 0x0F
+	}
+
+	def, ok := d.defs[local]
+	if !ok {
+		return 0, nil, fmt.Errorf("invalid FIT file: data message without definition")
+	}
+
+	values := make(map[byte]uint32)
+	for _, field := range def.fields {
+		raw, err := d.take(field.size)
+		if err != nil {
+			return 0, nil, err
+		}
+		if v, ok := fitUint(raw, field.baseType, def.order); ok {
+			values[field.num] = v
+		}
+	}
+	if _, err := d.take(def.devFields); err != nil {
+		return 0, nil, err
+	}
+
+	if ts, ok := values[fitFieldTimestamp]; ok {
+		d.lastTimestamp = ts
+	} else if compressedOffset >= 0 {
+		ts := d.lastTimestamp&^0x1F + uint32(compressedOffset)
+		if uint32(compressedOffset) < d.lastTimestamp&0x1F {
+			ts += 0x20
+		}
+		d.lastTimestamp = ts
+		values[fitFieldTimestamp] = ts
+	}
+	return def.global, values, nil
+}
+
+// readDefinition reads a definition message for a local message type
+func (d *fitDecoder) readDefinition(local byte, hasDevFields bool) error {
+	fixed, err := d.take(5)
+	if err != nil {
+		return err
+	}
+	def := &fitMessageDef{order: binary.LittleEndian}
+	if fixed[1] == 1 {
+		def.order = binary.BigEndian
+	}
+	def.global = def.order.Uint16(fixed[2:4])
+
+	for i := 0; i < int(fixed[4]); i++ {
+		raw, err := d.take(3)
+		if err != nil {
+			return err
+		}
+		def.fields = append(def.fields, fitFieldDef{num: raw[0], size: int(raw[1]), baseType: raw[2]})
+	}
+
+	if hasDevFields {
+		count, err := d.take(1)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < int(count[0]); i++ {
+			raw, err := d.take(3)
+			if err != nil {
+				return err
+			}
+			def.devFields += int(raw[1])
+		}
+	}
+
+	d.defs[local] = def
+	return nil
+}
+
+// take returns the next n bytes of the file
+func (d *fitDecoder) take(n int) ([]byte, error) {
+	if d.pos+n > len(d.data) {
+		return nil, fmt.Errorf("invalid FIT file: truncated record")
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// fitUint decodes an unsigned integer field, reporting false for other base
+// types and for the invalid value FIT uses to mark missing data
+func fitUint(raw []byte, baseType byte, order binary.ByteOrder) (uint32, bool) {
+	var v, invalid uint32
+	switch baseType & 0x1F {
+	case 0x00, 0x02, 0x0A: // enum, uint8, uint8z
+		if len(raw) != 1 {
+			return 0, false
+		}
+		v, invalid = uint32(raw[0]), 0xFF
+	case 0x04, 0x0B: // uint16, uint16z
+		if len(raw) != 2 {
+			return 0, false
+		}
+		v, invalid = uint32(order.Uint16(raw)), 0xFFFF
+	case 0x06, 0x0C: // uint32, uint32z
+		if len(raw) != 4 {
+			return 0, false
+		}
+		v, invalid = order.Uint32(raw), 0xFFFFFFFF
+	default:
+		return 0, false
+	}
+
+	// The z types mark missing data with zero instead of all ones
+	if baseType&0x1F >= 0x0A {
+		invalid = 0
+	}
+	return v, v != invalid
+}
+
+// fitTime converts a FIT timestamp to UTC time
+func fitTime(ts uint32) time.Time {
+	return time.Unix(int64(ts)+fitEpoch, 0).UTC()
+}
+
+// fitScaled returns a field divided by its scale, or 0 when missing
+func fitScaled(values map[byte]uint32, field byte, scale float64) float64 {
+	if v, ok := values[field]; ok {
+		return float64(v) / scale
+	}
+	return 0
+}
+
+// fitDuration returns the timer time of a lap or session in seconds, falling
+// back to the elapsed time when the timer time is missing
+func fitDuration(values map[byte]uint32) float64 {
+	if _, ok := values[8]; ok {
+		return fitScaled(values, 8, 1000)
+	}
+	return fitScaled(values, 7, 1000)
+}
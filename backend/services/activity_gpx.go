@@ -0,0 +1,65 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []struct {
+				Lat        float64  `xml:"lat,attr"`
+				Lon        float64  `xml:"lon,attr"`
+				Elevation  *float64 `xml:"ele"`
+				Time       string   `xml:"time"`
+				Extensions struct {
+					HeartRate *int `xml:"TrackPointExtension>hr"`
+				} `xml:"extensions"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// parseGPX reads the track points of a GPX file. GPX has no laps or recorded
+// distance, so distance is measured between consecutive points.
+func parseGPX(data []byte) (*Activity, []trackPoint, error) {
+	var file gpxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("invalid GPX file: %v", err)
+	}
+
+	var points []trackPoint
+	var distance float64
+	var prevLat, prevLon float64
+	for _, track := range file.Tracks {
+		for _, segment := range track.Segments {
+			for _, p := range segment.Points {
+				if p.Time == "" {
+					continue
+				}
+				t, err := parseActivityTime(p.Time)
+				if err != nil {
+					return nil, nil, fmt.Errorf("invalid GPX time %q", p.Time)
+				}
+				if len(points) > 0 {
+					distance += haversineMeters(prevLat, prevLon, p.Lat, p.Lon)
+				}
+				prevLat, prevLon = p.Lat, p.Lon
+
+				d := distance
+				points = append(points, trackPoint{
+					time:      t,
+					distance:  &d,
+					elevation: p.Elevation,
+					heartRate: p.Extensions.HeartRate,
+				})
+			}
+		}
+	}
+
+	if len(points) < 2 {
+		return nil, nil, fmt.Errorf("the GPX file has no timed track points")
+	}
+	return &Activity{}, points, nil
+}
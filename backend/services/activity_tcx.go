@@ -0,0 +1,85 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type tcxFile struct {
+	Activities []struct {
+		Laps []struct {
+			StartTime        string   `xml:"StartTime,attr"`
+			TotalTimeSeconds float64  `xml:"TotalTimeSeconds"`
+			DistanceMeters   float64  `xml:"DistanceMeters"`
+			AverageHeartRate *float64 `xml:"AverageHeartRateBpm>Value"`
+			Trackpoints      []struct {
+				Time           string   `xml:"Time"`
+				AltitudeMeters *float64 `xml:"AltitudeMeters"`
+				DistanceMeters *float64 `xml:"DistanceMeters"`
+				HeartRate      *float64 `xml:"HeartRateBpm>Value"`
+			} `xml:"Track>Trackpoint"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+// parseTCX reads the laps and track points of a TCX file. Only the first
+// activity of the file is imported.
+func parseTCX(data []byte) (*Activity, []trackPoint, error) {
+	var file tcxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("invalid TCX file: %v", err)
+	}
+	if len(file.Activities) == 0 || len(file.Activities[0].Laps) == 0 {
+		return nil, nil, fmt.Errorf("the TCX file has no activity laps")
+	}
+
+	activity := &Activity{}
+	var points []trackPoint
+	for i, lap := range file.Activities[0].Laps {
+		if i == 0 && lap.StartTime != "" {
+			start, err := parseActivityTime(lap.StartTime)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid TCX lap start time %q", lap.StartTime)
+			}
+			activity.StartTime = start
+		}
+
+		activityLap := ActivityLap{
+			DistanceMeters:  lap.DistanceMeters,
+			DurationSeconds: lap.TotalTimeSeconds,
+		}
+		if lap.AverageHeartRate != nil {
+			hr := int(*lap.AverageHeartRate + 0.5)
+			activityLap.AvgHeartRate = &hr
+		}
+		activity.Laps = append(activity.Laps, activityLap)
+		activity.DistanceMeters += lap.DistanceMeters
+		activity.DurationSeconds += lap.TotalTimeSeconds
+
+		for _, tp := range lap.Trackpoints {
+			if tp.Time == "" {
+				continue
+			}
+			t, err := parseActivityTime(tp.Time)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid TCX time %q", tp.Time)
+			}
+			point := trackPoint{time: t, distance: tp.DistanceMeters, elevation: tp.AltitudeMeters}
+			if tp.HeartRate != nil {
+				hr := int(*tp.HeartRate + 0.5)
+				point.heartRate = &hr
+			}
+			points = append(points, point)
+		}
+	}
+
+	// Devices may drop the distance of some points; splits need it throughout
+	var lastDistance *float64
+	for i := range points {
+		if points[i].distance == nil {
+			points[i].distance = lastDistance
+		}
+		lastDistance = points[i].distance
+	}
+	return activity, points, nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// datedBodyweight is one weigh-in in kilograms
+type datedBodyweight struct {
+	date string
+	kg   float64
+}
+
+// Bodyweights is a user's weigh-in history, oldest first
+type Bodyweights []datedBodyweight
+
+// LoadBodyweights loads the bodyweight entries of a user's body metrics
+func LoadBodyweights(q querier, userID int64) (Bodyweights, error) {
+	rows, err := q.Query(
+		`SELECT substr(date, 1, 10), bodyweight FROM body_metrics
+		 WHERE user_id = ? AND bodyweight IS NOT NULL
+		 ORDER BY date ASC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var weights Bodyweights
+	for rows.Next() {
+		var w datedBodyweight
+		if err := rows.Scan(&w.date, &w.kg); err != nil {
+			return nil, err
+		}
+		weights = append(weights, w)
+	}
+	return weights, rows.Err()
+}
+
+// On returns the bodyweight on a YYYY-MM-DD date: the latest weigh-in on or
+// before it, or the first one after it for dates before any weigh-in. Returns
+// nil when the user never recorded a bodyweight.
+func (b Bodyweights) On(date string) *float64 {
+	if len(b) == 0 {
+		return nil
+	}
+	if len(date) > 10 {
+		date = date[:10]
+	}
+	i := sort.Search(len(b), func(i int) bool { return b[i].date > date })
+	if i > 0 {
+		i--
+	}
+	kg := b[i].kg
+	return &kg
+}
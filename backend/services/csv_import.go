@@ -0,0 +1,530 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+// Fields a CSV column can be mapped to
+const (
+	CSVFieldDate     = "date"
+	CSVFieldWorkout  = "workout"
+	CSVFieldExercise = "exercise"
+	CSVFieldSetOrder = "set_order"
+	CSVFieldSetType  = "set_type"
+	CSVFieldWeight   = "weight"
+	CSVFieldReps     = "reps"
+	CSVFieldDistance = "distance"
+	CSVFieldDuration = "duration_seconds"
+	CSVFieldRPE      = "rpe"
+	CSVFieldNotes    = "notes"
+)
+
+// csvFields lists the mappable fields in the order they are reported
+var csvFields = []string{
+	CSVFieldDate, CSVFieldWorkout, CSVFieldExercise, CSVFieldSetOrder, CSVFieldSetType, CSVFieldWeight,
+	CSVFieldReps, CSVFieldDistance, CSVFieldDuration, CSVFieldRPE, CSVFieldNotes,
+}
+
+// CSV export formats that are recognized from their headers
+const (
+	CSVFormatStrong = "strong"
+	CSVFormatHevy   = "hevy"
+	CSVFormatCustom = "custom"
+)
+
+// CSVMapping maps import fields to the CSV column headers they are read from
+type CSVMapping map[string]string
+
+// CSVRowError is a problem with one row of a CSV file. Rows are numbered as in
+// a spreadsheet, so the header is row 1.
+type CSVRowError struct {
+	Row    int    `json:"row"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
+
+// CSVLog is one exercise of one workout, built from the rows of its sets.
+// Weights and distances are canonical.
+type CSVLog struct {
+	Date     string
+	Workout  string
+	Exercise string
+	Notes    *string
+	Sets     []models.WorkoutSet
+	Laps     []models.WorkoutLap
+	Rows     []int
+}
+
+// IsCardio reports whether the log was recorded as distance or time rather than sets
+func (l CSVLog) IsCardio() bool {
+	return len(l.Laps) > 0
+}
+
+// CSVImportPlan is the result of reading a CSV file: the logs it describes and
+// the rows that could not be read
+type CSVImportPlan struct {
+	Format   string
+	Mapping  CSVMapping
+	Units    utils.Units // units the file's weights and distances were read in
+	RowCount int
+	Logs     []CSVLog
+	Errors   []CSVRowError
+}
+
+// csvPreset describes a known export format
+type csvPreset struct {
+	format  string
+	mapping CSVMapping
+	units   *utils.Units // nil when the file does not say
+}
+
+// detectCSVPreset recognizes Strong and Hevy exports from their headers
+func detectCSVPreset(headers map[string]int) csvPreset {
+	has := func(h string) bool { _, ok := headers[h]; return ok }
+
+	if has("Exercise Name") && has("Set Order") && has("Date") {
+		return csvPreset{format: CSVFormatStrong, mapping: CSVMapping{
+			CSVFieldDate: "Date", CSVFieldWorkout: "Workout Name", CSVFieldExercise: "Exercise Name",
+			CSVFieldSetOrder: "Set Order", CSVFieldWeight: "Weight", CSVFieldReps: "Reps",
+			CSVFieldDistance: "Distance", CSVFieldDuration: "Seconds", CSVFieldRPE: "RPE", CSVFieldNotes: "Notes",
+		}}
+	}
+
+	if has("exercise_title") && has("start_time") {
+		preset := csvPreset{format: CSVFormatHevy, mapping: CSVMapping{
+			CSVFieldDate: "start_time", CSVFieldWorkout: "title", CSVFieldExercise: "exercise_title",
+			CSVFieldSetOrder: "set_index", CSVFieldSetType: "set_type", CSVFieldReps: "reps",
+			CSVFieldDuration: "duration_seconds", CSVFieldRPE: "rpe", CSVFieldNotes: "exercise_notes",
+		}}
+		units := utils.CanonicalUnits
+		if has("weight_lbs") {
+			preset.mapping[CSVFieldWeight] = "weight_lbs"
+			units.Weight = utils.WeightUnitLb
+		} else {
+			preset.mapping[CSVFieldWeight] = "weight_kg"
+		}
+		if has("distance_miles") {
+			preset.mapping[CSVFieldDistance] = "distance_miles"
+			units.Distance = utils.DistanceUnitMi
+		} else {
+			preset.mapping[CSVFieldDistance] = "distance_km"
+		}
+		preset.units = &units
+		return preset
+	}
+
+	return csvPreset{format: CSVFormatCustom, mapping: CSVMapping{}}
+}
+
+// csvDateLayouts are the date formats accepted in the date column
+var csvDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	time.RFC3339,
+	"2006-01-02",
+	"2 Jan 2006, 15:04",
+	"02 Jan 2006, 15:04",
+	"Jan 2, 2006",
+	"1/2/2006 15:04",
+	"1/2/2006",
+}
+
+// parseCSVDate reads a date in any of the accepted formats as YYYY-MM-DD
+func parseCSVDate(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range csvDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return utils.FormatDate(t), nil
+		}
+	}
+	return "", fmt.Errorf("unrecognized date %q", s)
+}
+
+// parseCSVSetType reads a set type from Hevy's set_type or Strong's set order,
+// which marks warm-up, drop and failure sets with W, D and F
+func parseCSVSetType(s string) (string, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "normal", "working":
+		return models.SetTypeWorking, true
+	case "w", "warmup", "warm_up", "warm-up":
+		return models.SetTypeWarmup, true
+	case "d", "drop", "dropset", "drop_set":
+		return models.SetTypeDrop, true
+	case "f", "failure":
+		return models.SetTypeFailure, true
+	}
+	if _, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+		return models.SetTypeWorking, true
+	}
+	return "", false
+}
+
+// csvDelimiter picks comma or semicolon, whichever splits the header line more
+func csvDelimiter(data []byte) rune {
+	line, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	if strings.Count(line, ";") > strings.Count(line, ",") {
+		return ';'
+	}
+	return ','
+}
+
+// ParseWorkoutCSV reads workout history from a CSV export. Strong and Hevy
+// exports are recognized from their headers; mapping overrides or, for other
+// files, provides the columns to read. fileUnits are the units of the file's
+// weights and distances unless the file states its own. Each row is a set;
+// rows of the same date, workout and exercise form one log.
+func ParseWorkoutCSV(data []byte, mapping CSVMapping, fileUnits utils.Units) (*CSVImportPlan, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = csvDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("the CSV file is empty")
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid CSV file: %v", err)
+	}
+	headers := make(map[string]int, len(header))
+	for i, h := range header {
+		headers[strings.TrimSpace(h)] = i
+	}
+
+	preset := detectCSVPreset(headers)
+	plan := &CSVImportPlan{Format: preset.format, Mapping: preset.mapping, Units: fileUnits, Errors: []CSVRowError{}}
+	if preset.units != nil {
+		plan.Units = *preset.units
+	}
+	for field, column := range mapping {
+		if !isCSVField(field) {
+			return nil, fmt.Errorf("unknown mapping field %q, expected one of %s", field, strings.Join(csvFields, ", "))
+		}
+		if column == "" {
+			delete(plan.Mapping, field)
+			continue
+		}
+		plan.Mapping[field] = column
+	}
+
+	columns := make(map[string]int)
+	for field, column := range plan.Mapping {
+		idx, ok := headers[column]
+		if !ok {
+			// Optional preset columns may be missing from older exports
+			if _, overridden := mapping[field]; !overridden && field != CSVFieldDate && field != CSVFieldExercise {
+				delete(plan.Mapping, field)
+				continue
+			}
+			return nil, fmt.Errorf("column %q mapped to %s is not in the file", column, field)
+		}
+		columns[field] = idx
+	}
+	if _, ok := columns[CSVFieldDate]; !ok {
+		return nil, fmt.Errorf("a column must be mapped to date")
+	}
+	if _, ok := columns[CSVFieldExercise]; !ok {
+		return nil, fmt.Errorf("a column must be mapped to exercise")
+	}
+
+	logIndex := make(map[string]int)
+	rowNumber := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		rowNumber++
+		if err != nil {
+			plan.Errors = append(plan.Errors, CSVRowError{Row: rowNumber, Error: err.Error()})
+			continue
+		}
+		if isBlankCSVRecord(record) {
+			continue
+		}
+		plan.RowCount++
+
+		row := csvRow{record: record, columns: columns, mapping: plan.Mapping, number: rowNumber}
+		entry, rowErr := row.parse(plan.Units)
+		if rowErr != nil {
+			plan.Errors = append(plan.Errors, *rowErr)
+			continue
+		}
+		if entry == nil {
+			continue
+		}
+
+		key := entry.date + "\x00" + entry.workout + "\x00" + strings.ToLower(entry.exercise)
+		i, ok := logIndex[key]
+		if !ok {
+			i = len(plan.Logs)
+			logIndex[key] = i
+			plan.Logs = append(plan.Logs, CSVLog{Date: entry.date, Workout: entry.workout, Exercise: entry.exercise})
+		}
+		plan.Logs[i].add(entry, rowNumber)
+	}
+
+	for i := range plan.Logs {
+		if err := plan.Logs[i].finish(); err != nil {
+			for _, row := range plan.Logs[i].Rows {
+				plan.Errors = append(plan.Errors, CSVRowError{Row: row, Error: err.Error()})
+			}
+			plan.Logs[i].Rows = nil
+		}
+	}
+	kept := plan.Logs[:0]
+	for _, log := range plan.Logs {
+		if log.Rows != nil {
+			kept = append(kept, log)
+		}
+	}
+	plan.Logs = kept
+	sort.SliceStable(plan.Errors, func(i, j int) bool { return plan.Errors[i].Row < plan.Errors[j].Row })
+	return plan, nil
+}
+
+func isCSVField(field string) bool {
+	for _, f := range csvFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlankCSVRecord(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// csvRow is a data row being read through the column mapping
+type csvRow struct {
+	record  []string
+	columns map[string]int
+	mapping CSVMapping
+	number  int
+}
+
+// csvEntry is one parsed set or cardio interval
+type csvEntry struct {
+	date     string
+	workout  string
+	exercise string
+	notes    string
+	set      models.WorkoutSet
+	distance *float64
+	seconds  *float64
+}
+
+func (r csvRow) value(field string) string {
+	idx, ok := r.columns[field]
+	if !ok || idx >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[idx])
+}
+
+func (r csvRow) fail(field, format string, args ...interface{}) *CSVRowError {
+	return &CSVRowError{Row: r.number, Column: r.mapping[field], Error: fmt.Sprintf(format, args...)}
+}
+
+// number64 reads an optional non-negative number; empty cells are nil
+func (r csvRow) number64(field string) (*float64, *CSVRowError) {
+	s := r.value(field)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", "."), 64)
+	if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, r.fail(field, "%s must be a non-negative number, got %q", field, s)
+	}
+	return &v, nil
+}
+
+// parse reads the row into an entry. Rows without any set data, such as
+// Strong's rest timer rows, are skipped by returning nil.
+func (r csvRow) parse(units utils.Units) (*csvEntry, *CSVRowError) {
+	entry := &csvEntry{
+		workout:  r.value(CSVFieldWorkout),
+		exercise: r.value(CSVFieldExercise),
+		notes:    r.value(CSVFieldNotes),
+	}
+	if entry.exercise == "" {
+		return nil, r.fail(CSVFieldExercise, "exercise is empty")
+	}
+
+	date, err := parseCSVDate(r.value(CSVFieldDate))
+	if err != nil {
+		return nil, r.fail(CSVFieldDate, "%v", err)
+	}
+	entry.date = date
+
+	setType := models.SetTypeWorking
+	if s := r.value(CSVFieldSetOrder); s != "" {
+		t, ok := parseCSVSetType(s)
+		if !ok {
+			if strings.EqualFold(s, "Rest Timer") || strings.EqualFold(s, "Note") {
+				return nil, nil
+			}
+			return nil, r.fail(CSVFieldSetOrder, "unrecognized set order %q", s)
+		}
+		setType = t
+	}
+	if s := r.value(CSVFieldSetType); s != "" {
+		t, ok := parseCSVSetType(s)
+		if !ok {
+			return nil, r.fail(CSVFieldSetType, "unrecognized set type %q", s)
+		}
+		setType = t
+	}
+
+	weight, rowErr := r.number64(CSVFieldWeight)
+	if rowErr != nil {
+		return nil, rowErr
+	}
+	reps, rowErr := r.number64(CSVFieldReps)
+	if rowErr != nil {
+		return nil, rowErr
+	}
+	distance, rowErr := r.number64(CSVFieldDistance)
+	if rowErr != nil {
+		return nil, rowErr
+	}
+	seconds, rowErr := r.number64(CSVFieldDuration)
+	if rowErr != nil {
+		return nil, rowErr
+	}
+	rpe, rowErr := r.number64(CSVFieldRPE)
+	if rowErr != nil {
+		return nil, rowErr
+	}
+	if rpe != nil && (*rpe < 1 || *rpe > 10) {
+		return nil, r.fail(CSVFieldRPE, "rpe must be between 1 and 10")
+	}
+
+	// Exports fill unused columns with zeros
+	if distance != nil && *distance == 0 {
+		distance = nil
+	}
+	if seconds != nil && *seconds == 0 {
+		seconds = nil
+	}
+	if reps != nil && *reps == 0 && weight != nil && *weight == 0 {
+		reps, weight = nil, nil
+	}
+
+	if reps == nil && weight == nil && distance == nil && seconds == nil {
+		return nil, nil
+	}
+
+	if reps != nil || weight != nil {
+		entry.set = models.WorkoutSet{SetType: setType, RPE: rpe}
+		if reps != nil {
+			n := int(math.Round(*reps))
+			entry.set.Reps = &n
+		}
+		if weight != nil {
+			kg := units.WeightToCanonical(*weight)
+			entry.set.Weight = &kg
+		}
+	}
+	if distance != nil {
+		km := units.DistanceToCanonical(*distance)
+		entry.distance = &km
+	}
+	entry.seconds = seconds
+	return entry, nil
+}
+
+// add appends a row's set or interval to the log
+func (l *CSVLog) add(entry *csvEntry, row int) {
+	l.Rows = append(l.Rows, row)
+	if entry.notes != "" && (l.Notes == nil || !strings.Contains(*l.Notes, entry.notes)) {
+		notes := entry.notes
+		if l.Notes != nil {
+			notes = *l.Notes + "; " + entry.notes
+		}
+		l.Notes = &notes
+	}
+	if entry.set.Reps != nil || entry.set.Weight != nil {
+		entry.set.SetIndex = len(l.Sets) + 1
+		l.Sets = append(l.Sets, entry.set)
+	}
+	if entry.distance != nil || entry.seconds != nil {
+		l.Laps = append(l.Laps, models.WorkoutLap{
+			LapIndex:        len(l.Laps) + 1,
+			Distance:        entry.distance,
+			DurationSeconds: entry.seconds,
+		})
+	}
+}
+
+// finish checks that a log is either strength or cardio
+func (l *CSVLog) finish() error {
+	if len(l.Sets) > 0 && len(l.Laps) > 0 {
+		return fmt.Errorf("%s mixes sets with distance or time on %s", l.Exercise, l.Date)
+	}
+	return nil
+}
+
+// TopSet returns the heaviest working set of a strength log, which is stored
+// as the log's summary weight and reps
+func (l CSVLog) TopSet() *models.WorkoutSet {
+	var top *models.WorkoutSet
+	for i, set := range l.Sets {
+		if set.SetType == models.SetTypeWarmup {
+			continue
+		}
+		if top == nil || (set.Weight != nil && (top.Weight == nil || *set.Weight > *top.Weight)) {
+			top = &l.Sets[i]
+		}
+	}
+	if top == nil && len(l.Sets) > 0 {
+		top = &l.Sets[0]
+	}
+	return top
+}
+
+// CardioTotals returns the total canonical distance and the duration in
+// minutes of a cardio log
+func (l CSVLog) CardioTotals() (*float64, *int) {
+	var distance, seconds float64
+	var hasDistance, hasSeconds bool
+	for _, lap := range l.Laps {
+		if lap.Distance != nil {
+			distance += *lap.Distance
+			hasDistance = true
+		}
+		if lap.DurationSeconds != nil {
+			seconds += *lap.DurationSeconds
+			hasSeconds = true
+		}
+	}
+
+	var d *float64
+	var m *int
+	if hasDistance {
+		d = &distance
+	}
+	if hasSeconds {
+		minutes := int(math.Round(seconds / 60))
+		m = &minutes
+	}
+	return d, m
+}
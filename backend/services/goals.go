@@ -0,0 +1,345 @@
+package services
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"gym-app-backend/models"
+	"gym-app-backend/utils"
+)
+
+// goalEntry is a log's contribution to a goal: the heaviest qualifying weight,
+// the time over the goal distance, or a training day
+type goalEntry struct {
+	logID int64
+	date  string
+	value float64
+}
+
+// goalExerciseRules returns the rules of a goal's exercise
+func goalExerciseRules(q querier, goal models.Goal) (models.ExerciseTypeRules, error) {
+	table := "exercises WHERE id = ? AND user_id = ?"
+	params := []interface{}{*goal.ExerciseID, goal.UserID}
+	if goalExerciseSource(goal) == "public" {
+		table = "public_exercises WHERE id = ?"
+		params = params[:1]
+	}
+
+	var exerciseType sql.NullString
+	err := q.QueryRow("SELECT exercise_type FROM "+table, params...).Scan(&exerciseType)
+	if err != nil && err != sql.ErrNoRows {
+		return models.ExerciseTypeRules{}, err
+	}
+	return models.RulesForExerciseType(exerciseType.String), nil
+}
+
+// goalExerciseSource returns the source of a goal's exercise
+func goalExerciseSource(goal models.Goal) string {
+	if goal.ExerciseSource != nil && *goal.ExerciseSource == "public" {
+		return "public"
+	}
+	return "private"
+}
+
+// loadGoalEntries loads every log that counts towards a goal, oldest first.
+// Logs before the goal's start date are included; they make up the baseline.
+func loadGoalEntries(q querier, goal models.Goal) ([]goalEntry, error) {
+	if goal.GoalType == models.GoalWorkoutCount {
+		return loadTrainingDayEntries(q, goal)
+	}
+
+	rules, err := goalExerciseRules(q, goal)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := loadRecordLogs(q, goal.UserID, *goal.ExerciseID, goalExerciseSource(goal), rules)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []goalEntry
+	for _, log := range logs {
+		switch goal.GoalType {
+		case models.GoalWeight:
+			// Assistance is not a load to work up to
+			if rules.Load == models.LoadAssistance {
+				continue
+			}
+			minReps := 1
+			if goal.TargetReps != nil {
+				minReps = *goal.TargetReps
+			}
+			heaviest := 0.0
+			for _, lift := range log.workingLifts(rules) {
+				if lift.reps >= minReps && lift.weight > heaviest {
+					heaviest = lift.weight
+				}
+			}
+			if heaviest > 0 {
+				entries = append(entries, goalEntry{logID: log.id, date: log.date, value: heaviest})
+			}
+
+		case models.GoalDistanceTime:
+			// The time over the goal distance at the pace of a log that
+			// covered at least that distance
+			if log.distance == nil || *log.distance < goal.TargetValue {
+				continue
+			}
+			var minutes float64
+			switch {
+			case log.duration != nil && *log.duration > 0:
+				minutes = float64(*log.duration) * goal.TargetValue / *log.distance
+			case log.pace != nil && *log.pace > 0:
+				minutes = *log.pace * goal.TargetValue
+			default:
+				continue
+			}
+			entries = append(entries, goalEntry{logID: log.id, date: log.date, value: minutes})
+		}
+	}
+	return entries, nil
+}
+
+// loadTrainingDayEntries returns the first performed log of each training day,
+// limited to the goal's exercise when it has one
+func loadTrainingDayEntries(q querier, goal models.Goal) ([]goalEntry, error) {
+	query := `SELECT id, substr(date, 1, 10) AS day FROM workout_logs WHERE user_id = ? AND planned = 0`
+	params := []interface{}{goal.UserID}
+	if goal.ExerciseID != nil {
+		query += " AND exercise_id = ? AND exercise_source = ?"
+		params = append(params, *goal.ExerciseID, goalExerciseSource(goal))
+	}
+	query += " ORDER BY day ASC, created_at ASC, id ASC"
+
+	rows, err := q.Query(query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []goalEntry
+	for rows.Next() {
+		var entry goalEntry
+		if err := rows.Scan(&entry.logID, &entry.date); err != nil {
+			return nil, err
+		}
+		if len(entries) > 0 && entries[len(entries)-1].date == entry.date {
+			continue
+		}
+		entry.value = 1
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// lowerIsBetter reports whether smaller values bring a goal closer
+func lowerIsBetter(goal models.Goal) bool {
+	return goal.GoalType == models.GoalDistanceTime
+}
+
+// goalTarget returns the value an entry has to reach to meet a goal
+func goalTarget(goal models.Goal) float64 {
+	if goal.GoalType == models.GoalDistanceTime && goal.TargetDuration != nil {
+		return float64(*goal.TargetDuration)
+	}
+	return goal.TargetValue
+}
+
+// measureGoal works out a goal's baseline, current value and the log that
+// achieved it from its entries
+func measureGoal(goal models.Goal, entries []goalEntry) (baseline, current *float64, achievedBy *goalEntry) {
+	target := goalTarget(goal)
+	better := func(a float64, b *float64) bool {
+		if b == nil {
+			return true
+		}
+		if lowerIsBetter(goal) {
+			return a < *b
+		}
+		return a > *b
+	}
+
+	count := 0.0
+	for i := range entries {
+		entry := &entries[i]
+		switch {
+		case entry.date < goal.StartDate:
+			if goal.GoalType != models.GoalWorkoutCount && better(entry.value, baseline) {
+				v := entry.value
+				baseline = &v
+			}
+			continue
+		case entry.date > goal.Deadline:
+			continue
+		}
+
+		if goal.GoalType == models.GoalWorkoutCount {
+			count++
+			current = &count
+			if achievedBy == nil && count >= target {
+				achievedBy = entry
+			}
+			continue
+		}
+		if better(entry.value, current) {
+			v := entry.value
+			current = &v
+		}
+		met := entry.value >= target
+		if lowerIsBetter(goal) {
+			met = entry.value <= target
+		}
+		if achievedBy == nil && met {
+			achievedBy = entry
+		}
+	}
+	return baseline, current, achievedBy
+}
+
+// EvaluateGoals marks each of the user's goals achieved by the first log in
+// its date range that meets the target. Like personal records, achievements
+// are recomputed from the logs, so a goal whose qualifying log was edited or
+// deleted is reopened. It returns the IDs of goals achieved by this call.
+func EvaluateGoals(tx *sql.Tx, userID int64) ([]int64, error) {
+	goals, err := loadGoals(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var achieved []int64
+	for _, goal := range goals {
+		entries, err := loadGoalEntries(tx, goal)
+		if err != nil {
+			return nil, err
+		}
+		_, _, achievedBy := measureGoal(goal, entries)
+
+		var logID *int64
+		var achievedOn *string
+		if achievedBy != nil {
+			logID, achievedOn = &achievedBy.logID, &achievedBy.date
+			if goal.AchievedLogID == nil {
+				achieved = append(achieved, goal.ID)
+			}
+		}
+		if sameID(logID, goal.AchievedLogID) && (achievedOn == nil || (goal.AchievedOn != nil && *achievedOn == *goal.AchievedOn)) {
+			continue
+		}
+		_, err = tx.Exec(
+			"UPDATE goals SET achieved_log_id = ?, achieved_on = ? WHERE id = ? AND user_id = ?",
+			logID, achievedOn, goal.ID, userID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return achieved, nil
+}
+
+// loadGoals loads the fields of the user's goals that evaluation needs
+func loadGoals(q querier, userID int64) ([]models.Goal, error) {
+	rows, err := q.Query(
+		`SELECT id, user_id, exercise_id, exercise_source, goal_type, target_value, target_reps,
+		        target_duration, substr(start_date, 1, 10), substr(deadline, 1, 10), achieved_log_id,
+		        substr(achieved_on, 1, 10)
+		 FROM goals WHERE user_id = ?`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []models.Goal
+	for rows.Next() {
+		var goal models.Goal
+		err := rows.Scan(
+			&goal.ID, &goal.UserID, &goal.ExerciseID, &goal.ExerciseSource, &goal.GoalType, &goal.TargetValue,
+			&goal.TargetReps, &goal.TargetDuration, &goal.StartDate, &goal.Deadline, &goal.AchievedLogID,
+			&goal.AchievedOn,
+		)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+func sameID(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// MeasureGoalProgress fills in the progress of a goal as of today, in
+// canonical units. The goal's achievement must be up to date.
+func MeasureGoalProgress(q querier, goal *models.Goal, today time.Time) error {
+	entries, err := loadGoalEntries(q, *goal)
+	if err != nil {
+		return err
+	}
+	baseline, current, _ := measureGoal(*goal, entries)
+
+	progress := models.GoalProgress{CurrentValue: current, BaselineValue: baseline}
+	progress.Percent = goalPercent(*goal, baseline, current)
+	progress.ExpectedPercent = expectedGoalPercent(goal.StartDate, goal.Deadline, today)
+
+	switch {
+	case goal.AchievedOn != nil:
+		progress.Percent = 100
+		progress.Status = models.GoalAchieved
+	case utils.FormatDate(today) > goal.Deadline:
+		progress.Status = models.GoalMissed
+	case progress.Percent >= progress.ExpectedPercent:
+		progress.Status = models.GoalOnTrack
+	default:
+		progress.Status = models.GoalBehind
+	}
+	goal.Progress = progress
+	return nil
+}
+
+// goalPercent is the share of the way from the baseline to the target that
+// the current value covers. Without a baseline short of the target, the way
+// is measured from zero.
+func goalPercent(goal models.Goal, baseline, current *float64) float64 {
+	if current == nil {
+		return 0
+	}
+	target := goalTarget(goal)
+
+	var percent float64
+	switch {
+	case lowerIsBetter(goal) && (baseline == nil || *baseline <= target):
+		percent = target / *current * 100
+	case lowerIsBetter(goal):
+		percent = (*baseline - *current) / (*baseline - target) * 100
+	case baseline == nil || *baseline >= target:
+		percent = *current / target * 100
+	default:
+		percent = (*current - *baseline) / (target - *baseline) * 100
+	}
+	return math.Round(math.Max(0, math.Min(100, percent))*10) / 10
+}
+
+// expectedGoalPercent is the share of the time from the start date to the
+// deadline that has passed by today
+func expectedGoalPercent(startDate, deadline string, today time.Time) float64 {
+	start, err := utils.ParseDate(startDate)
+	if err != nil {
+		return 0
+	}
+	end, err := utils.ParseDate(deadline)
+	if err != nil {
+		return 0
+	}
+	total := end.Sub(start).Hours() / 24
+	elapsed := today.Sub(start).Hours() / 24
+	switch {
+	case elapsed <= 0:
+		return 0
+	case elapsed >= total:
+		return 100
+	}
+	return math.Round(elapsed/total*1000) / 10
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"gym-app-backend/models"
+)
+
+// Suggested actions
+const (
+	ActionIncreaseWeight = "increase_weight"
+	ActionAddReps        = "add_reps"
+	ActionHold           = "hold"
+	ActionDeload         = "deload"
+)
+
+// defaultDeloadPercent is used when a rule deloads without a configured percentage
+const defaultDeloadPercent = 10.0
+
+// ProgressionSuggestion is the suggested next prescription of an exercise
+type ProgressionSuggestion struct {
+	Method        string   `json:"method"`
+	Action        string   `json:"action"`
+	Weight        *float64 `json:"weight"`
+	Sets          *int     `json:"sets"`
+	Reps          *int     `json:"reps"`
+	Explanation   string   `json:"explanation"`
+	BasedOnLogIDs []int64  `json:"based_on_log_ids"`
+}
+
+// sessionPerformance is what was lifted at the top weight of one log
+type sessionPerformance struct {
+	weight float64
+	sets   int   // working sets of the log
+	reps   []int // reps of each working set at the top weight
+}
+
+// lowestReps returns the reps of the weakest set at the top weight
+func (p sessionPerformance) lowestReps() int {
+	lowest := 0
+	for i, r := range p.reps {
+		if i == 0 || r < lowest {
+			lowest = r
+		}
+	}
+	return lowest
+}
+
+// summarizeSession reduces a log to its top working weight and the reps done
+// there. Logs without sets are treated as straight sets of the logged values.
+func summarizeSession(log models.WorkoutLog) sessionPerformance {
+	var p sessionPerformance
+	for _, set := range log.WorkoutSets {
+		if set.SetType == models.SetTypeWarmup || set.Weight == nil {
+			continue
+		}
+		p.sets++
+		reps := 0
+		if set.Reps != nil {
+			reps = *set.Reps
+		}
+		switch {
+		case *set.Weight > p.weight:
+			p.weight = *set.Weight
+			p.reps = []int{reps}
+		case *set.Weight == p.weight:
+			p.reps = append(p.reps, reps)
+		}
+	}
+	if p.sets > 0 || log.Weight == nil || log.Reps == nil {
+		return p
+	}
+
+	p.weight = *log.Weight
+	p.sets = 1
+	if log.Sets != nil && *log.Sets > 0 {
+		p.sets = *log.Sets
+	}
+	for i := 0; i < p.sets; i++ {
+		p.reps = append(p.reps, *log.Reps)
+	}
+	return p
+}
+
+// SuggestProgression suggests the next prescription from the recent history of
+// an exercise, newest log first. Weights in the rule and the history must use
+// the same units, which weightLabel names in the explanation. Returns nil when
+// the last log has no weighted sets to progress from.
+func SuggestProgression(rule models.ProgressionRule, history []models.WorkoutLog, weightLabel string) *ProgressionSuggestion {
+	if len(history) == 0 {
+		return nil
+	}
+	last := summarizeSession(history[0])
+	if last.weight <= 0 || len(last.reps) == 0 {
+		return nil
+	}
+
+	var increment float64
+	if rule.Increment != nil {
+		increment = *rule.Increment
+	}
+	weight := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64) + " " + weightLabel
+	}
+
+	suggestion := &ProgressionSuggestion{Method: rule.Method, Sets: &last.sets}
+	for _, log := range history {
+		suggestion.BasedOnLogIDs = append(suggestion.BasedOnLogIDs, log.ID)
+	}
+	set := func(action string, w float64, reps int, explanation string) *ProgressionSuggestion {
+		w = math.Round(w*1000) / 1000
+		suggestion.Action = action
+		suggestion.Weight = &w
+		suggestion.Reps = &reps
+		suggestion.Explanation = explanation
+		return suggestion
+	}
+	lowest := last.lowestReps()
+
+	if rule.Method == models.ProgressionDouble {
+		minReps, maxReps := *rule.MinReps, *rule.MaxReps
+		switch {
+		case lowest >= maxReps:
+			return set(ActionIncreaseWeight, last.weight+increment, minReps, fmt.Sprintf(
+				"Every set at %s reached the top of your %d-%d rep range, so add %s and start again at %d reps.",
+				weight(last.weight), minReps, maxReps, weight(increment), minReps,
+			))
+		case lowest >= minReps:
+			target := lowest + 1
+			return set(ActionAddReps, last.weight, target, fmt.Sprintf(
+				"Your weakest set at %s was %d reps, inside your %d-%d rep range. Stay at %s and aim for %d reps per set; the weight goes up once every set reaches %d.",
+				weight(last.weight), lowest, minReps, maxReps, weight(last.weight), target, maxReps,
+			))
+		}
+		return suggestMissed(rule, history, last, minReps, increment, set, weight)
+	}
+
+	// Fixed increments: the rule's target reps, or whatever the last log prescribed
+	target := lowest
+	if rule.TargetReps != nil {
+		target = *rule.TargetReps
+	} else if history[0].Reps != nil {
+		target = *history[0].Reps
+	}
+	if lowest >= target {
+		return set(ActionIncreaseWeight, last.weight+increment, target, fmt.Sprintf(
+			"You hit %d reps on every set at %s, so add the fixed increment of %s.",
+			target, weight(last.weight), weight(increment),
+		))
+	}
+	if !rule.HoldOnMissedReps {
+		return set(ActionIncreaseWeight, last.weight+increment, target, fmt.Sprintf(
+			"Your weakest set at %s was %d of %d reps, but this rule keeps progressing after missed reps, so add %s.",
+			weight(last.weight), lowest, target, weight(increment),
+		))
+	}
+	return suggestMissed(rule, history, last, target, increment, set, weight)
+}
+
+// suggestMissed holds the load after missed reps, or deloads once the rule's
+// limit of consecutive missed sessions at the same weight is reached
+func suggestMissed(
+	rule models.ProgressionRule,
+	history []models.WorkoutLog,
+	last sessionPerformance,
+	target int,
+	increment float64,
+	set func(action string, w float64, reps int, explanation string) *ProgressionSuggestion,
+	weight func(v float64) string,
+) *ProgressionSuggestion {
+	misses := 0
+	for _, log := range history {
+		p := summarizeSession(log)
+		if p.weight != last.weight || len(p.reps) == 0 || p.lowestReps() >= target {
+			break
+		}
+		misses++
+	}
+
+	if rule.DeloadAfterMisses != nil && *rule.DeloadAfterMisses > 0 && misses >= *rule.DeloadAfterMisses {
+		percent := defaultDeloadPercent
+		if rule.DeloadPercent != nil {
+			percent = *rule.DeloadPercent
+		}
+		deloaded := last.weight * (1 - percent/100)
+		if increment > 0 {
+			deloaded = math.Round(deloaded/increment) * increment
+		}
+		deloaded = math.Round(deloaded*1000) / 1000
+		return set(ActionDeload, deloaded, target, fmt.Sprintf(
+			"You fell short of %d reps at %s in %d sessions in a row, so drop %s%% to %s and build back up.",
+			target, weight(last.weight), misses, strconv.FormatFloat(percent, 'f', -1, 64), weight(deloaded),
+		))
+	}
+
+	explanation := fmt.Sprintf(
+		"Your weakest set at %s was %d reps, short of the %d you need. Stay at %s until every set reaches %d reps.",
+		weight(last.weight), last.lowestReps(), target, weight(last.weight), target,
+	)
+	if misses > 1 {
+		explanation += fmt.Sprintf(" This is %d sessions in a row at this weight.", misses)
+	}
+	return set(ActionHold, last.weight, target, explanation)
+}
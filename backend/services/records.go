@@ -0,0 +1,339 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gym-app-backend/database"
+	"gym-app-backend/models"
+)
+
+// EstimatedOneRepMax estimates a one-rep max from a set. Brzycki is used up to
+// ten reps and Epley above that; the two formulas agree at ten reps.
+func EstimatedOneRepMax(weight float64, reps int) float64 {
+	switch {
+	case reps <= 0:
+		return 0
+	case reps == 1:
+		return weight
+	case reps <= 10:
+		return weight * 36 / float64(37-reps)
+	default:
+		return weight * (1 + float64(reps)/30)
+	}
+}
+
+// recordLog is the part of a workout log that records are computed from
+type recordLog struct {
+	id       int64
+	date     string
+	weight   *float64
+	reps     *int
+	distance *float64
+	duration *int
+	pace     *float64
+	lifts    []recordLift
+}
+
+// recordLift is a working set of a log. weight is the logged weight, read
+// according to the exercise type.
+type recordLift struct {
+	weight  float64
+	reps    int
+	seconds int
+}
+
+// RebuildPersonalRecords recomputes the record history of an exercise by
+// replaying the user's logs in date order, so edits, deletions and backdated
+// logs are always reflected. source is "private" or "public". It returns the
+// rebuilt history.
+func RebuildPersonalRecords(tx *sql.Tx, userID, exerciseID int64, source string) ([]models.PersonalRecord, error) {
+	table := "exercises WHERE id = ? AND user_id = ?"
+	params := []interface{}{exerciseID, userID}
+	if source == "public" {
+		table = "public_exercises WHERE id = ?"
+		params = params[:1]
+	}
+	var exerciseType sql.NullString
+	err := tx.QueryRow("SELECT exercise_type FROM "+table, params...).Scan(&exerciseType)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	rules := models.RulesForExerciseType(exerciseType.String)
+
+	logs, err := loadRecordLogs(tx, userID, exerciseID, source, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	// Estimated maxes of bodyweight movements count the lifter's bodyweight
+	var bodyweights Bodyweights
+	if rules.UsesBodyweight() {
+		if bodyweights, err = LoadBodyweights(tx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(
+		"DELETE FROM personal_records WHERE user_id = ? AND exercise_id = ? AND exercise_source = ?",
+		userID, exerciseID, source,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.PersonalRecord
+	best := make(map[string]float64)
+
+	// improve records a candidate when it beats the current best for key
+	improve := func(log recordLog, key, recordType string, value float64, lowerIsBetter bool, weight *float64, reps *int) {
+		previous, seen := best[key]
+		if seen && (value == previous || (value > previous) == lowerIsBetter) {
+			return
+		}
+		best[key] = value
+
+		record := models.PersonalRecord{
+			UserID:         userID,
+			ExerciseID:     exerciseID,
+			ExerciseSource: source,
+			RecordType:     recordType,
+			Value:          value,
+			Weight:         weight,
+			Reps:           reps,
+			WorkoutLogID:   log.id,
+			AchievedOn:     log.date,
+		}
+		if seen {
+			record.PreviousValue = &previous
+		}
+		records = append(records, record)
+	}
+
+	for _, log := range logs {
+		lifts := log.workingLifts(rules)
+		bodyweight := bodyweights.On(log.date)
+
+		// Heaviest set and best estimated 1RM of the log. Assistance is not a
+		// load to beat, and the estimated max counts bodyweight where it applies.
+		var heaviest, strongest *recordLift
+		var strongestLoad float64
+		for i := range lifts {
+			lift := &lifts[i]
+			if lift.weight > 0 && rules.Load != models.LoadAssistance &&
+				(heaviest == nil || lift.weight > heaviest.weight || (lift.weight == heaviest.weight && lift.reps > heaviest.reps)) {
+				heaviest = lift
+			}
+			weight := lift.weight
+			load, ok := rules.EffectiveLoad(&weight, bodyweight)
+			if ok && load > 0 && lift.reps > 0 && rules.Reps &&
+				(strongest == nil || EstimatedOneRepMax(load, lift.reps) > EstimatedOneRepMax(strongestLoad, strongest.reps)) {
+				strongest, strongestLoad = lift, load
+			}
+		}
+		if heaviest != nil {
+			weight, reps := heaviest.weight, heaviest.reps
+			improve(log, models.RecordMaxWeight, models.RecordMaxWeight, weight, false, &weight, optionalReps(reps))
+		}
+		if strongest != nil {
+			weight, reps := strongestLoad, strongest.reps
+			improve(log, models.RecordEstimated1RM, models.RecordEstimated1RM, EstimatedOneRepMax(weight, reps), false, &weight, &reps)
+		}
+
+		// Most reps at each weight of the log, in the order the weights appear.
+		// Bodyweight movements also count sets without added weight.
+		mostReps := make(map[string]recordLift)
+		var weightKeys []string
+		for _, lift := range lifts {
+			if lift.reps <= 0 || !rules.Reps || (lift.weight <= 0 && !rules.UsesBodyweight()) {
+				continue
+			}
+			key := fmt.Sprintf("%s:%.3f", models.RecordMaxReps, lift.weight)
+			current, ok := mostReps[key]
+			if !ok {
+				weightKeys = append(weightKeys, key)
+			}
+			if !ok || lift.reps > current.reps {
+				mostReps[key] = lift
+			}
+		}
+		for _, key := range weightKeys {
+			lift := mostReps[key]
+			reps := lift.reps
+			var weight *float64
+			if lift.weight > 0 {
+				w := lift.weight
+				weight = &w
+			}
+			improve(log, key, models.RecordMaxReps, float64(reps), false, weight, &reps)
+		}
+
+		// Longest hold of timed holds and mobility work
+		var longest *recordLift
+		for i := range lifts {
+			if lifts[i].seconds > 0 && (longest == nil || lifts[i].seconds > longest.seconds) {
+				longest = &lifts[i]
+			}
+		}
+		if longest != nil {
+			var weight *float64
+			if longest.weight > 0 {
+				w := longest.weight
+				weight = &w
+			}
+			improve(log, models.RecordLongestHold, models.RecordLongestHold, float64(longest.seconds), false, weight, nil)
+		}
+
+		// Longest distance and fastest pace
+		if log.distance != nil && *log.distance > 0 {
+			improve(log, models.RecordMaxDistance, models.RecordMaxDistance, *log.distance, false, nil, nil)
+		}
+		pace := log.pace
+		if (pace == nil || *pace <= 0) && log.duration != nil && *log.duration > 0 && log.distance != nil && *log.distance > 0 {
+			derived := float64(*log.duration) / *log.distance
+			pace = &derived
+		}
+		if pace != nil && *pace > 0 {
+			improve(log, models.RecordBestPace, models.RecordBestPace, *pace, true, nil, nil)
+		}
+	}
+
+	for i := range records {
+		r := &records[i]
+		result, err := tx.Exec(
+			`INSERT INTO personal_records (user_id, exercise_id, exercise_source, record_type, value, weight, reps, previous_value, workout_log_id, achieved_on)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			r.UserID, r.ExerciseID, r.ExerciseSource, r.RecordType, r.Value, r.Weight, r.Reps, r.PreviousValue, r.WorkoutLogID, r.AchievedOn,
+		)
+		if err != nil {
+			return nil, err
+		}
+		r.ID, _ = result.LastInsertId()
+	}
+	return records, nil
+}
+
+// workingLifts returns the working sets of a log, falling back to the log's
+// own weight and reps for logs recorded without sets
+func (log recordLog) workingLifts(rules models.ExerciseTypeRules) []recordLift {
+	if len(log.lifts) > 0 || (log.weight == nil && (!rules.UsesBodyweight() || log.reps == nil)) {
+		return log.lifts
+	}
+	lift := recordLift{}
+	if log.weight != nil {
+		lift.weight = *log.weight
+	}
+	if log.reps != nil {
+		lift.reps = *log.reps
+	}
+	return []recordLift{lift}
+}
+
+// loadRecordLogs loads an exercise's performed logs in date order with their
+// working sets. Planned logs are left out, and sets without a weight only
+// count for types that need no load.
+func loadRecordLogs(q querier, userID, exerciseID int64, source string, rules models.ExerciseTypeRules) ([]recordLog, error) {
+	rows, err := q.Query(
+		`SELECT id, substr(date, 1, 10), weight, reps, distance, duration, pace
+		 FROM workout_logs
+		 WHERE user_id = ? AND exercise_id = ? AND exercise_source = ? AND planned = 0
+		 ORDER BY date ASC, created_at ASC, id ASC`,
+		userID, exerciseID, source,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []recordLog
+	index := make(map[int64]int)
+	for rows.Next() {
+		var log recordLog
+		if err := rows.Scan(&log.id, &log.date, &log.weight, &log.reps, &log.distance, &log.duration, &log.pace); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[log.id] = len(logs)
+		logs = append(logs, log)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Warm-up sets never count towards records
+	rows, err = q.Query(
+		`SELECT ws.workout_log_id, ws.weight, ws.reps, ws.duration_seconds
+		 FROM workout_sets ws
+		 JOIN workout_logs wl ON wl.id = ws.workout_log_id
+		 WHERE wl.user_id = ? AND wl.exercise_id = ? AND wl.exercise_source = ? AND wl.planned = 0 AND ws.set_type != ?
+		 ORDER BY ws.workout_log_id, ws.set_index`,
+		userID, exerciseID, source, models.SetTypeWarmup,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var logID int64
+		var lift recordLift
+		var weight sql.NullFloat64
+		var reps, seconds sql.NullInt64
+		if err := rows.Scan(&logID, &weight, &reps, &seconds); err != nil {
+			return nil, err
+		}
+		if !weight.Valid && rules.Load == models.LoadAbsolute && !rules.Hold {
+			continue
+		}
+		lift.weight = weight.Float64
+		lift.reps = int(reps.Int64)
+		lift.seconds = int(seconds.Int64)
+		if i, ok := index[logID]; ok {
+			logs[i].lifts = append(logs[i].lifts, lift)
+		}
+	}
+	return logs, rows.Err()
+}
+
+// BackfillPersonalRecords builds the record history of logs recorded before
+// personal records existed. It only runs once per database.
+func BackfillPersonalRecords() error {
+	return database.RunOnce("backfill_personal_records", func(tx *sql.Tx) error {
+		rows, err := tx.Query("SELECT DISTINCT user_id, exercise_id, exercise_source FROM workout_logs")
+		if err != nil {
+			return err
+		}
+
+		type exerciseKey struct {
+			userID, exerciseID int64
+			source             string
+		}
+		var keys []exerciseKey
+		for rows.Next() {
+			var key exerciseKey
+			if err := rows.Scan(&key.userID, &key.exerciseID, &key.source); err != nil {
+				rows.Close()
+				return err
+			}
+			keys = append(keys, key)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			if _, err := RebuildPersonalRecords(tx, key.userID, key.exerciseID, key.source); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func optionalReps(reps int) *int {
+	if reps <= 0 {
+		return nil
+	}
+	return &reps
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"time"
+
+	"gym-app-backend/utils"
+)
+
+// StreakRules decide which days without training keep a streak alive
+type StreakRules struct {
+	// RestAllowance is how many unplanned rest days in a row a streak survives
+	RestAllowance int
+	// RestWeekdays are planned rest days, which never break a streak
+	RestWeekdays map[time.Weekday]bool
+}
+
+// Streak is a run of training days
+type Streak struct {
+	Days       int    `json:"days"`        // calendar days from the first to the last training day
+	ActiveDays int    `json:"active_days"` // days with at least one log
+	StartDate  string `json:"start_date,omitempty"`
+	EndDate    string `json:"end_date,omitempty"`
+}
+
+// bridges reports whether a streak survives the rest days strictly between
+// two training days
+func (r StreakRules) bridges(from, to time.Time) bool {
+	missed := 0
+	for day := from.AddDate(0, 0, 1); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if r.RestWeekdays[day.Weekday()] {
+			continue
+		}
+		missed++
+		if missed > r.RestAllowance {
+			return false
+		}
+	}
+	return true
+}
+
+// ComputeStreaks returns the current and longest streaks of the training days
+// in active, which must be sorted, distinct dates. The current streak is the
+// one still alive today; today itself never counts as a missed day, since it
+// can still be trained.
+func ComputeStreaks(active []time.Time, today time.Time, rules StreakRules) (current, longest Streak) {
+	var run Streak
+	var start, last time.Time
+	for i, day := range active {
+		if i > 0 && !rules.bridges(last, day) {
+			run = Streak{}
+		}
+		if run.ActiveDays == 0 {
+			start = day
+		}
+		run.ActiveDays++
+		last = day
+		run.Days = int(day.Sub(start).Hours()/24) + 1
+		run.StartDate, run.EndDate = utils.FormatDate(start), utils.FormatDate(day)
+
+		if run.Days > longest.Days {
+			longest = run
+		}
+	}
+
+	if run.ActiveDays > 0 && rules.bridges(last, today) {
+		current = run
+	}
+	return current, longest
+}
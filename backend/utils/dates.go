@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// DateLayout is the layout used for workout dates (YYYY-MM-DD)
+const DateLayout = "2006-01-02"
+
+// ParseDate parses a workout date. The SQLite driver returns DATE columns
+// either as "2006-01-02" or as an RFC3339 timestamp, so only the date part is used.
+func ParseDate(s string) (time.Time, error) {
+	if len(s) < len(DateLayout) {
+		return time.Time{}, fmt.Errorf("invalid date: %q", s)
+	}
+	return time.Parse(DateLayout, s[:len(DateLayout)])
+}
+
+// FormatDate formats a time as a workout date
+func FormatDate(t time.Time) string {
+	return t.Format(DateLayout)
+}
+
+// WeekStart returns the Sunday that starts the week of t, matching the weekly report
+func WeekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -int(day.Weekday()))
+}
+
+// MonthStart returns the first day of the month of t
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
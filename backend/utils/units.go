@@ -0,0 +1,179 @@
+package utils
+
+import "math"
+
+// Weight and distance units a user can choose
+const (
+	WeightUnitKg   = "kg"
+	WeightUnitLb   = "lb"
+	DistanceUnitKm = "km"
+	DistanceUnitMi = "mi"
+)
+
+const (
+	kgPerLb = 0.45359237
+	kmPerMi = 1.609344
+	mPerFt  = 0.3048
+	cmPerIn = 2.54
+)
+
+// displayPrecision is the number of decimals values are rounded to when
+// converted out of canonical units, which hides float noise from round trips
+const displayPrecision = 3
+
+// Units is a unit system. The database always stores kilograms, kilometers,
+// minutes per kilometer, meters of elevation and centimeters of body
+// measurements; Units converts between those and a user's preference.
+// Elevation and body measurements follow the distance unit: feet and inches
+// for miles.
+type Units struct {
+	Weight   string `json:"weight_unit"`
+	Distance string `json:"distance_unit"`
+}
+
+// CanonicalUnits are the units values are stored in
+var CanonicalUnits = Units{Weight: WeightUnitKg, Distance: DistanceUnitKm}
+
+// DefaultUnits apply to users without a preference. They match the units the
+// app displayed before preferences existed.
+var DefaultUnits = Units{Weight: WeightUnitLb, Distance: DistanceUnitMi}
+
+// IsValidWeightUnit reports whether unit is a supported weight unit
+func IsValidWeightUnit(unit string) bool {
+	return unit == WeightUnitKg || unit == WeightUnitLb
+}
+
+// IsValidDistanceUnit reports whether unit is a supported distance unit
+func IsValidDistanceUnit(unit string) bool {
+	return unit == DistanceUnitKm || unit == DistanceUnitMi
+}
+
+func (u Units) kgPerUnit() float64 {
+	if u.Weight == WeightUnitLb {
+		return kgPerLb
+	}
+	return 1
+}
+
+func (u Units) kmPerUnit() float64 {
+	if u.Distance == DistanceUnitMi {
+		return kmPerMi
+	}
+	return 1
+}
+
+// WeightToCanonical converts a weight in these units to kilograms
+func (u Units) WeightToCanonical(v float64) float64 {
+	return v * u.kgPerUnit()
+}
+
+// WeightFromCanonical converts kilograms to these units
+func (u Units) WeightFromCanonical(v float64) float64 {
+	return round(v/u.kgPerUnit(), displayPrecision)
+}
+
+// PlateIncrement is the smallest common jump between loadable weights in
+// these units: 2.5 kg or 5 lb
+func (u Units) PlateIncrement() float64 {
+	if u.Weight == WeightUnitLb {
+		return 5
+	}
+	return 2.5
+}
+
+// DistanceToCanonical converts a distance in these units to kilometers
+func (u Units) DistanceToCanonical(v float64) float64 {
+	return v * u.kmPerUnit()
+}
+
+// DistanceFromCanonical converts kilometers to these units
+func (u Units) DistanceFromCanonical(v float64) float64 {
+	return round(v/u.kmPerUnit(), displayPrecision)
+}
+
+// PaceToCanonical converts minutes per distance unit to minutes per kilometer
+func (u Units) PaceToCanonical(v float64) float64 {
+	return v / u.kmPerUnit()
+}
+
+// PaceFromCanonical converts minutes per kilometer to minutes per distance unit
+func (u Units) PaceFromCanonical(v float64) float64 {
+	return round(v*u.kmPerUnit(), displayPrecision)
+}
+
+// ElevationToCanonical converts an elevation in these units to meters
+func (u Units) ElevationToCanonical(v float64) float64 {
+	if u.Distance == DistanceUnitMi {
+		return v * mPerFt
+	}
+	return v
+}
+
+// ElevationFromCanonical converts meters to these units
+func (u Units) ElevationFromCanonical(v float64) float64 {
+	if u.Distance == DistanceUnitMi {
+		return round(v/mPerFt, displayPrecision)
+	}
+	return round(v, displayPrecision)
+}
+
+// LengthToCanonical converts a body measurement in these units to centimeters
+func (u Units) LengthToCanonical(v float64) float64 {
+	if u.Distance == DistanceUnitMi {
+		return v * cmPerIn
+	}
+	return v
+}
+
+// LengthFromCanonical converts centimeters to these units
+func (u Units) LengthFromCanonical(v float64) float64 {
+	if u.Distance == DistanceUnitMi {
+		return round(v/cmPerIn, displayPrecision)
+	}
+	return round(v, displayPrecision)
+}
+
+// WeightLabel returns the label shown next to weights
+func (u Units) WeightLabel() string {
+	if u.Weight == WeightUnitLb {
+		return "lbs"
+	}
+	return "kg"
+}
+
+// DistanceLabel returns the label shown next to distances
+func (u Units) DistanceLabel() string {
+	if u.Distance == DistanceUnitMi {
+		return "miles"
+	}
+	return "km"
+}
+
+// PaceLabel returns the label shown next to paces
+func (u Units) PaceLabel() string {
+	if u.Distance == DistanceUnitMi {
+		return "min/mile"
+	}
+	return "min/km"
+}
+
+// ElevationLabel returns the label shown next to elevations
+func (u Units) ElevationLabel() string {
+	if u.Distance == DistanceUnitMi {
+		return "ft"
+	}
+	return "m"
+}
+
+// LengthLabel returns the label shown next to body measurements
+func (u Units) LengthLabel() string {
+	if u.Distance == DistanceUnitMi {
+		return "in"
+	}
+	return "cm"
+}
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}